@@ -1 +1,41 @@
 package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnv returns the value of the environment variable named by key, or
+// fallback when it is unset or empty.
+func GetEnv(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// GetDuration parses the environment variable named by key as a
+// time.Duration (e.g. "15m", "720h"), returning fallback when it is unset
+// or invalid.
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+// GetInt parses the environment variable named by key as an integer,
+// returning fallback when it is unset or invalid.
+func GetInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
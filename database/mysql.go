@@ -1,8 +1,15 @@
 package database
 
 import (
+	"crowdfunding-minpro-alterra/modules/apikey"
+	"crowdfunding-minpro-alterra/modules/audit"
 	"crowdfunding-minpro-alterra/modules/campaign"
+	"crowdfunding-minpro-alterra/modules/category"
+	"crowdfunding-minpro-alterra/modules/comment"
 	"crowdfunding-minpro-alterra/modules/donation"
+	"crowdfunding-minpro-alterra/modules/loginguard"
+	"crowdfunding-minpro-alterra/modules/notification"
+	"crowdfunding-minpro-alterra/modules/session"
 	"crowdfunding-minpro-alterra/modules/user"
 	"fmt"
 
@@ -28,15 +35,105 @@ func ConnectDB() (*gorm.DB, error) {
 	}
 
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", config.DBUser, config.DBPass, config.DBHost, config.DBPort, config.DBName)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError turns duplicate keys into gorm.ErrDuplicatedKey so the
+	// repositories can tell them apart from other failures.
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	err = MigrateAllEntities(db)
 	if err != nil {
 		return nil, err
 	}
 
-	MigrateAllEntities(db)
 	return db, nil
 }
 
-func MigrateAllEntities(db *gorm.DB) {
-	db.AutoMigrate(&user.User{}, &campaign.Campaign{}, &campaign.CampaignImage{}, &donation.Donation{})
+func MigrateAllEntities(db *gorm.DB) error {
+	err := backfillVerifiedAt(db)
+	if err != nil {
+		return err
+	}
+
+	err = dedupeCampaignSlugs(db)
+	if err != nil {
+		return err
+	}
+
+	return db.AutoMigrate(&user.User{}, &user.UserToken{}, &user.RecoveryCode{}, &user.UserIdentity{}, &category.Category{}, &campaign.Tag{}, &campaign.Campaign{}, &campaign.CampaignSlug{}, &campaign.CampaignImage{}, &campaign.Reward{}, &campaign.CampaignUpdate{}, &campaign.CampaignUpdateImage{}, &campaign.CampaignFollower{}, &donation.Donation{}, &comment.Comment{}, &comment.Report{}, &session.Session{}, &session.RefreshToken{}, &loginguard.Attempt{}, &loginguard.FailedLogin{}, &apikey.APIKey{}, &audit.Log{}, &notification.Notification{})
+}
+
+// backfillVerifiedAt adds users.verified_at and counts every account that
+// existed before email verification as verified on the day it registered.
+// Without it they would all be locked out of the endpoints that require a
+// verified email. It only runs while the column is missing.
+func backfillVerifiedAt(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	if !migrator.HasTable(&user.User{}) || migrator.HasColumn(&user.User{}, "VerifiedAt") {
+		return nil
+	}
+
+	err := migrator.AddColumn(&user.User{}, "VerifiedAt")
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&user.User{}).Unscoped().Where("verified_at IS NULL").Update("verified_at", gorm.Expr("created_at")).Error
+}
+
+// dedupeCampaignSlugs gives every campaign a slug of its own before
+// AutoMigrate puts a unique index on campaigns.slug. Campaigns from before
+// the index may have no slug or share one; later ones get "-2", "-3" and so
+// on, the same way new campaigns do.
+func dedupeCampaignSlugs(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	if !migrator.HasTable(&campaign.Campaign{}) || migrator.HasIndex(&campaign.Campaign{}, "Slug") {
+		return nil
+	}
+
+	if !migrator.HasColumn(&campaign.Campaign{}, "Slug") {
+		err := migrator.AddColumn(&campaign.Campaign{}, "Slug")
+		if err != nil {
+			return err
+		}
+	}
+
+	var campaigns []campaign.Campaign
+
+	err := db.Select("id", "name", "slug").Order("id").Find(&campaigns).Error
+	if err != nil {
+		return err
+	}
+
+	taken := map[string]bool{}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range campaigns {
+			base := campaign.SlugFrom(c.Slug)
+			if c.Slug == "" {
+				base = campaign.SlugFrom(c.Name)
+			}
+
+			candidate := base
+			for suffix := 2; taken[candidate]; suffix++ {
+				candidate = fmt.Sprintf("%s-%d", base, suffix)
+			}
+
+			taken[candidate] = true
+
+			if candidate == c.Slug {
+				continue
+			}
+
+			err := tx.Model(&campaign.Campaign{}).Where("id = ?", c.ID).Update("slug", candidate).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
@@ -10,7 +10,6 @@ require (
 	github.com/go-playground/validator/v10 v10.21.0
 	github.com/gosimple/slug v1.14.0
 	github.com/leekchan/accounting v1.0.0
-	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.9.0
 	github.com/veritrans/go-midtrans v0.0.0-20210616100512-16326c5eeb00
 	golang.org/x/crypto v0.24.0
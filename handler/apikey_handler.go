@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/modules/apikey"
+	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type apiKeyHandler struct {
+	apiKeyService apikey.Service
+}
+
+func NewAPIKeyHandler(apiKeyService apikey.Service) *apiKeyHandler {
+	return &apiKeyHandler{apiKeyService}
+}
+
+func (h *apiKeyHandler) CreateAPIKey(c *gin.Context) {
+	var input apikey.CreateAPIKeyInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to create API key.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+	input.UserID = currentUser.ID
+
+	newAPIKey, rawKey, err := h.apiKeyService.CreateAPIKey(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to create API key.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("API key has been created. Store it now, it will not be shown again.", http.StatusCreated, "success", apikey.FormatCreatedAPIKey(newAPIKey, rawKey))
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *apiKeyHandler) GetAPIKeys(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	apiKeys, err := h.apiKeyService.GetAPIKeys(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get API keys.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of API keys.", http.StatusOK, "success", apikey.FormatAPIKeys(apiKeys))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *apiKeyHandler) RevokeAPIKey(c *gin.Context) {
+	var input apikey.RevokeAPIKeyInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to revoke API key.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+	input.UserID = currentUser.ID
+
+	revokedAPIKey, err := h.apiKeyService.RevokeAPIKey(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to revoke API key.", http.StatusNotFound, "error", errorMessage)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	response := helper.APIResponse("API key has been revoked.", http.StatusOK, "success", apikey.FormatAPIKey(revokedAPIKey))
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type auditHandler struct {
+	auditService audit.Service
+}
+
+func NewAuditHandler(auditService audit.Service) *auditHandler {
+	return &auditHandler{auditService}
+}
+
+func (h *auditHandler) GetAuditLogs(c *gin.Context) {
+	var input audit.SearchLogsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get audit logs", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	logs, total, input, err := h.auditService.SearchLogs(input)
+	if err != nil {
+		response := helper.APIResponse("Error to get audit logs", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("List of audit logs", http.StatusOK, "success", audit.FormatLogs(logs), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportAuditLogs streams every entry matching the filters as CSV. Rows are
+// written as they are read, so an error part way through can only cut the
+// file short rather than change the status code.
+func (h *auditHandler) ExportAuditLogs(c *gin.Context) {
+	var input audit.SearchLogsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to export audit logs", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-logs-%s.csv"`, time.Now().Format("20060102-150405")))
+	c.Status(http.StatusOK)
+
+	err = h.auditService.ExportLogsCSV(input, c.Writer)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// auditActor describes who is making the request for the audit log. Routes
+// without authentication yield an actor with only the client address.
+func auditActor(c *gin.Context) audit.Actor {
+	actor := audit.Actor{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	if currentUser, ok := c.Get("currentUser"); ok {
+		actor.ID = currentUser.(user.User).ID
+	}
+
+	return actor
+}
@@ -5,8 +5,8 @@ import (
 	"crowdfunding-minpro-alterra/modules/campaign"
 	"crowdfunding-minpro-alterra/modules/user"
 	"crowdfunding-minpro-alterra/utils/helper"
+	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/cloudinary/cloudinary-go"
 	"github.com/cloudinary/cloudinary-go/api/uploader"
@@ -23,9 +23,195 @@ func NewCampaignHandler(service campaign.Service, cloudinary *cloudinary.Cloudin
 }
 
 func (h *campaignHandler) GetCampaigns(c *gin.Context) {
-	userID, _ := strconv.Atoi(c.Query("user_id"))
+	var input campaign.GetCampaignsInput
 
-	campaigns, err := h.service.GetCampaigns(userID)
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get campaigns.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+
+		return
+	}
+
+	// Campaigns waiting for review, rejected or cancelled are not public.
+	input.Statuses = campaign.PublicStatuses
+
+	campaigns, total, input, err := h.service.SearchCampaigns(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get campaigns.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+
+		return
+	}
+
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("List of campaigns.", http.StatusOK, "success", campaign.FormatCampaigns(campaigns), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCampaignFacets counts public campaigns per category and tag for the
+// same filters GetCampaigns accepts.
+func (h *campaignHandler) GetCampaignFacets(c *gin.Context) {
+	var input campaign.GetCampaignsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get campaign facets.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+
+		return
+	}
+
+	input.Statuses = campaign.PublicStatuses
+
+	facets, err := h.service.GetCampaignFacets(input)
+	if err != nil {
+		response := helper.APIResponse("Error to get campaign facets.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+
+		return
+	}
+
+	response := helper.APIResponse("Campaign facets.", http.StatusOK, "success", campaign.FormatCampaignFacets(facets))
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAdminCampaigns lists campaigns in any state, optionally filtered with
+// ?status=.
+func (h *campaignHandler) GetAdminCampaigns(c *gin.Context) {
+	var input campaign.GetCampaignsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get campaigns.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+
+		return
+	}
+
+	input.IncludeArchived = true
+
+	campaigns, total, input, err := h.service.SearchCampaigns(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get campaigns.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+
+		return
+	}
+
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("List of campaigns.", http.StatusOK, "success", campaign.FormatCampaigns(campaigns), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) GetReviewQueue(c *gin.Context) {
+	var input campaign.GetCampaignsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get review queue.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+
+		return
+	}
+
+	campaigns, total, input, err := h.service.GetReviewQueue(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get review queue.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+
+		return
+	}
+
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("Campaigns waiting for review.", http.StatusOK, "success", campaign.FormatCampaigns(campaigns), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) ApproveCampaign(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to approve campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input := campaign.ReviewCampaignInput{ID: inputID.ID, Actor: auditActor(c)}
+
+	approvedCampaign, err := h.service.ApproveCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to approve campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign approved", http.StatusOK, "success", campaign.FormatCampaign(approvedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) RejectCampaign(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to reject campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var input campaign.ReviewCampaignInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to reject campaign", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.ID = inputID.ID
+	input.Actor = auditActor(c)
+
+	rejectedCampaign, err := h.service.RejectCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to reject campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign rejected", http.StatusOK, "success", campaign.FormatCampaign(rejectedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) GetUserCampaigns(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	campaigns, err := h.service.GetCampaigns(currentUser.ID)
 	if err != nil {
 		response := helper.APIResponse("Error to get campaigns.", http.StatusBadRequest, "error", nil)
 		c.JSON(http.StatusBadRequest, response)
@@ -33,7 +219,7 @@ func (h *campaignHandler) GetCampaigns(c *gin.Context) {
 		return
 	}
 
-	response := helper.APIResponse("List of campaigns.", http.StatusOK, "success", campaign.FormatCampaigns(campaigns))
+	response := helper.APIResponse("List of your campaigns.", http.StatusOK, "success", campaign.FormatCampaigns(campaigns))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -59,6 +245,41 @@ func (h *campaignHandler) GetCampaign(c *gin.Context) {
 		return
 	}
 
+	if !campaignDetail.IsViewable() {
+		response := helper.APIResponse("Campaign not found.", http.StatusNotFound, "error", nil)
+		c.JSON(http.StatusNotFound, response)
+
+		return
+	}
+
+	response := helper.APIResponse("Campaign detail.", http.StatusOK, "success", campaign.FormatCampaignDetail(campaignDetail))
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCampaignBySlug shows a campaign by slug. Slugs the campaign had before
+// a rename answer with a permanent redirect to the current one.
+func (h *campaignHandler) GetCampaignBySlug(c *gin.Context) {
+	var input campaign.GetCampaignSlugInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get detail of campaign.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	campaignDetail, err := h.service.GetCampaignBySlug(input)
+	if err != nil || !campaignDetail.IsViewable() {
+		response := helper.APIResponse("Campaign not found.", http.StatusNotFound, "error", nil)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	if campaignDetail.Slug != input.Slug {
+		c.Redirect(http.StatusMovedPermanently, "/api/v1/campaigns/slug/"+campaignDetail.Slug)
+		return
+	}
+
 	response := helper.APIResponse("Campaign detail.", http.StatusOK, "success", campaign.FormatCampaignDetail(campaignDetail))
 	c.JSON(http.StatusOK, response)
 }
@@ -79,10 +300,13 @@ func (h *campaignHandler) CreateCampaign(c *gin.Context) {
 	currentUser := c.MustGet("currentUser").(user.User)
 
 	input.User = currentUser
+	input.Actor = auditActor(c)
 
 	newCampaign, err := h.service.CreateCampaign(input)
 	if err != nil {
-		response := helper.APIResponse("Failed to create campaign.", http.StatusBadRequest, "error", nil)
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to create campaign.", http.StatusBadRequest, "error", errorMessage)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
@@ -116,10 +340,13 @@ func (h *campaignHandler) UpdateCampaign(c *gin.Context) {
 
 	currentUser := c.MustGet("currentUser").(user.User)
 	inputData.User = currentUser
+	inputData.Actor = auditActor(c)
 
 	updatedCampaign, err := h.service.UpdateCampaign(inputID, inputData)
 	if err != nil {
-			response := helper.APIResponse("Failed to update campaign", http.StatusBadRequest, "error", nil)
+			errorMessage := gin.H{"errors": err.Error()}
+
+			response := helper.APIResponse("Failed to update campaign", http.StatusBadRequest, "error", errorMessage)
 			c.JSON(http.StatusBadRequest, response)
 			return
 	}
@@ -129,6 +356,405 @@ func (h *campaignHandler) UpdateCampaign(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *campaignHandler) CreateReward(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to create reward", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaign.CreateRewardInput
+
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to create reward", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+	inputData.Actor = auditActor(c)
+
+	newReward, err := h.service.CreateReward(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to create reward", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Success to create reward", http.StatusOK, "success", campaign.FormatReward(newReward))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) UpdateReward(c *gin.Context) {
+	var inputID campaign.GetRewardDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to update reward", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaign.CreateRewardInput
+
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to update reward", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+	inputData.Actor = auditActor(c)
+
+	updatedReward, err := h.service.UpdateReward(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to update reward", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Success to update reward", http.StatusOK, "success", campaign.FormatReward(updatedReward))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) CreateCampaignUpdate(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to post campaign update", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaign.CreateCampaignUpdateInput
+
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to post campaign update", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+	inputData.Actor = auditActor(c)
+
+	newUpdate, err := h.service.CreateCampaignUpdate(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to post campaign update", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Success to post campaign update", http.StatusOK, "success", campaign.FormatCampaignUpdate(newUpdate))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) GetCampaignUpdates(c *gin.Context) {
+	var input campaign.GetCampaignUpdatesInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get campaign updates.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Anonymous visitors see the public updates only.
+	if currentUser, ok := c.Get("currentUser"); ok {
+		input.User = currentUser.(user.User)
+	}
+
+	updates, err := h.service.GetCampaignUpdates(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to get campaign updates.", http.StatusNotFound, "error", errorMessage)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	response := helper.APIResponse("List of campaign updates.", http.StatusOK, "success", campaign.FormatCampaignUpdates(updates))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) UploadCampaignUpdateImage(c *gin.Context) {
+	var input campaign.CreateCampaignUpdateImageInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to upload update image.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+	input.Actor = auditActor(c)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		data := gin.H{"is_uploaded": false}
+		response := helper.APIResponse("Failed to upload update image.", http.StatusBadRequest, "error", data)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	fileReader, err := file.Open()
+	if err != nil {
+		response := helper.APIResponse("Failed to open uploaded file.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+	defer fileReader.Close()
+
+	params := uploader.UploadParams{
+		Folder:    "campaign-updates",
+		Overwrite: true,
+	}
+
+	uploadResult, err := h.cloudinary.Upload.Upload(context.Background(), fileReader, params)
+	if err != nil {
+		response := helper.APIResponse("Failed to upload update image.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	_, err = h.service.SaveCampaignUpdateImage(input, uploadResult.SecureURL)
+	if err != nil {
+		data := gin.H{"is_uploaded": false, "errors": err.Error()}
+		response := helper.APIResponse("Failed to upload update image.", http.StatusBadRequest, "error", data)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	data := gin.H{"is_uploaded": true}
+	response := helper.APIResponse("Update image uploaded successfully.", http.StatusOK, "success", data)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) FollowCampaign(c *gin.Context) {
+	var input campaign.FollowCampaignInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to follow campaign.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	err = h.service.FollowCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to follow campaign.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign followed.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) UnfollowCampaign(c *gin.Context) {
+	var input campaign.FollowCampaignInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to unfollow campaign.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	err = h.service.UnfollowCampaign(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to unfollow campaign.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign unfollowed.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) SubmitCampaign(c *gin.Context) {
+	var input campaign.SubmitCampaignInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to submit campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+	input.Actor = auditActor(c)
+
+	submittedCampaign, err := h.service.SubmitCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to submit campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign submitted for review", http.StatusOK, "success", campaign.FormatCampaign(submittedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) CloseCampaign(c *gin.Context) {
+	var input campaign.CloseCampaignInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to close campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+	input.Actor = auditActor(c)
+
+	closedCampaign, err := h.service.CloseCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to close campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign closed", http.StatusOK, "success", campaign.FormatCampaign(closedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) CancelCampaign(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to cancel campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var input campaign.CancelCampaignInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to cancel campaign", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.ID = inputID.ID
+	input.User = c.MustGet("currentUser").(user.User)
+	input.Actor = auditActor(c)
+
+	cancelledCampaign, err := h.service.CancelCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to cancel campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign cancelled", http.StatusOK, "success", campaign.FormatCampaign(cancelledCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) ArchiveCampaign(c *gin.Context) {
+	var input campaign.ArchiveCampaignInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to archive campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+	input.Actor = auditActor(c)
+
+	archivedCampaign, err := h.service.ArchiveCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to archive campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign archived", http.StatusOK, "success", campaign.FormatCampaign(archivedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) UpdateCampaignStatus(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to update campaign status", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var input campaign.UpdateCampaignStatusInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to update campaign status", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.ID = inputID.ID
+	input.Actor = auditActor(c)
+
+	updatedCampaign, err := h.service.UpdateCampaignStatus(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to update campaign status", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign status updated", http.StatusOK, "success", campaign.FormatCampaign(updatedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *campaignHandler) UploadImage(c *gin.Context) {
 	var input campaign.CreateCampaignImageInput
 
@@ -146,6 +772,7 @@ func (h *campaignHandler) UploadImage(c *gin.Context) {
 
 	currentUser := c.MustGet("currentUser").(user.User)
 	input.User = currentUser
+	input.Actor = auditActor(c)
 
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -176,11 +803,14 @@ func (h *campaignHandler) UploadImage(c *gin.Context) {
 	}
 
 	imageURL := uploadResult.SecureURL
+	input.PublicID = uploadResult.PublicID
 
 	_, err = h.service.SaveCampaignImage(input, imageURL)
 
 	if err != nil {
-		data := gin.H{"is_uploaded": false}
+		h.removeStoredImage(input.PublicID)
+
+		data := gin.H{"is_uploaded": false, "errors": err.Error()}
 		response := helper.APIResponse("Failed to upload campaign image.", http.StatusBadRequest, "error", data)
 		c.JSON(http.StatusBadRequest, response)
 		return
@@ -190,3 +820,108 @@ func (h *campaignHandler) UploadImage(c *gin.Context) {
 	response := helper.APIResponse("Campaign image uploaded successfully.", http.StatusOK, "success", data)
 	c.JSON(http.StatusOK, response)
 }
+
+func (h *campaignHandler) DeleteCampaignImage(c *gin.Context) {
+	var input campaign.GetCampaignImageDetailInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to delete campaign image.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	deletedImage, err := h.service.DeleteCampaignImage(input, currentUser, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to delete campaign image.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	h.removeStoredImage(deletedImage.PublicID)
+
+	response := helper.APIResponse("Campaign image deleted.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) SetPrimaryCampaignImage(c *gin.Context) {
+	var input campaign.GetCampaignImageDetailInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to set primary image.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	primaryImage, err := h.service.SetPrimaryCampaignImage(input, currentUser, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to set primary image.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Primary image updated.", http.StatusOK, "success", campaign.FormatCampaignImage(primaryImage))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) MoveCampaignImage(c *gin.Context) {
+	var inputID campaign.GetCampaignImageDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to move campaign image.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaign.MoveCampaignImageInput
+
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to move campaign image.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+	inputData.Actor = auditActor(c)
+
+	images, err := h.service.MoveCampaignImage(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to move campaign image.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign images reordered.", http.StatusOK, "success", campaign.FormatCampaignImages(images))
+	c.JSON(http.StatusOK, response)
+}
+
+// removeStoredImage deletes an uploaded file from Cloudinary. It is best
+// effort: the image is already gone from the campaign, so a failure only
+// leaves an orphaned file behind. Images uploaded before public IDs were
+// stored have none and are skipped.
+func (h *campaignHandler) removeStoredImage(publicID string) {
+	if publicID == "" {
+		return
+	}
+
+	_, err := h.cloudinary.Upload.Destroy(context.Background(), uploader.DestroyParams{PublicID: publicID})
+	if err != nil {
+		log.Println("Failed to remove stored image:", err)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/modules/category"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type categoryHandler struct {
+	service category.Service
+}
+
+func NewCategoryHandler(service category.Service) *categoryHandler {
+	return &categoryHandler{service}
+}
+
+func (h *categoryHandler) GetCategories(c *gin.Context) {
+	categories, err := h.service.GetCategories()
+	if err != nil {
+		response := helper.APIResponse("Error to get categories.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of categories.", http.StatusOK, "success", category.FormatCategories(categories))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *categoryHandler) CreateCategory(c *gin.Context) {
+	var input category.CreateCategoryInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to create category.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.Actor = auditActor(c)
+
+	newCategory, err := h.service.CreateCategory(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to create category.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Category has been created.", http.StatusCreated, "success", category.FormatCategory(newCategory))
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *categoryHandler) UpdateCategory(c *gin.Context) {
+	var inputID category.GetCategoryDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to update category.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData category.CreateCategoryInput
+
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to update category.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.Actor = auditActor(c)
+
+	updatedCategory, err := h.service.UpdateCategory(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to update category.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Category has been updated.", http.StatusOK, "success", category.FormatCategory(updatedCategory))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *categoryHandler) DeleteCategory(c *gin.Context) {
+	var inputID category.GetCategoryDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to delete category.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = h.service.DeleteCategory(inputID, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to delete category.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Category has been deleted.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
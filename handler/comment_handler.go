@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/modules/comment"
+	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type commentHandler struct {
+	service comment.Service
+}
+
+func NewCommentHandler(service comment.Service) *commentHandler {
+	return &commentHandler{service}
+}
+
+func (h *commentHandler) GetComments(c *gin.Context) {
+	var input comment.GetCommentsInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get comments.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to get comments.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	comments, total, input, err := h.service.GetComments(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to get comments.", http.StatusNotFound, "error", errorMessage)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("List of comments.", http.StatusOK, "success", comment.FormatComments(comments), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) CreateComment(c *gin.Context) {
+	var inputID comment.GetCommentDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to post comment.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var input comment.CreateCommentInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to post comment.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.CampaignID = inputID.ID
+	input.User = c.MustGet("currentUser").(user.User)
+	input.Actor = auditActor(c)
+
+	newComment, err := h.service.CreateComment(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to post comment.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Comment has been posted.", http.StatusCreated, "success", comment.FormatComment(newComment))
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *commentHandler) UpdateComment(c *gin.Context) {
+	var inputID comment.GetCommentDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to update comment.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData comment.UpdateCommentInput
+
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to update comment.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+	inputData.Actor = auditActor(c)
+
+	updatedComment, err := h.service.UpdateComment(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to update comment.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Comment has been updated.", http.StatusOK, "success", comment.FormatComment(updatedComment))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) DeleteComment(c *gin.Context) {
+	var inputID comment.GetCommentDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to delete comment.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	err = h.service.DeleteComment(inputID, currentUser, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to delete comment.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Comment has been deleted.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) ReportComment(c *gin.Context) {
+	var inputID comment.GetCommentDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to report comment.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var input comment.ReportCommentInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to report comment.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.ID = inputID.ID
+	input.User = c.MustGet("currentUser").(user.User)
+
+	err = h.service.ReportComment(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to report comment.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Comment has been reported.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) GetReportedComments(c *gin.Context) {
+	var input comment.GetReportedCommentsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to get reported comments.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	comments, total, input, err := h.service.GetReportedComments(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get reported comments.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("List of reported comments.", http.StatusOK, "success", comment.FormatReportedComments(comments), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) HideComment(c *gin.Context) {
+	var inputID comment.GetCommentDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to hide comment.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	hiddenComment, err := h.service.HideComment(inputID, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to hide comment.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Comment has been hidden.", http.StatusOK, "success", comment.FormatReportedComment(hiddenComment))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) UnhideComment(c *gin.Context) {
+	var inputID comment.GetCommentDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to unhide comment.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	visibleComment, err := h.service.UnhideComment(inputID, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to unhide comment.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Comment is visible again.", http.StatusOK, "success", comment.FormatReportedComment(visibleComment))
+	c.JSON(http.StatusOK, response)
+}
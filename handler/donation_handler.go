@@ -1,13 +1,13 @@
 package handler
 
 import (
+	"crowdfunding-minpro-alterra/modules/audit"
 	"crowdfunding-minpro-alterra/modules/donation"
 	"crowdfunding-minpro-alterra/modules/user"
 	"crowdfunding-minpro-alterra/utils/helper"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 type donationHandler struct {
@@ -65,13 +65,11 @@ func (h *donationHandler) GetUserDonations(c *gin.Context) {
 }
 
 func (h *donationHandler) CreateDonation(c *gin.Context) {
-	var logger = logrus.New()
 	var input donation.CreateDonationInput
 
 	err := c.ShouldBindJSON(&input)
 
 	if err != nil {
-		logger.Error(err)
 		errors := helper.FormatValidationError(err)
 		errorMessage := gin.H{"errors": errors}
 
@@ -80,23 +78,22 @@ func (h *donationHandler) CreateDonation(c *gin.Context) {
 
 		return
 	}
-	logger.Info("input: ", input)
 
 	currentUser := c.MustGet("currentUser").(user.User)
 	input.User = currentUser
+	input.Actor = auditActor(c)
 
 	newDonation, err := h.service.CreateDonation(input)
 
 	if err != nil {
-		logger.Error(err)
-		response := helper.APIResponse("Failed to create donation.", http.StatusBadRequest, "error", nil)
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to create donation.", http.StatusBadRequest, "error", errorMessage)
 		c.JSON(http.StatusBadRequest, response)
 
 		return
 	}
 
-	logger.Info("newDonation: ", newDonation)
-
 	response := helper.APIResponse("Donation created.", http.StatusOK, "success", donation.FormatDonation(newDonation))
 	c.JSON(http.StatusOK, response)
 }
@@ -113,6 +110,10 @@ func (h *donationHandler) GetNotification(c *gin.Context) {
 		return
 	}
 
+	// Payment notifications come from the gateway, so the actor is the
+	// system, identified only by where the request came from.
+	input.Actor = audit.Actor{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+
 	err = h.service.ProcessPayment(input)
 
 	if err != nil {
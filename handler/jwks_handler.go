@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/utils/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type jwksHandler struct {
+	authService auth.Service
+}
+
+func NewJWKSHandler(authService auth.Service) *jwksHandler {
+	return &jwksHandler{authService}
+}
+
+// GetJWKS serves the public signing keys in the standard JWK Set format, not
+// wrapped in helper.APIResponse, so off-the-shelf JWT libraries can use it.
+func (h *jwksHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/modules/notification"
+	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type notificationHandler struct {
+	service notification.Service
+}
+
+func NewNotificationHandler(service notification.Service) *notificationHandler {
+	return &notificationHandler{service}
+}
+
+func (h *notificationHandler) GetNotifications(c *gin.Context) {
+	var input notification.GetNotificationsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to get notifications.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.UserID = c.MustGet("currentUser").(user.User).ID
+
+	notifications, total, input, err := h.service.GetNotifications(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to get notifications.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("List of notifications.", http.StatusOK, "success", notification.FormatNotifications(notifications), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *notificationHandler) MarkRead(c *gin.Context) {
+	var input notification.MarkReadInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to mark notification as read.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.UserID = c.MustGet("currentUser").(user.User).ID
+
+	readNotification, err := h.service.MarkRead(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to mark notification as read.", http.StatusNotFound, "error", errorMessage)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	response := helper.APIResponse("Notification marked as read.", http.StatusOK, "success", notification.FormatNotification(readNotification))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *notificationHandler) MarkAllRead(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	err := h.service.MarkAllRead(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to mark notifications as read.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("All notifications marked as read.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/modules/apikey"
+	"crowdfunding-minpro-alterra/modules/session"
+	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/auth"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"crowdfunding-minpro-alterra/utils/oidc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+type oidcHandler struct {
+	providers      map[string]*oidc.Provider
+	stateStore     oidc.StateStore
+	userService    user.Service
+	authService    auth.Service
+	sessionService session.Service
+	apiKeyService  apikey.Service
+}
+
+func NewOIDCHandler(providers map[string]*oidc.Provider, stateStore oidc.StateStore, userService user.Service, authService auth.Service, sessionService session.Service, apiKeyService apikey.Service) *oidcHandler {
+	return &oidcHandler{providers, stateStore, userService, authService, sessionService, apiKeyService}
+}
+
+func (h *oidcHandler) Login(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		response := helper.APIResponse("Unknown login provider.", http.StatusNotFound, "error", nil)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	state, err := helper.GenerateRandomToken(24)
+	if err != nil {
+		response := helper.APIResponse("Failed to start login.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	nonce, err := helper.GenerateRandomToken(24)
+	if err != nil {
+		response := helper.APIResponse("Failed to start login.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	codeVerifier, codeChallenge, err := oidc.NewPKCE()
+	if err != nil {
+		response := helper.APIResponse("Failed to start login.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, codeChallenge)
+	if err != nil {
+		response := helper.APIResponse("Login provider is unavailable.", http.StatusBadGateway, "error", nil)
+		c.JSON(http.StatusBadGateway, response)
+		return
+	}
+
+	err = h.stateStore.Save(state, oidc.AuthState{
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	})
+	if err != nil {
+		response := helper.APIResponse("Failed to start login.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+func (h *oidcHandler) Callback(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		response := helper.APIResponse("Unknown login provider.", http.StatusNotFound, "error", nil)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	if c.Query("error") != "" {
+		errorMessage := gin.H{"errors": c.Query("error")}
+
+		response := helper.APIResponse("Login failed.", http.StatusUnauthorized, "error", errorMessage)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	authState, ok := h.stateStore.Consume(c.Query("state"))
+	if !ok || authState.Provider != provider.Name() || c.Query("code") == "" {
+		response := helper.APIResponse("Login failed.", http.StatusBadRequest, "error", gin.H{"errors": "Invalid or expired login state"})
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	tokenResponse, err := provider.Exchange(c.Query("code"), authState.CodeVerifier)
+	if err != nil {
+		response := helper.APIResponse("Login failed.", http.StatusUnauthorized, "error", nil)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(tokenResponse.IDToken, authState.Nonce)
+	if err != nil {
+		response := helper.APIResponse("Login failed.", http.StatusUnauthorized, "error", nil)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	loggedinUser, reclaimed, err := h.userService.LoginWithOIDC(user.OIDCLoginInput{
+		Provider:      provider.Name(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Actor:         auditActor(c),
+	})
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Login failed.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	// Whoever registered the unverified account may still hold a session or
+	// an API key from before it was reclaimed.
+	if reclaimed {
+		err = h.sessionService.RevokeUserSessions(loggedinUser.ID)
+		if err == nil {
+			err = h.apiKeyService.RevokeUserAPIKeys(loggedinUser.ID)
+		}
+
+		if err != nil {
+			response := helper.APIResponse("Login failed.", http.StatusInternalServerError, "error", nil)
+			c.JSON(http.StatusInternalServerError, response)
+			return
+		}
+	}
+
+	if loggedinUser.TwoFactorEnabled() {
+		challengeToken, err := h.authService.GenerateChallengeToken(loggedinUser.ID)
+		if err != nil {
+			response := helper.APIResponse("Login failed", http.StatusBadRequest, "error", nil)
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		response := helper.APIResponse("Two-factor authentication required.", http.StatusOK, "success", user.FormatTwoFactorChallenge(challengeToken))
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, refreshToken, err := startSession(c, h.authService, h.sessionService, loggedinUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Login failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Login successfuly.", http.StatusOK, "success", user.FormatUser(loggedinUser, token, refreshToken))
+	c.JSON(http.StatusOK, response)
+}
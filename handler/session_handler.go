@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"crowdfunding-minpro-alterra/modules/session"
+	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/auth"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sessionHandler struct {
+	sessionService session.Service
+	authService    auth.Service
+}
+
+func NewSessionHandler(sessionService session.Service, authService auth.Service) *sessionHandler {
+	return &sessionHandler{sessionService, authService}
+}
+
+func (h *sessionHandler) RefreshSession(c *gin.Context) {
+	var input session.RefreshTokenInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to refresh session.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	refreshToken, newRefreshToken, err := h.sessionService.RotateRefreshToken(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to refresh session.", http.StatusUnauthorized, "error", errorMessage)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	token, err := h.authService.GenerateToken(refreshToken.UserID, refreshToken.FamilyID)
+	if err != nil {
+		response := helper.APIResponse("Failed to refresh session.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Session refreshed.", http.StatusOK, "success", session.FormatToken(token, newRefreshToken))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *sessionHandler) Logout(c *gin.Context) {
+	var input session.RefreshTokenInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Logout failed.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	err = h.sessionService.RevokeRefreshToken(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Logout failed.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Logout successfuly.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *sessionHandler) GetSessions(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	sessions, err := h.sessionService.GetUserSessions(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to get sessions.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of sessions.", http.StatusOK, "success", session.FormatSessions(sessions, c.GetString("currentSessionID")))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *sessionHandler) RevokeSession(c *gin.Context) {
+	var input session.RevokeSessionInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to revoke session.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+	input.UserID = currentUser.ID
+
+	err = h.sessionService.RevokeSession(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to revoke session.", http.StatusNotFound, "error", errorMessage)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	response := helper.APIResponse("Session has been revoked.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *sessionHandler) RevokeOtherSessions(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+	currentSessionID := c.GetString("currentSessionID")
+
+	// Without a current session (API key requests) every session would be
+	// revoked, which is not what "other sessions" means.
+	if currentSessionID == "" {
+		response := helper.APIResponse("Failed to revoke other sessions.", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err := h.sessionService.RevokeOtherSessions(currentUser.ID, currentSessionID)
+	if err != nil {
+		response := helper.APIResponse("Failed to revoke other sessions.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response := helper.APIResponse("Other sessions have been revoked.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
@@ -2,10 +2,22 @@ package handler
 
 import (
 	"context"
+	"crowdfunding-minpro-alterra/modules/apikey"
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/campaign"
+	"crowdfunding-minpro-alterra/modules/donation"
+	"crowdfunding-minpro-alterra/modules/loginguard"
+	"crowdfunding-minpro-alterra/modules/session"
 	"crowdfunding-minpro-alterra/modules/user"
 	"crowdfunding-minpro-alterra/utils/auth"
 	"crowdfunding-minpro-alterra/utils/helper"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
 
 	"github.com/cloudinary/cloudinary-go"
 	"github.com/cloudinary/cloudinary-go/api/uploader"
@@ -13,13 +25,19 @@ import (
 )
 
 type userHandler struct {
-	userService user.Service
-	authService auth.Service
-	cloudinary  *cloudinary.Cloudinary
+	userService       user.Service
+	authService       auth.Service
+	sessionService    session.Service
+	apiKeyService     apikey.Service
+	loginGuardService loginguard.Service
+	campaignService   campaign.Service
+	donationService   donation.Service
+	auditService      audit.Service
+	cloudinary        *cloudinary.Cloudinary
 }
 
-func NewUserHandler(userService user.Service, authService auth.Service, cloudinary *cloudinary.Cloudinary) *userHandler {
-	return &userHandler{userService, authService, cloudinary}
+func NewUserHandler(userService user.Service, authService auth.Service, sessionService session.Service, apiKeyService apikey.Service, loginGuardService loginguard.Service, campaignService campaign.Service, donationService donation.Service, auditService audit.Service, cloudinary *cloudinary.Cloudinary) *userHandler {
+	return &userHandler{userService, authService, sessionService, apiKeyService, loginGuardService, campaignService, donationService, auditService, cloudinary}
 }
 
 func (h *userHandler) RegisterUser(c *gin.Context) {
@@ -37,16 +55,20 @@ func (h *userHandler) RegisterUser(c *gin.Context) {
 		return
 	}
 
+	input.Actor = auditActor(c)
+
 	newUser, err := h.userService.RegisterUser(input)
 	
 	if err != nil {
-		response := helper.APIResponse("Register account failed", http.StatusBadRequest, "error", nil)
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Register account failed", http.StatusBadRequest, "error", errorMessage)
 		c.JSON(http.StatusBadRequest, response)
 
 		return
 	}
 
-	token, err := h.authService.GenerateToken(newUser.ID)
+	token, refreshToken, err := startSession(c, h.authService, h.sessionService, newUser.ID)
 
 	if err != nil {
 		response := helper.APIResponse("Register account failed", http.StatusBadRequest, "error", nil)
@@ -55,7 +77,7 @@ func (h *userHandler) RegisterUser(c *gin.Context) {
 		return
 	}
 
-	formatter := user.FormatUser(newUser, token)
+	formatter := user.FormatUser(newUser, token, refreshToken)
 
 	response := helper.APIResponse("Account has been registered.", http.StatusOK, "success", formatter)
 
@@ -76,9 +98,37 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
+	err = h.loginGuardService.Check(input.Email, c.ClientIP())
+
+	if err != nil {
+		h.rejectGuardedLogin(c, err, 0, input.Email)
+
+		return
+	}
+
+	input.Actor = auditActor(c)
+
 	loggedinUser, err := h.userService.Login(input)
 
+	if errors.Is(err, user.ErrUserSuspended) {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Login failed.", http.StatusForbidden, "error", errorMessage)
+		c.JSON(http.StatusForbidden, response)
+
+		return
+	}
+
 	if err != nil {
+		if guardErr := h.loginGuardService.RecordFailure(loginguard.FailedLoginInput{
+			Email:     input.Email,
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Reason:    err.Error(),
+		}); guardErr != nil {
+			log.Println("Failed to record failed login:", guardErr)
+		}
+
 		errorMessage := gin.H{"errors": err.Error()}
 
 		response := helper.APIResponse("Login failed.", http.StatusUnprocessableEntity, "error", errorMessage)
@@ -87,8 +137,31 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.GenerateToken(loggedinUser.ID)
-	
+	// With two-factor authentication the password alone does not clear the
+	// failure count; VerifyTwoFactorLogin does once the code checks out, so
+	// the guard also limits guesses at the code.
+	if loggedinUser.TwoFactorEnabled() {
+		challengeToken, err := h.authService.GenerateChallengeToken(loggedinUser.ID)
+
+		if err != nil {
+			response := helper.APIResponse("Login failed", http.StatusBadRequest, "error", nil)
+			c.JSON(http.StatusBadRequest, response)
+
+			return
+		}
+
+		response := helper.APIResponse("Two-factor authentication required.", http.StatusOK, "success", user.FormatTwoFactorChallenge(challengeToken))
+		c.JSON(http.StatusOK, response)
+
+		return
+	}
+
+	if err := h.loginGuardService.RecordSuccess(input.Email); err != nil {
+		log.Println("Failed to clear failed logins:", err)
+	}
+
+	token, refreshToken, err := startSession(c, h.authService, h.sessionService, loggedinUser.ID)
+
 	if err != nil {
 		response := helper.APIResponse("Login failed", http.StatusBadRequest, "error", nil)
 		c.JSON(http.StatusBadRequest, response)
@@ -96,7 +169,7 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
-	formatter := user.FormatUser(loggedinUser, token)
+	formatter := user.FormatUser(loggedinUser, token, refreshToken)
 
 	response := helper.APIResponse("Login successfuly.", http.StatusOK, "success", formatter)
 
@@ -176,7 +249,7 @@ func (h *userHandler) UploadAvatar(c *gin.Context) {
 	currentUser := c.MustGet("currentUser").(user.User)
 	userID := currentUser.ID
 	currentUser.AvatarFileName = imageURL
-	_, err = h.userService.SaveAvatar(userID, imageURL)
+	_, err = h.userService.SaveAvatar(userID, imageURL, auditActor(c))
 	if err != nil {
 		response := helper.APIResponse("Failed to save avatar image URL.", http.StatusInternalServerError, "error", nil)
 		c.JSON(http.StatusInternalServerError, response)
@@ -188,6 +261,122 @@ func (h *userHandler) UploadAvatar(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *userHandler) RemoveAvatar(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+	previousAvatar := currentUser.AvatarFileName
+
+	updatedUser, err := h.userService.RemoveAvatar(currentUser.ID, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to remove avatar image.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// The stored image is removed on a best-effort basis; the profile no
+	// longer references it either way.
+	if publicID := avatarPublicID(previousAvatar); publicID != "" {
+		_, _ = h.cloudinary.Upload.Destroy(context.Background(), uploader.DestroyParams{PublicID: publicID})
+	}
+
+	formatter := user.GetFormatUser(updatedUser)
+	response := helper.APIResponse("Avatar removed successfully.", http.StatusOK, "success", formatter)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) UpdateProfile(c *gin.Context) {
+	var input user.FormUpdateUserInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to update profile.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+	input.ID = currentUser.ID
+	input.Actor = auditActor(c)
+
+	updatedUser, err := h.userService.UpdateUser(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to update profile.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	formatter := user.GetFormatUser(updatedUser)
+	response := helper.APIResponse("Profile has been updated.", http.StatusOK, "success", formatter)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ChangePassword(c *gin.Context) {
+	var input user.ChangePasswordInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to change password.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+	input.ID = currentUser.ID
+	input.Actor = auditActor(c)
+
+	updatedUser, err := h.userService.ChangePassword(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to change password.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Other devices and API keys may belong to whoever knew the old
+	// password. A request made with an API key has no session to keep.
+	if currentSessionID := c.GetString("currentSessionID"); currentSessionID != "" {
+		err = h.sessionService.RevokeOtherSessions(currentUser.ID, currentSessionID)
+	} else {
+		err = h.sessionService.RevokeUserSessions(currentUser.ID)
+	}
+
+	if err == nil {
+		err = h.apiKeyService.RevokeUserAPIKeys(currentUser.ID)
+	}
+
+	if err != nil {
+		response := helper.APIResponse("Failed to change password.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	formatter := user.GetFormatUser(updatedUser)
+	response := helper.APIResponse("Password has been changed.", http.StatusOK, "success", formatter)
+	c.JSON(http.StatusOK, response)
+}
+
+// avatarPublicID derives the Cloudinary public ID ("avatars/<name>") from a
+// secure URL returned by UploadAvatar.
+func avatarPublicID(imageURL string) string {
+	index := strings.Index(imageURL, "/avatars/")
+	if index == -1 {
+		return ""
+	}
+
+	publicID := imageURL[index+1:]
+	return strings.TrimSuffix(publicID, path.Ext(publicID))
+}
+
 func (h *userHandler) FetchUser(c *gin.Context) {
 	currentUser := c.MustGet("currentUser").(user.User)
 
@@ -198,22 +387,102 @@ func (h *userHandler) FetchUser(c *gin.Context) {
 }
 
 func (h * userHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.userService.GetAllUsers()
+	var input user.GetUsersInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get all users", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	users, total, input, err := h.userService.SearchUsers(input)
 
 	if err != nil {
-		response := helper.APIResponse("Error to get all users", http.StatusBadRequest, "error", nil)
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Error to get all users", http.StatusBadRequest, "error", errorMessage)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	currentUser := c.MustGet("currentUser").(user.User)
-	if currentUser.ID != 2 {
-		response := helper.APIResponse("You are not authorized", http.StatusForbidden, "error", nil)
-		c.JSON(http.StatusForbidden, response)
+	pagination := helper.NewPagination(input.Page, input.Limit, total)
+
+	response := helper.APIResponseWithPagination("List of all users", http.StatusOK, "success", user.FormatAdminUsers(users), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) SuspendUser(c *gin.Context) {
+	var inputID struct {
+		ID int `uri:"id" binding:"required"`
+	}
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to suspend user", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var input user.SuspendUserInput
+
+	// The reason is optional, so an empty body is accepted.
+	if c.Request.ContentLength > 0 {
+		err = c.ShouldBindJSON(&input)
+		if err != nil {
+			errorMessage := gin.H{"errors": err.Error()}
+
+			response := helper.APIResponse("Failed to suspend user", http.StatusUnprocessableEntity, "error", errorMessage)
+			c.JSON(http.StatusUnprocessableEntity, response)
+			return
+		}
+	}
+
+	input.ID = inputID.ID
+	input.Actor = auditActor(c)
+
+	suspendedUser, err := h.userService.SuspendUser(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to suspend user", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	response := helper.APIResponse("List of all users", http.StatusOK, "success", user.GetFormatUsers(users))
+	// Lifting the suspension does not bring back the user's sessions or API
+	// keys; they have to sign in and create new keys.
+	_ = h.sessionService.RevokeUserSessions(suspendedUser.ID)
+	_ = h.apiKeyService.RevokeUserAPIKeys(suspendedUser.ID)
+
+	response := helper.APIResponse("User suspended successfully", http.StatusOK, "success", user.FormatAdminUser(suspendedUser))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) UnsuspendUser(c *gin.Context) {
+	var input struct {
+		ID int `uri:"id" binding:"required"`
+	}
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to unsuspend user", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	unsuspendedUser, err := h.userService.UnsuspendUser(input.ID, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to unsuspend user", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("User unsuspended successfully", http.StatusOK, "success", user.FormatAdminUser(unsuspendedUser))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -229,20 +498,513 @@ func (h *userHandler) DeleteUser(c *gin.Context) {
 			return
 	}
 
-	currentUser := c.MustGet("currentUser").(user.User)
-	if currentUser.ID != 2 {
-			response := helper.APIResponse("You are not authorized", http.StatusForbidden, "error", nil)
-			c.JSON(http.StatusForbidden, response)
-			return
-	}
-
-	err = h.userService.DeleteUser(input.ID)
+	err = h.userService.DeleteUser(input.ID, auditActor(c))
 	if err != nil {
-			response := helper.APIResponse("Failed to delete user", http.StatusBadRequest, "error", nil)
+			errorMessage := gin.H{"errors": err.Error()}
+
+			response := helper.APIResponse("Failed to delete user", http.StatusBadRequest, "error", errorMessage)
 			c.JSON(http.StatusBadRequest, response)
 			return
 	}
 
+	// Revoked rather than left in place, so restoring the user does not
+	// bring old keys back.
+	_ = h.sessionService.RevokeUserSessions(input.ID)
+	_ = h.apiKeyService.RevokeUserAPIKeys(input.ID)
+
 	response := helper.APIResponse("User deleted successfully", http.StatusOK, "success", nil)
 	c.JSON(http.StatusOK, response)
 }
+
+func (h *userHandler) RestoreUser(c *gin.Context) {
+	var input struct {
+		ID int `uri:"id" binding:"required"`
+	}
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to restore user", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	restoredUser, err := h.userService.RestoreUser(input.ID, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to restore user", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("User restored successfully", http.StatusOK, "success", user.GetFormatUser(restoredUser))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) EraseUser(c *gin.Context) {
+	var input struct {
+		ID int `uri:"id" binding:"required"`
+	}
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to erase user", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = h.userService.EraseUser(input.ID, auditActor(c))
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to erase user", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	_ = h.sessionService.RevokeUserSessions(input.ID)
+	_ = h.apiKeyService.RevokeUserAPIKeys(input.ID)
+
+	response := helper.APIResponse("User erased successfully", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ExportUserData(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	campaigns, err := h.campaignService.GetCampaigns(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to export user data", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	donations, err := h.donationService.GetDonationsByUserID(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to export user data", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	data := gin.H{
+		"profile":   user.FormatUserExport(currentUser),
+		"campaigns": campaign.FormatCampaigns(campaigns),
+		"donations": donation.FormatUserDonations(donations),
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.json"`, currentUser.ID))
+
+	response := helper.APIResponse("User data export", http.StatusOK, "success", data)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) GetRoles(c *gin.Context) {
+	response := helper.APIResponse("List of roles", http.StatusOK, "success", user.FormatRoles(user.Roles()))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) AssignRole(c *gin.Context) {
+	var inputID struct {
+		ID int `uri:"id" binding:"required"`
+	}
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Failed to assign role", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var input user.AssignRoleInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to assign role", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.ID = inputID.ID
+	input.Actor = auditActor(c)
+
+	updatedUser, err := h.userService.AssignRole(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to assign role", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Role assigned successfully", http.StatusOK, "success", user.GetFormatUser(updatedUser))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ForgotPassword(c *gin.Context) {
+	var input user.ForgotPasswordInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to request password reset.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	// Failures are only logged: answering differently when the email could
+	// not be sent would tell the caller that the address is registered.
+	err = h.userService.ForgotPassword(input)
+	if err != nil {
+		log.Println("Failed to send password reset:", err)
+	}
+
+	response := helper.APIResponse("If the email is registered, a password reset link has been sent.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ResetPassword(c *gin.Context) {
+	var input user.ResetPasswordInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to reset password.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	input.Actor = auditActor(c)
+
+	updatedUser, err := h.userService.ResetPassword(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to reset password.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = h.sessionService.RevokeUserSessions(updatedUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to reset password.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	// A reset usually means the account was compromised, so keys created
+	// with the old password stop working as well.
+	err = h.apiKeyService.RevokeUserAPIKeys(updatedUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to reset password.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response := helper.APIResponse("Password has been reset.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) VerifyEmail(c *gin.Context) {
+	var input user.VerifyEmailInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to verify email.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	verifiedUser, err := h.userService.VerifyEmail(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to verify email.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Email has been verified.", http.StatusOK, "success", user.GetFormatUser(verifiedUser))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ResendVerificationEmail(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	err := h.userService.ResendVerificationEmail(currentUser.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to send verification email.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Verification email has been sent.", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) UnlockUser(c *gin.Context) {
+	var input struct {
+		ID int `uri:"id" binding:"required"`
+	}
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to unlock user", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	lockedUser, err := h.userService.GetUserByID(input.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to unlock user", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = h.loginGuardService.Unlock(lockedUser.Email)
+	if err != nil {
+		response := helper.APIResponse("Failed to unlock user", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	_, _ = h.auditService.Record(audit.RecordInput{
+		Actor:      auditActor(c),
+		Action:     audit.ActionUserUnlock,
+		TargetType: audit.TargetUser,
+		TargetID:   lockedUser.ID,
+	})
+
+	response := helper.APIResponse("User unlocked successfully", http.StatusOK, "success", user.GetFormatUser(lockedUser))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) GetFailedLogins(c *gin.Context) {
+	var input loginguard.GetFailedLoginsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		response := helper.APIResponse("Error to get failed logins", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	failedLogins, err := h.loginGuardService.GetFailedLogins(input)
+	if err != nil {
+		response := helper.APIResponse("Error to get failed logins", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of failed logins", http.StatusOK, "success", loginguard.FormatFailedLogins(failedLogins))
+	c.JSON(http.StatusOK, response)
+}
+
+// rejectGuardedLogin answers a login the guard did not let through: 429 with
+// Retry-After when the email or address is locked, 500 when the guard itself
+// failed.
+func (h *userHandler) rejectGuardedLogin(c *gin.Context, err error, userID int, email string) {
+	var lockedError *loginguard.LockedError
+
+	if !errors.As(err, &lockedError) {
+		response := helper.APIResponse("Login failed.", http.StatusInternalServerError, "error", nil)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	_, _ = h.auditService.Record(audit.RecordInput{
+		Actor:      auditActor(c),
+		Action:     audit.ActionLoginBlocked,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+		Metadata:   map[string]interface{}{"email": email},
+	})
+
+	c.Header("Retry-After", strconv.Itoa(int(lockedError.RetryAfter.Seconds())+1))
+
+	errorMessage := gin.H{"errors": err.Error()}
+
+	response := helper.APIResponse("Login failed.", http.StatusTooManyRequests, "error", errorMessage)
+	c.JSON(http.StatusTooManyRequests, response)
+}
+
+func (h *userHandler) VerifyTwoFactorLogin(c *gin.Context) {
+	var input user.TwoFactorLoginInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Login failed.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	userID, err := h.authService.ValidateChallengeToken(input.ChallengeToken)
+	if err != nil {
+		response := helper.APIResponse("Login failed.", http.StatusUnauthorized, "error", nil)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	challengedUser, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		response := helper.APIResponse("Login failed.", http.StatusUnauthorized, "error", nil)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	err = h.loginGuardService.Check(challengedUser.Email, c.ClientIP())
+	if err != nil {
+		h.rejectGuardedLogin(c, err, challengedUser.ID, challengedUser.Email)
+		return
+	}
+
+	input.ID = userID
+	input.Actor = auditActor(c)
+
+	loggedinUser, err := h.userService.VerifyTwoFactor(input)
+	if err != nil {
+		if guardErr := h.loginGuardService.RecordFailure(loginguard.FailedLoginInput{
+			Email:     challengedUser.Email,
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Reason:    err.Error(),
+		}); guardErr != nil {
+			log.Println("Failed to record failed login:", guardErr)
+		}
+
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Login failed.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	if err := h.loginGuardService.RecordSuccess(loggedinUser.Email); err != nil {
+		log.Println("Failed to clear failed logins:", err)
+	}
+
+	token, refreshToken, err := startSession(c, h.authService, h.sessionService, loggedinUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Login failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Login successfuly.", http.StatusOK, "success", user.FormatUser(loggedinUser, token, refreshToken))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) EnrollTwoFactor(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	enrolledUser, provisioningURI, err := h.userService.EnrollTwoFactor(currentUser.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to set up two-factor authentication.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	formatter := user.FormatTwoFactorEnrollment(enrolledUser.TOTPSecret, provisioningURI)
+
+	response := helper.APIResponse("Scan the provisioning URI with your authenticator app, then confirm with a code.", http.StatusOK, "success", formatter)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ConfirmTwoFactor(c *gin.Context) {
+	var input user.ConfirmTwoFactorInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to enable two-factor authentication.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+	input.ID = currentUser.ID
+	input.Actor = auditActor(c)
+
+	recoveryCodes, err := h.userService.ConfirmTwoFactor(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to enable two-factor authentication.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	data := gin.H{"recovery_codes": recoveryCodes}
+
+	response := helper.APIResponse("Two-factor authentication enabled. Store the recovery codes somewhere safe.", http.StatusOK, "success", data)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) DisableTwoFactor(c *gin.Context) {
+	var input user.DisableTwoFactorInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Failed to disable two-factor authentication.", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+	input.ID = currentUser.ID
+	input.Actor = auditActor(c)
+
+	updatedUser, err := h.userService.DisableTwoFactor(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+
+		response := helper.APIResponse("Failed to disable two-factor authentication.", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Two-factor authentication disabled.", http.StatusOK, "success", user.GetFormatUser(updatedUser))
+	c.JSON(http.StatusOK, response)
+}
+
+// startSession records a session for the device making the request and
+// issues the access token and refresh token returned to a user who has
+// fully authenticated.
+func startSession(c *gin.Context, authService auth.Service, sessionService session.Service, userID int) (string, string, error) {
+	newSession, refreshToken, err := sessionService.CreateSession(session.CreateSessionInput{
+		UserID:    userID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := authService.GenerateToken(userID, newSession.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
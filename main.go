@@ -1,23 +1,37 @@
 package main
 
 import (
+	"context"
+	"crowdfunding-minpro-alterra/config"
 	"crowdfunding-minpro-alterra/database"
 	"crowdfunding-minpro-alterra/handler"
+	"crowdfunding-minpro-alterra/modules/apikey"
+	"crowdfunding-minpro-alterra/modules/audit"
 	"crowdfunding-minpro-alterra/modules/campaign"
+	"crowdfunding-minpro-alterra/modules/category"
+	"crowdfunding-minpro-alterra/modules/comment"
 	"crowdfunding-minpro-alterra/modules/chat"
 	"crowdfunding-minpro-alterra/modules/donation"
+	"crowdfunding-minpro-alterra/modules/loginguard"
+	"crowdfunding-minpro-alterra/modules/notification"
 	"crowdfunding-minpro-alterra/modules/payment"
+	"crowdfunding-minpro-alterra/modules/session"
 	"crowdfunding-minpro-alterra/modules/user"
 	"crowdfunding-minpro-alterra/utils/auth"
 	"crowdfunding-minpro-alterra/utils/helper"
+	"crowdfunding-minpro-alterra/utils/mailer"
+	"crowdfunding-minpro-alterra/utils/oidc"
+	"crowdfunding-minpro-alterra/utils/password"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -29,56 +43,177 @@ func main() {
 
 	userRepository := user.NewRepository(db)
 	campaignRepository := campaign.NewRepository(db)
+	categoryRepository := category.NewRepository(db)
 	donationRepository := donation.NewRepository(db)
 	chatRepository := chat.NewChatRepository()
+	sessionRepository := session.NewRepository(db)
+	loginGuardRepository := loginguard.NewRepository(db)
+	apiKeyRepository := apikey.NewRepository(db)
+	auditRepository := audit.NewRepository(db)
+	notificationRepository := notification.NewRepository(db)
+	commentRepository := comment.NewRepository(db)
 
-	userService := user.NewService(userRepository)
-	authService := auth.NewService()
-	campaignService := campaign.NewService(campaignRepository)
+	mailService := mailer.NewFromEnv()
+
+	passwordHasher, err := password.NewHasherFromEnv()
+	if err != nil {
+		fmt.Println("Failed to configure password hashing:", err)
+		return
+	}
+
+	passwordPolicy, err := password.NewPolicyFromEnv()
+	if err != nil {
+		fmt.Println("Failed to load password policy:", err)
+		return
+	}
+
+	auditService := audit.NewService(auditRepository)
+	userService := user.NewService(userRepository, mailService, passwordHasher, passwordPolicy, auditService)
+
+	// ADMIN_EMAIL names an already registered account to make the first
+	// admin. It is ignored once any admin exists.
+	if adminEmail := config.GetEnv("ADMIN_EMAIL", ""); adminEmail != "" {
+		_, err := userService.BootstrapAdmin(adminEmail)
+		if err != nil {
+			fmt.Println("Failed to bootstrap the admin account:", err)
+		}
+	}
+	authService, err := auth.NewService()
+	if err != nil {
+		fmt.Println("Failed to load JWT signing keys:", err)
+		return
+	}
+
+	loginGuardService := loginguard.NewService(loginGuardRepository, initLoginGuardStore(db))
+	sessionService := session.NewService(sessionRepository, config.GetDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour))
+	apiKeyService := apikey.NewService(apiKeyRepository)
+	notificationService := notification.NewService(notificationRepository)
+	campaignService := campaign.NewService(campaignRepository, categoryRepository, auditService, mailService, notificationService)
+	categoryService := category.NewService(categoryRepository, auditService)
 	paymentService := payment.NewService()
-	donationService := donation.NewService(donationRepository, campaignRepository, paymentService)
+	donationService := donation.NewService(donationRepository, campaignRepository, paymentService, auditService)
+	commentService := comment.NewService(commentRepository, campaignRepository, donationRepository, auditService)
 	chatUC := chat.NewChatUseCase(chatRepository)
 
+	go campaign.NewScheduler(campaignService, config.GetDuration("CAMPAIGN_SCHEDULER_INTERVAL", time.Minute)).Run(context.Background())
+
 	cloudinary, err := initCloudinary()
 	if err != nil {
 		fmt.Println("Failed to initialize Cloudinary:", err)
 		return
 	}
 
-	userHandler := handler.NewUserHandler(userService, authService, cloudinary)
+	userHandler := handler.NewUserHandler(userService, authService, sessionService, apiKeyService, loginGuardService, campaignService, donationService, auditService, cloudinary)
 	campaignHandler := handler.NewCampaignHandler(campaignService, cloudinary)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	commentHandler := handler.NewCommentHandler(commentService)
 	donationHandler := handler.NewDonationHandler(donationService)
 	chatHandler := handler.NewChatHandler(chatUC)
+	sessionHandler := handler.NewSessionHandler(sessionService, authService)
+	jwksHandler := handler.NewJWKSHandler(authService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	oidcHandler := handler.NewOIDCHandler(oidc.ProvidersFromEnv(), oidc.NewMemoryStateStore(), userService, authService, sessionService, apiKeyService)
 
 	router := gin.Default()
 	router.Use(cors.Default())
 	
 	router.Static("/images", "./images")
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
 
 	api := router.Group("/api/v1")
 
 	api.POST("/chatbot", chatHandler.HandleChat)
 
-	api.GET("/admin/users", authMiddleware(authService, userService), userHandler.GetAllUsers)
-	api.DELETE("/admin/users/:id", authMiddleware(authService, userService), userHandler.DeleteUser)
-	api.GET("/admin/campaigns", campaignHandler.GetCampaigns)
+	api.GET("/admin/users", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserRead), userHandler.GetAllUsers)
+	api.DELETE("/admin/users/:id", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserDelete), userHandler.DeleteUser)
+	api.POST("/admin/users/:id/restore", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserDelete), userHandler.RestoreUser)
+	api.POST("/admin/users/:id/erase", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserDelete), userHandler.EraseUser)
+	api.PUT("/admin/users/:id/role", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionRoleAssign), userHandler.AssignRole)
+	api.POST("/admin/users/:id/suspend", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserSuspend), userHandler.SuspendUser)
+	api.POST("/admin/users/:id/unsuspend", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserSuspend), userHandler.UnsuspendUser)
+	api.POST("/admin/users/:id/unlock", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserUnlock), userHandler.UnlockUser)
+	api.GET("/admin/failed-logins", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionUserRead), userHandler.GetFailedLogins)
+	api.GET("/admin/audit-logs", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionAuditRead), auditHandler.GetAuditLogs)
+	api.GET("/admin/audit-logs/export", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionAuditRead), auditHandler.ExportAuditLogs)
+	api.GET("/admin/roles", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionRoleAssign), userHandler.GetRoles)
+	api.GET("/admin/campaigns", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCampaignModerate), campaignHandler.GetAdminCampaigns)
+	api.GET("/admin/campaigns/review-queue", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCampaignModerate), campaignHandler.GetReviewQueue)
+	api.POST("/admin/campaigns/:id/approve", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCampaignModerate), campaignHandler.ApproveCampaign)
+	api.POST("/admin/campaigns/:id/reject", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCampaignModerate), campaignHandler.RejectCampaign)
+	api.PUT("/admin/campaigns/:id/status", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCampaignModerate), campaignHandler.UpdateCampaignStatus)
+	api.POST("/admin/categories", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCategoryManage), categoryHandler.CreateCategory)
+	api.PUT("/admin/categories/:id", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCategoryManage), categoryHandler.UpdateCategory)
+	api.DELETE("/admin/categories/:id", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCategoryManage), categoryHandler.DeleteCategory)
+	api.GET("/admin/comments/reported", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCommentModerate), commentHandler.GetReportedComments)
+	api.POST("/admin/comments/:id/hide", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCommentModerate), commentHandler.HideComment)
+	api.POST("/admin/comments/:id/unhide", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCommentModerate), commentHandler.UnhideComment)
 	api.POST("/admin/sessions", userHandler.Login)
 
 	api.POST("/users", userHandler.RegisterUser)
 	api.POST("/sessions", userHandler.Login)
+	api.GET("/auth/:provider/login", oidcHandler.Login)
+	api.GET("/auth/:provider/callback", oidcHandler.Callback)
+	api.POST("/sessions/two_factor", userHandler.VerifyTwoFactorLogin)
+	api.POST("/sessions/refresh", sessionHandler.RefreshSession)
+	api.DELETE("/sessions", sessionHandler.Logout)
+	api.GET("/sessions", authMiddleware(authService, userService, sessionService, apiKeyService), sessionHandler.GetSessions)
+	api.DELETE("/sessions/others", authMiddleware(authService, userService, sessionService, apiKeyService), sessionHandler.RevokeOtherSessions)
+	api.DELETE("/sessions/:id", authMiddleware(authService, userService, sessionService, apiKeyService), sessionHandler.RevokeSession)
 	api.POST("/email_checkers", userHandler.CheckEmailAvailability)
-	api.POST("/avatars", authMiddleware(authService, userService), userHandler.UploadAvatar)
-	api.GET("/users/fetch", authMiddleware(authService, userService), userHandler.FetchUser)
+	api.POST("/passwords/forgot", userHandler.ForgotPassword)
+	api.POST("/passwords/reset", userHandler.ResetPassword)
+	api.POST("/email_verifications", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.ResendVerificationEmail)
+	api.POST("/email_verifications/confirm", userHandler.VerifyEmail)
+	api.POST("/avatars", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.UploadAvatar)
+	api.DELETE("/avatars", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.RemoveAvatar)
+	api.GET("/users/fetch", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.FetchUser)
+	api.GET("/users/export", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.ExportUserData)
+	api.PUT("/users/profile", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.UpdateProfile)
+	api.PUT("/users/password", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.ChangePassword)
+	api.GET("/users/campaigns", apiKeyScope(apikey.ScopeCampaignsRead), authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.GetUserCampaigns)
+	api.GET("/api_keys", authMiddleware(authService, userService, sessionService, apiKeyService), apiKeyHandler.GetAPIKeys)
+	api.POST("/api_keys", authMiddleware(authService, userService, sessionService, apiKeyService), apiKeyHandler.CreateAPIKey)
+	api.DELETE("/api_keys/:id", authMiddleware(authService, userService, sessionService, apiKeyService), apiKeyHandler.RevokeAPIKey)
+	api.GET("/notifications", authMiddleware(authService, userService, sessionService, apiKeyService), notificationHandler.GetNotifications)
+	api.POST("/notifications/read_all", authMiddleware(authService, userService, sessionService, apiKeyService), notificationHandler.MarkAllRead)
+	api.POST("/notifications/:id/read", authMiddleware(authService, userService, sessionService, apiKeyService), notificationHandler.MarkRead)
+	api.POST("/users/two_factor", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.EnrollTwoFactor)
+	api.POST("/users/two_factor/confirm", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.ConfirmTwoFactor)
+	api.DELETE("/users/two_factor", authMiddleware(authService, userService, sessionService, apiKeyService), userHandler.DisableTwoFactor)
 
 	api.GET("/campaigns", campaignHandler.GetCampaigns)
+	api.GET("/campaigns/facets", campaignHandler.GetCampaignFacets)
+	api.GET("/categories", categoryHandler.GetCategories)
+	api.GET("/campaigns/slug/:slug", campaignHandler.GetCampaignBySlug)
 	api.GET("/campaigns/:id", campaignHandler.GetCampaign)
-	api.POST("/campaigns", authMiddleware(authService, userService), campaignHandler.CreateCampaign)
-	api.PUT("/campaigns/:id", authMiddleware(authService, userService), campaignHandler.UpdateCampaign)
-	api.POST("/campaign-images", authMiddleware(authService, userService), campaignHandler.UploadImage)
+	api.POST("/campaigns", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionCampaignCreate), verifiedMiddleware(), campaignHandler.CreateCampaign)
+	api.PUT("/campaigns/:id", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.UpdateCampaign)
+	api.POST("/campaigns/:id/submit", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.SubmitCampaign)
+	api.POST("/campaigns/:id/close", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.CloseCampaign)
+	api.POST("/campaigns/:id/cancel", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.CancelCampaign)
+	api.POST("/campaigns/:id/archive", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.ArchiveCampaign)
+	api.GET("/campaigns/:id/updates", optionalAuthMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.GetCampaignUpdates)
+	api.POST("/campaigns/:id/updates", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.CreateCampaignUpdate)
+	api.POST("/campaigns/:id/updates/:update_id/images", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.UploadCampaignUpdateImage)
+	api.GET("/campaigns/:id/comments", commentHandler.GetComments)
+	api.POST("/campaigns/:id/comments", authMiddleware(authService, userService, sessionService, apiKeyService), verifiedMiddleware(), commentHandler.CreateComment)
+	api.PUT("/comments/:id", authMiddleware(authService, userService, sessionService, apiKeyService), commentHandler.UpdateComment)
+	api.DELETE("/comments/:id", authMiddleware(authService, userService, sessionService, apiKeyService), commentHandler.DeleteComment)
+	api.POST("/comments/:id/report", authMiddleware(authService, userService, sessionService, apiKeyService), commentHandler.ReportComment)
+	api.POST("/campaigns/:id/follow", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.FollowCampaign)
+	api.DELETE("/campaigns/:id/follow", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.UnfollowCampaign)
+	api.POST("/campaigns/:id/rewards", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.CreateReward)
+	api.PUT("/campaigns/:id/rewards/:reward_id", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.UpdateReward)
+	api.POST("/campaign-images", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.UploadImage)
+	api.DELETE("/campaigns/:id/images/:image_id", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.DeleteCampaignImage)
+	api.PUT("/campaigns/:id/images/:image_id/primary", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.SetPrimaryCampaignImage)
+	api.PUT("/campaigns/:id/images/:image_id/position", authMiddleware(authService, userService, sessionService, apiKeyService), campaignHandler.MoveCampaignImage)
 
-	api.GET("/campaigns/:id/donations", authMiddleware(authService, userService), donationHandler.GetCampaignDonations)
-	api.GET("/donations", authMiddleware(authService, userService), donationHandler.GetUserDonations)
-	api.POST("/donations", authMiddleware(authService, userService), donationHandler.CreateDonation)
+	api.GET("/campaigns/:id/donations", apiKeyScope(apikey.ScopeDonationsRead), authMiddleware(authService, userService, sessionService, apiKeyService), donationHandler.GetCampaignDonations)
+	api.GET("/donations", apiKeyScope(apikey.ScopeDonationsRead), authMiddleware(authService, userService, sessionService, apiKeyService), donationHandler.GetUserDonations)
+	api.POST("/donations", authMiddleware(authService, userService, sessionService, apiKeyService), permissionMiddleware(user.PermissionDonationCreate), verifiedMiddleware(), donationHandler.CreateDonation)
 	api.POST("/donations/notification", donationHandler.GetNotification)
 
 	router.GET("/", func(c *gin.Context) {
@@ -97,7 +232,17 @@ func initCloudinary() (*cloudinary.Cloudinary, error) {
 	return cloudinary, nil
 }
 
-func authMiddleware(authService auth.Service, userService user.Service) gin.HandlerFunc {
+// initLoginGuardStore picks where failed login counters live. The database
+// store must be used when more than one instance serves /sessions.
+func initLoginGuardStore(db *gorm.DB) loginguard.Store {
+	if config.GetEnv("LOGIN_GUARD_STORE", "memory") == "database" {
+		return loginguard.NewDatabaseStore(db)
+	}
+
+	return loginguard.NewMemoryStore(time.Hour)
+}
+
+func authMiddleware(authService auth.Service, userService user.Service, sessionService session.Service, apiKeyService apikey.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
@@ -114,7 +259,59 @@ func authMiddleware(authService auth.Service, userService user.Service) gin.Hand
 			tokenString = arrayToken[1]
 		}
 
-		token, err := authService.ValidateToken(tokenString)
+		var userID int
+
+		if strings.HasPrefix(tokenString, apikey.KeyPrefix) {
+			apiKey, err := apiKeyService.Authenticate(tokenString)
+			if err != nil {
+				response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+				return
+			}
+
+			// API keys only reach routes that opted in with apiKeyScope, and
+			// only with a key that was granted that scope.
+			scope := c.GetString("apiKeyScope")
+			if scope == "" || !apiKey.HasScope(scope) {
+				response := helper.APIResponse("API key is not allowed to access this resource", http.StatusForbidden, "error", nil)
+				c.AbortWithStatusJSON(http.StatusForbidden, response)
+				return
+			}
+
+			c.Set("apiKey", apiKey)
+			userID = apiKey.UserID
+		} else {
+			token, err := authService.ValidateToken(tokenString)
+
+			if err != nil {
+				response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+				return
+			}
+
+			claim, ok := token.Claims.(jwt.MapClaims)
+
+			if !ok || !token.Valid || claim["typ"] != auth.TokenTypeAccess {
+				response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+				return
+			}
+
+			userID = int(claim["user_id"].(float64))
+
+			sessionID, _ := claim["sid"].(string)
+
+			_, err = sessionService.ValidateSession(sessionID, userID)
+			if err != nil {
+				response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+				return
+			}
+
+			c.Set("currentSessionID", sessionID)
+		}
+
+		user, err := userService.GetUserByID(userID)
 
 		if err != nil {
 			response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
@@ -122,24 +319,70 @@ func authMiddleware(authService auth.Service, userService user.Service) gin.Hand
 			return
 		}
 
-		claim, ok := token.Claims.(jwt.MapClaims)
+		// The user is loaded on every request, so a suspension applies to
+		// access tokens and API keys that were issued before it.
+		if user.IsSuspended() {
+			response := helper.APIResponse("Your account has been suspended", http.StatusForbidden, "error", nil)
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
 
-		if !ok || !token.Valid {
-			response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+		c.Set("currentUser", user)
+	}
+}
+
+// optionalAuthMiddleware authenticates requests that send credentials, with
+// the same checks as authMiddleware, and lets anonymous ones through without
+// a currentUser.
+func optionalAuthMiddleware(authService auth.Service, userService user.Service, sessionService session.Service, apiKeyService apikey.Service) gin.HandlerFunc {
+	authenticate := authMiddleware(authService, userService, sessionService, apiKeyService)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
 			return
 		}
 
-		userID := int(claim["user_id"].(float64))
+		authenticate(c)
+	}
+}
 
-		user, err := userService.GetUserByID(userID)
+// apiKeyScope lets API keys holding scope through the authMiddleware that
+// follows it. Routes without it accept JWTs only.
+func apiKeyScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("apiKeyScope", scope)
+	}
+}
 
-		if err != nil {
-			response := helper.APIResponse("Unauthorized", http.StatusUnauthorized, "error", nil)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+// permissionMiddleware rejects users whose role lacks permission. Roles that
+// require two-factor authentication cannot use any permission until it is
+// enabled.
+func permissionMiddleware(permission user.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(user.User)
+
+		if !currentUser.HasPermission(permission) {
+			response := helper.APIResponse("You are not authorized", http.StatusForbidden, "error", nil)
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
 			return
 		}
 
-		c.Set("currentUser", user)
+		if currentUser.TwoFactorRequired() && !currentUser.TwoFactorEnabled() {
+			response := helper.APIResponse("Two-factor authentication must be enabled for your role", http.StatusForbidden, "error", nil)
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
+	}
+}
+
+func verifiedMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(user.User)
+
+		if !currentUser.IsVerified() {
+			response := helper.APIResponse("Please verify your email address first", http.StatusForbidden, "error", nil)
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
 	}
 }
@@ -0,0 +1,72 @@
+package apikey
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	ScopeCampaignsRead = "campaigns:read"
+	ScopeDonationsRead = "donations:read"
+
+	// KeyPrefix starts every raw key, which is how authMiddleware tells API
+	// keys apart from JWTs in the Authorization header.
+	KeyPrefix = "pbb_"
+)
+
+// APIKey is a long-lived credential a user creates for server-to-server
+// integrations. Only a hash of the key is stored; Prefix is kept in clear so
+// the owner can tell their keys apart.
+type APIKey struct {
+	ID         int        `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID     int        `gorm:"column:user_id;index"`
+	Name       string     `gorm:"column:name"`
+	Prefix     string     `gorm:"column:prefix;type:varchar(16)"`
+	KeyHash    string     `gorm:"column:key_hash;type:varchar(64);uniqueIndex"`
+	Scopes     string     `gorm:"column:scopes"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+func Scopes() []string {
+	return []string{ScopeCampaignsRead, ScopeDonationsRead}
+}
+
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (k APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+
+	return strings.Split(k.Scopes, ",")
+}
+
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (k APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
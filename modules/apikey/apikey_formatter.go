@@ -0,0 +1,49 @@
+package apikey
+
+import "time"
+
+type APIKeyFormatter struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func FormatAPIKey(apiKey APIKey) APIKeyFormatter {
+	formatter := APIKeyFormatter{}
+	formatter.ID = apiKey.ID
+	formatter.Name = apiKey.Name
+	formatter.Prefix = apiKey.Prefix
+	formatter.Scopes = apiKey.ScopeList()
+	formatter.ExpiresAt = apiKey.ExpiresAt
+	formatter.LastUsedAt = apiKey.LastUsedAt
+	formatter.RevokedAt = apiKey.RevokedAt
+	formatter.CreatedAt = apiKey.CreatedAt
+
+	return formatter
+}
+
+func FormatAPIKeys(apiKeys []APIKey) []APIKeyFormatter {
+	formatters := []APIKeyFormatter{}
+
+	for _, apiKey := range apiKeys {
+		formatters = append(formatters, FormatAPIKey(apiKey))
+	}
+
+	return formatters
+}
+
+// CreatedAPIKeyFormatter is returned once, right after creation; the raw key
+// cannot be retrieved afterwards.
+type CreatedAPIKeyFormatter struct {
+	APIKeyFormatter
+	Key string `json:"key"`
+}
+
+func FormatCreatedAPIKey(apiKey APIKey, rawKey string) CreatedAPIKeyFormatter {
+	return CreatedAPIKeyFormatter{FormatAPIKey(apiKey), rawKey}
+}
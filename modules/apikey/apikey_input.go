@@ -0,0 +1,13 @@
+package apikey
+
+type CreateAPIKeyInput struct {
+	UserID        int
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type RevokeAPIKeyInput struct {
+	ID     int `uri:"id" binding:"required"`
+	UserID int
+}
@@ -0,0 +1,86 @@
+package apikey
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Save(apiKey APIKey) (APIKey, error)
+	FindByKeyHash(keyHash string) (APIKey, error)
+	FindByID(ID int) (APIKey, error)
+	FindByUserID(userID int) ([]APIKey, error)
+	Update(apiKey APIKey) (APIKey, error)
+	TouchLastUsed(ID int, usedAt time.Time) error
+	RevokeByUserID(userID int) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+func (r *repository) Save(apiKey APIKey) (APIKey, error) {
+	err := r.db.Create(&apiKey).Error
+	if err != nil {
+		return apiKey, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *repository) FindByKeyHash(keyHash string) (APIKey, error) {
+	var apiKey APIKey
+
+	err := r.db.Where("key_hash = ?", keyHash).Find(&apiKey).Error
+	if err != nil {
+		return apiKey, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *repository) FindByID(ID int) (APIKey, error) {
+	var apiKey APIKey
+
+	err := r.db.Where("id = ?", ID).Find(&apiKey).Error
+	if err != nil {
+		return apiKey, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *repository) FindByUserID(userID int) ([]APIKey, error) {
+	var apiKeys []APIKey
+
+	err := r.db.Where("user_id = ?", userID).Order("id desc").Find(&apiKeys).Error
+	if err != nil {
+		return apiKeys, err
+	}
+
+	return apiKeys, nil
+}
+
+func (r *repository) Update(apiKey APIKey) (APIKey, error) {
+	err := r.db.Save(&apiKey).Error
+	if err != nil {
+		return apiKey, err
+	}
+
+	return apiKey, nil
+}
+
+// TouchLastUsed only writes last_used_at, so it cannot overwrite a
+// concurrent revocation with stale data.
+func (r *repository) TouchLastUsed(ID int, usedAt time.Time) error {
+	return r.db.Model(&APIKey{}).Where("id = ?", ID).UpdateColumn("last_used_at", usedAt).Error
+}
+
+func (r *repository) RevokeByUserID(userID int) error {
+	return r.db.Model(&APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,147 @@
+package apikey
+
+import (
+	"crowdfunding-minpro-alterra/utils/helper"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	maxActiveKeysPerUser = 10
+
+	// lastUsedInterval limits last_used_at writes to one per key per
+	// interval instead of one per request.
+	lastUsedInterval = time.Minute
+)
+
+type Service interface {
+	CreateAPIKey(input CreateAPIKeyInput) (APIKey, string, error)
+	GetAPIKeys(userID int) ([]APIKey, error)
+	RevokeAPIKey(input RevokeAPIKeyInput) (APIKey, error)
+	RevokeUserAPIKeys(userID int) error
+	Authenticate(rawKey string) (APIKey, error)
+}
+
+type service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *service {
+	return &service{repository}
+}
+
+func (s *service) CreateAPIKey(input CreateAPIKeyInput) (APIKey, string, error) {
+	for _, scope := range input.Scopes {
+		if !IsValidScope(scope) {
+			return APIKey{}, "", errors.New("Invalid scope: " + scope)
+		}
+	}
+
+	apiKeys, err := s.repository.FindByUserID(input.UserID)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	now := time.Now()
+	active := 0
+	for _, apiKey := range apiKeys {
+		if apiKey.RevokedAt == nil && !apiKey.IsExpired(now) {
+			active++
+		}
+	}
+
+	if active >= maxActiveKeysPerUser {
+		return APIKey{}, "", errors.New("Too many active API keys")
+	}
+
+	publicPart, err := helper.GenerateRandomToken(6)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	secret, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	prefix := KeyPrefix + publicPart
+	rawKey := prefix + "." + secret
+
+	apiKey := APIKey{
+		UserID:  input.UserID,
+		Name:    input.Name,
+		Prefix:  prefix,
+		KeyHash: helper.HashToken(rawKey),
+		Scopes:  strings.Join(input.Scopes, ","),
+	}
+
+	if input.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, input.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	newAPIKey, err := s.repository.Save(apiKey)
+	if err != nil {
+		return newAPIKey, "", err
+	}
+
+	return newAPIKey, rawKey, nil
+}
+
+func (s *service) GetAPIKeys(userID int) ([]APIKey, error) {
+	apiKeys, err := s.repository.FindByUserID(userID)
+	if err != nil {
+		return apiKeys, err
+	}
+
+	return apiKeys, nil
+}
+
+func (s *service) RevokeAPIKey(input RevokeAPIKeyInput) (APIKey, error) {
+	apiKey, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return apiKey, err
+	}
+
+	if apiKey.ID == 0 || apiKey.UserID != input.UserID {
+		return APIKey{}, errors.New("No API key found with that ID")
+	}
+
+	if apiKey.RevokedAt != nil {
+		return apiKey, nil
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+
+	return s.repository.Update(apiKey)
+}
+
+func (s *service) RevokeUserAPIKeys(userID int) error {
+	return s.repository.RevokeByUserID(userID)
+}
+
+func (s *service) Authenticate(rawKey string) (APIKey, error) {
+	apiKey, err := s.repository.FindByKeyHash(helper.HashToken(rawKey))
+	if err != nil {
+		return apiKey, err
+	}
+
+	now := time.Now()
+
+	if apiKey.ID == 0 || apiKey.RevokedAt != nil || apiKey.IsExpired(now) {
+		return APIKey{}, errors.New("Invalid API key")
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedInterval {
+		err = s.repository.TouchLastUsed(apiKey.ID, now)
+		if err != nil {
+			return apiKey, err
+		}
+
+		apiKey.LastUsedAt = &now
+	}
+
+	return apiKey, nil
+}
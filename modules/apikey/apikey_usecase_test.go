@@ -0,0 +1,150 @@
+package apikey
+
+import (
+	"crowdfunding-minpro-alterra/utils/helper"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRepository struct {
+	APIKeys      []APIKey
+	TouchedIDs   []int
+	RevokedUsers []int
+}
+
+func (m *MockRepository) Save(apiKey APIKey) (APIKey, error) {
+	apiKey.ID = len(m.APIKeys) + 1
+	m.APIKeys = append(m.APIKeys, apiKey)
+	return apiKey, nil
+}
+
+func (m *MockRepository) FindByKeyHash(keyHash string) (APIKey, error) {
+	for _, apiKey := range m.APIKeys {
+		if apiKey.KeyHash == keyHash {
+			return apiKey, nil
+		}
+	}
+	return APIKey{}, nil
+}
+
+func (m *MockRepository) FindByID(ID int) (APIKey, error) {
+	for _, apiKey := range m.APIKeys {
+		if apiKey.ID == ID {
+			return apiKey, nil
+		}
+	}
+	return APIKey{}, nil
+}
+
+func (m *MockRepository) FindByUserID(userID int) ([]APIKey, error) {
+	var apiKeys []APIKey
+	for _, apiKey := range m.APIKeys {
+		if apiKey.UserID == userID {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	return apiKeys, nil
+}
+
+func (m *MockRepository) Update(apiKey APIKey) (APIKey, error) {
+	for i := range m.APIKeys {
+		if m.APIKeys[i].ID == apiKey.ID {
+			m.APIKeys[i] = apiKey
+		}
+	}
+	return apiKey, nil
+}
+
+func (m *MockRepository) TouchLastUsed(ID int, usedAt time.Time) error {
+	m.TouchedIDs = append(m.TouchedIDs, ID)
+	for i := range m.APIKeys {
+		if m.APIKeys[i].ID == ID {
+			m.APIKeys[i].LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func (m *MockRepository) RevokeByUserID(userID int) error {
+	m.RevokedUsers = append(m.RevokedUsers, userID)
+	return nil
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	apiKey, rawKey, err := service.CreateAPIKey(CreateAPIKeyInput{UserID: 1, Name: "Nightly export", Scopes: []string{ScopeDonationsRead}, ExpiresInDays: 30})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rawKey, apiKey.Prefix+"."))
+	assert.True(t, strings.HasPrefix(apiKey.Prefix, KeyPrefix))
+	assert.Equal(t, helper.HashToken(rawKey), apiKey.KeyHash)
+	assert.NotContains(t, apiKey.KeyHash, rawKey)
+	assert.True(t, apiKey.HasScope(ScopeDonationsRead))
+	assert.False(t, apiKey.HasScope(ScopeCampaignsRead))
+	assert.NotNil(t, apiKey.ExpiresAt)
+}
+
+func TestCreateAPIKey_InvalidScope(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	_, _, err := service.CreateAPIKey(CreateAPIKeyInput{UserID: 1, Name: "Admin", Scopes: []string{"users:delete"}})
+
+	assert.EqualError(t, err, "Invalid scope: users:delete")
+	assert.Empty(t, repo.APIKeys)
+}
+
+func TestAuthenticate(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	_, rawKey, _ := service.CreateAPIKey(CreateAPIKeyInput{UserID: 1, Name: "Nightly export", Scopes: []string{ScopeDonationsRead}})
+
+	apiKey, err := service.Authenticate(rawKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, apiKey.UserID)
+	assert.NotNil(t, apiKey.LastUsedAt)
+
+	_, err = service.Authenticate(rawKey)
+	assert.NoError(t, err)
+	assert.Len(t, repo.TouchedIDs, 1)
+
+	_, err = service.Authenticate(rawKey + "x")
+	assert.EqualError(t, err, "Invalid API key")
+}
+
+func TestAuthenticate_RevokedAndExpired(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	revokedKey, rawRevokedKey, _ := service.CreateAPIKey(CreateAPIKeyInput{UserID: 1, Name: "Revoked", Scopes: []string{ScopeDonationsRead}})
+	_, err := service.RevokeAPIKey(RevokeAPIKeyInput{ID: revokedKey.ID, UserID: 1})
+	assert.NoError(t, err)
+
+	_, err = service.Authenticate(rawRevokedKey)
+	assert.EqualError(t, err, "Invalid API key")
+
+	_, rawExpiredKey, _ := service.CreateAPIKey(CreateAPIKeyInput{UserID: 1, Name: "Expired", Scopes: []string{ScopeDonationsRead}, ExpiresInDays: 1})
+	expiredAt := time.Now().Add(-time.Hour)
+	repo.APIKeys[1].ExpiresAt = &expiredAt
+
+	_, err = service.Authenticate(rawExpiredKey)
+	assert.EqualError(t, err, "Invalid API key")
+}
+
+func TestRevokeAPIKey_OtherUser(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	apiKey, _, _ := service.CreateAPIKey(CreateAPIKeyInput{UserID: 1, Name: "Nightly export", Scopes: []string{ScopeDonationsRead}})
+
+	_, err := service.RevokeAPIKey(RevokeAPIKeyInput{ID: apiKey.ID, UserID: 2})
+
+	assert.EqualError(t, err, "No API key found with that ID")
+	assert.Nil(t, repo.APIKeys[0].RevokedAt)
+}
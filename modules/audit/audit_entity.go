@@ -0,0 +1,87 @@
+package audit
+
+import "time"
+
+const (
+	ActionUserRegister     = "user.register"
+	ActionLoginSuccess     = "auth.login"
+	ActionLoginFailed      = "auth.login_failed"
+	ActionLoginBlocked     = "auth.login_blocked"
+	ActionTwoFactorSuccess = "auth.two_factor"
+	ActionTwoFactorFailed  = "auth.two_factor_failed"
+	ActionOIDCLogin        = "auth.oidc_login"
+	ActionTwoFactorEnable  = "user.two_factor_enable"
+	ActionTwoFactorDisable = "user.two_factor_disable"
+	ActionProfileUpdate    = "user.profile_update"
+	ActionPasswordChange   = "user.password_change"
+	ActionPasswordReset    = "user.password_reset"
+	ActionAvatarUpdate     = "user.avatar_update"
+	ActionAvatarRemove     = "user.avatar_remove"
+	ActionUserDelete       = "user.delete"
+	ActionUserRestore      = "user.restore"
+	ActionUserErase        = "user.erase"
+	ActionUserSuspend      = "user.suspend"
+	ActionUserUnsuspend    = "user.unsuspend"
+	ActionUserUnlock       = "user.unlock"
+	ActionRoleAssign       = "user.role_assign"
+	ActionCampaignCreate   = "campaign.create"
+	ActionCampaignUpdate   = "campaign.update"
+	ActionCampaignImage    = "campaign.image_upload"
+	ActionImageDelete      = "campaign.image_delete"
+	ActionImagePrimary     = "campaign.image_primary"
+	ActionImageMove        = "campaign.image_move"
+	ActionCampaignStatus   = "campaign.status_change"
+	ActionCampaignSubmit   = "campaign.submit"
+	ActionCampaignApprove  = "campaign.approve"
+	ActionCampaignReject   = "campaign.reject"
+	ActionCampaignClose    = "campaign.close"
+	ActionCampaignCancel   = "campaign.cancel"
+	ActionCampaignArchive  = "campaign.archive"
+	ActionRewardCreate     = "campaign.reward_create"
+	ActionRewardUpdate     = "campaign.reward_update"
+	ActionCampaignPost     = "campaign.update_post"
+	ActionCategoryCreate   = "category.create"
+	ActionCategoryUpdate   = "category.update"
+	ActionCategoryDelete   = "category.delete"
+	ActionCommentCreate    = "comment.create"
+	ActionCommentUpdate    = "comment.update"
+	ActionCommentDelete    = "comment.delete"
+	ActionCommentHide      = "comment.hide"
+	ActionCommentUnhide    = "comment.unhide"
+	ActionDonationCreate   = "donation.create"
+	ActionDonationPayment  = "donation.payment_status"
+
+	TargetUser     = "user"
+	TargetCampaign = "campaign"
+	TargetDonation = "donation"
+	TargetCategory = "category"
+	TargetComment  = "comment"
+)
+
+// Actor is who performed an action and from where. An ID of 0 means the
+// system or an anonymous client, e.g. a payment notification or a failed
+// login for an unknown email.
+type Actor struct {
+	ID        int
+	IPAddress string
+	UserAgent string
+}
+
+// Log records who did what to which record. Entries are append-only: the
+// repository has no way to update or delete them, except for removing an
+// erased user's email addresses from the metadata.
+type Log struct {
+	ID         int       `gorm:"column:id;primaryKey;autoIncrement"`
+	ActorID    int       `gorm:"column:actor_id;index"`
+	Action     string    `gorm:"column:action;type:varchar(64);index"`
+	TargetType string    `gorm:"column:target_type;type:varchar(32);index:idx_audit_logs_target"`
+	TargetID   int       `gorm:"column:target_id;index:idx_audit_logs_target"`
+	IPAddress  string    `gorm:"column:ip_address;type:varchar(64)"`
+	UserAgent  string    `gorm:"column:user_agent;type:varchar(255)"`
+	Metadata   string    `gorm:"column:metadata;type:text"`
+	CreatedAt  time.Time `gorm:"column:created_at;index"`
+}
+
+func (Log) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type LogFormatter struct {
+	ID         int             `json:"id"`
+	ActorID    int             `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int             `json:"target_id"`
+	IPAddress  string          `json:"ip_address"`
+	UserAgent  string          `json:"user_agent"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func FormatLog(log Log) LogFormatter {
+	formatter := LogFormatter{
+		ID:         log.ID,
+		ActorID:    log.ActorID,
+		Action:     log.Action,
+		TargetType: log.TargetType,
+		TargetID:   log.TargetID,
+		IPAddress:  log.IPAddress,
+		UserAgent:  log.UserAgent,
+		CreatedAt:  log.CreatedAt,
+	}
+
+	if log.Metadata != "" {
+		formatter.Metadata = json.RawMessage(log.Metadata)
+	}
+
+	return formatter
+}
+
+func FormatLogs(logs []Log) []LogFormatter {
+	logsFormatter := []LogFormatter{}
+
+	for _, log := range logs {
+		logsFormatter = append(logsFormatter, FormatLog(log))
+	}
+
+	return logsFormatter
+}
@@ -0,0 +1,22 @@
+package audit
+
+import "time"
+
+type RecordInput struct {
+	Actor      Actor
+	Action     string
+	TargetType string
+	TargetID   int
+	Metadata   map[string]interface{}
+}
+
+type SearchLogsInput struct {
+	Page       int       `form:"page" binding:"omitempty,min=1"`
+	Limit      int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	ActorID    int       `form:"actor_id"`
+	Action     string    `form:"action"`
+	TargetType string    `form:"target_type"`
+	TargetID   int       `form:"target_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02"`
+	To         time.Time `form:"to" time_format:"2006-01-02"`
+}
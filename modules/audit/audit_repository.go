@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Save(log Log) (Log, error)
+	Search(input SearchLogsInput) ([]Log, int64, error)
+	FindInBatches(input SearchLogsInput, batchSize int, fn func(logs []Log) error) error
+	RemoveMetadataKeys(userID int, email string, keys []string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+func (r *repository) Save(log Log) (Log, error) {
+	err := r.db.Create(&log).Error
+	if err != nil {
+		return log, err
+	}
+
+	return log, nil
+}
+
+func (r *repository) Search(input SearchLogsInput) ([]Log, int64, error) {
+	var logs []Log
+	var total int64
+
+	query := r.filter(input)
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return logs, total, err
+	}
+
+	err = query.Order("id desc").Offset((input.Page - 1) * input.Limit).Limit(input.Limit).Find(&logs).Error
+	if err != nil {
+		return logs, total, err
+	}
+
+	return logs, total, nil
+}
+
+// FindInBatches walks every matching entry in id order without loading the
+// whole result into memory.
+func (r *repository) FindInBatches(input SearchLogsInput, batchSize int, fn func(logs []Log) error) error {
+	var logs []Log
+
+	return r.filter(input).Order("id").FindInBatches(&logs, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(logs)
+	}).Error
+}
+
+// RemoveMetadataKeys is the one exception to logs being append-only: when
+// a user is erased the given keys are dropped from the metadata of entries
+// about them, including failed logins that only know their email.
+func (r *repository) RemoveMetadataKeys(userID int, email string, keys []string) error {
+	paths := make([]interface{}, 0, len(keys))
+	placeholders := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		paths = append(paths, "$."+key)
+		placeholders = append(placeholders, "?")
+	}
+
+	return r.db.Model(&Log{}).
+		Where("metadata IS NOT NULL AND metadata <> ''").
+		Where("(target_type = ? AND target_id = ?) OR JSON_UNQUOTE(JSON_EXTRACT(metadata, '$.email')) = ?", TargetUser, userID, email).
+		Update("metadata", gorm.Expr("JSON_REMOVE(metadata, "+strings.Join(placeholders, ", ")+")", paths...)).Error
+}
+
+func (r *repository) filter(input SearchLogsInput) *gorm.DB {
+	query := r.db.Model(&Log{})
+
+	if input.ActorID != 0 {
+		query = query.Where("actor_id = ?", input.ActorID)
+	}
+
+	if input.Action != "" {
+		query = query.Where("action = ?", input.Action)
+	}
+
+	if input.TargetType != "" {
+		query = query.Where("target_type = ?", input.TargetType)
+	}
+
+	if input.TargetID != 0 {
+		query = query.Where("target_id = ?", input.TargetID)
+	}
+
+	if !input.From.IsZero() {
+		query = query.Where("created_at >= ?", input.From)
+	}
+
+	if !input.To.IsZero() {
+		query = query.Where("created_at < ?", input.To.AddDate(0, 0, 1))
+	}
+
+	return query
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLogsPageLimit = 50
+	exportBatchSize      = 500
+)
+
+// personalMetadataKeys are the metadata keys holding email addresses.
+var personalMetadataKeys = []string{"email", "previous_email"}
+
+type Service interface {
+	Record(input RecordInput) (Log, error)
+	SearchLogs(input SearchLogsInput) ([]Log, int64, SearchLogsInput, error)
+	ExportLogsCSV(input SearchLogsInput, w io.Writer) error
+	ScrubUser(userID int, email string) error
+}
+
+type service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *service {
+	return &service{repository}
+}
+
+func (s *service) Record(input RecordInput) (Log, error) {
+	if input.Action == "" {
+		return Log{}, errors.New("Audit action is required")
+	}
+
+	log := Log{
+		ActorID:    input.Actor.ID,
+		Action:     input.Action,
+		TargetType: input.TargetType,
+		TargetID:   input.TargetID,
+		IPAddress:  input.Actor.IPAddress,
+		UserAgent:  truncate(input.Actor.UserAgent, 255),
+	}
+
+	if len(input.Metadata) > 0 {
+		metadata, err := json.Marshal(input.Metadata)
+		if err != nil {
+			return Log{}, err
+		}
+		log.Metadata = string(metadata)
+	}
+
+	return s.repository.Save(log)
+}
+
+// RecordQuietly writes an audit entry about a target of the given type and
+// drops any error, for callers whose change must not fail because the
+// audit write did.
+func RecordQuietly(service Service, targetType string, actor Actor, action string, targetID int, metadata map[string]interface{}) {
+	_, _ = service.Record(RecordInput{
+		Actor:      actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Metadata:   metadata,
+	})
+}
+
+func (s *service) SearchLogs(input SearchLogsInput) ([]Log, int64, SearchLogsInput, error) {
+	if input.Page == 0 {
+		input.Page = 1
+	}
+
+	if input.Limit == 0 {
+		input.Limit = defaultLogsPageLimit
+	}
+
+	logs, total, err := s.repository.Search(input)
+	if err != nil {
+		return logs, total, input, err
+	}
+
+	return logs, total, input, nil
+}
+
+// ExportLogsCSV writes every entry matching the filters, ignoring paging.
+func (s *service) ExportLogsCSV(input SearchLogsInput, w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "ip_address", "user_agent", "metadata"})
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.FindInBatches(input, exportBatchSize, func(logs []Log) error {
+		for _, log := range logs {
+			err := writer.Write([]string{
+				strconv.Itoa(log.ID),
+				log.CreatedAt.UTC().Format(time.RFC3339),
+				strconv.Itoa(log.ActorID),
+				csvSafe(log.Action),
+				csvSafe(log.TargetType),
+				strconv.Itoa(log.TargetID),
+				csvSafe(log.IPAddress),
+				csvSafe(log.UserAgent),
+				csvSafe(log.Metadata),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ScrubUser removes the email addresses recorded about an erased user.
+func (s *service) ScrubUser(userID int, email string) error {
+	return s.repository.RemoveMetadataKeys(userID, email, personalMetadataKeys)
+}
+
+// csvSafe keeps spreadsheets from evaluating a value as a formula, e.g. a
+// user agent starting with "=", by prefixing it with an apostrophe.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+
+	return value[:length]
+}
@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRepository struct {
+	Logs          []Log
+	SearchInput   SearchLogsInput
+	RemovedKeys   []string
+	ScrubbedEmail string
+}
+
+func (m *MockRepository) Save(log Log) (Log, error) {
+	log.ID = len(m.Logs) + 1
+	m.Logs = append(m.Logs, log)
+	return log, nil
+}
+
+func (m *MockRepository) Search(input SearchLogsInput) ([]Log, int64, error) {
+	m.SearchInput = input
+	return m.Logs, int64(len(m.Logs)), nil
+}
+
+func (m *MockRepository) FindInBatches(input SearchLogsInput, batchSize int, fn func(logs []Log) error) error {
+	for start := 0; start < len(m.Logs); start += batchSize {
+		end := start + batchSize
+		if end > len(m.Logs) {
+			end = len(m.Logs)
+		}
+
+		if err := fn(m.Logs[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MockRepository) RemoveMetadataKeys(userID int, email string, keys []string) error {
+	m.RemovedKeys = keys
+	m.ScrubbedEmail = email
+	return nil
+}
+
+func TestRecord(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	log, err := service.Record(RecordInput{
+		Actor:      Actor{ID: 1, IPAddress: "10.0.0.1", UserAgent: "curl"},
+		Action:     ActionUserSuspend,
+		TargetType: TargetUser,
+		TargetID:   2,
+		Metadata:   map[string]interface{}{"reason": "spam"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, log.ID)
+	assert.Equal(t, ActionUserSuspend, repo.Logs[0].Action)
+	assert.Equal(t, 2, repo.Logs[0].TargetID)
+	assert.Equal(t, "10.0.0.1", repo.Logs[0].IPAddress)
+	assert.JSONEq(t, `{"reason": "spam"}`, repo.Logs[0].Metadata)
+}
+
+func TestRecord_MissingAction(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	_, err := service.Record(RecordInput{Actor: Actor{ID: 1}})
+
+	assert.EqualError(t, err, "Audit action is required")
+	assert.Empty(t, repo.Logs)
+}
+
+func TestSearchLogs_Defaults(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	_, _, input, err := service.SearchLogs(SearchLogsInput{Action: ActionLoginFailed})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, input.Page)
+	assert.Equal(t, defaultLogsPageLimit, input.Limit)
+	assert.Equal(t, ActionLoginFailed, repo.SearchInput.Action)
+}
+
+func TestExportLogsCSV(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	for i := 0; i < exportBatchSize+1; i++ {
+		service.Record(RecordInput{Actor: Actor{ID: 1}, Action: ActionLoginSuccess, TargetType: TargetUser, TargetID: 1})
+	}
+
+	var buf bytes.Buffer
+	err := service.ExportLogsCSV(SearchLogsInput{}, &buf)
+
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, exportBatchSize+2)
+	assert.True(t, strings.HasPrefix(lines[0], "id,created_at,actor_id,action"))
+	assert.Contains(t, lines[1], ActionLoginSuccess)
+}
+
+func TestExportLogsCSV_NeutralizesFormulas(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	for _, userAgent := range []string{"=HYPERLINK(\"http://evil\")", "+1", "-1", "@SUM(A1)", "curl"} {
+		service.Record(RecordInput{Actor: Actor{UserAgent: userAgent}, Action: ActionLoginFailed, TargetType: TargetUser})
+	}
+
+	var buf bytes.Buffer
+	err := service.ExportLogsCSV(SearchLogsInput{}, &buf)
+
+	assert.NoError(t, err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 6)
+
+	userAgents := []string{}
+	for _, record := range records[1:] {
+		userAgents = append(userAgents, record[7])
+	}
+
+	assert.Equal(t, []string{"'=HYPERLINK(\"http://evil\")", "'+1", "'-1", "'@SUM(A1)", "curl"}, userAgents)
+}
+
+func TestScrubUser(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	err := service.ScrubUser(7, "john@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"email", "previous_email"}, repo.RemovedKeys)
+	assert.Equal(t, "john@example.com", repo.ScrubbedEmail)
+}
@@ -0,0 +1,41 @@
+// Package audittest provides an audit.Service for tests of the modules that
+// record audit logs.
+package audittest
+
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"io"
+)
+
+// Service keeps every recorded entry in Records instead of storing it.
+type Service struct {
+	Records       []audit.RecordInput
+	ScrubbedUsers []int
+}
+
+func (s *Service) Record(input audit.RecordInput) (audit.Log, error) {
+	s.Records = append(s.Records, input)
+	return audit.Log{ID: len(s.Records), Action: input.Action}, nil
+}
+
+func (s *Service) SearchLogs(input audit.SearchLogsInput) ([]audit.Log, int64, audit.SearchLogsInput, error) {
+	return []audit.Log{}, 0, input, nil
+}
+
+func (s *Service) ExportLogsCSV(input audit.SearchLogsInput, w io.Writer) error {
+	return nil
+}
+
+func (s *Service) ScrubUser(userID int, email string) error {
+	s.ScrubbedUsers = append(s.ScrubbedUsers, userID)
+	return nil
+}
+
+// Actions lists the recorded actions in order.
+func (s *Service) Actions() []string {
+	actions := []string{}
+	for _, record := range s.Records {
+		actions = append(actions, record.Action)
+	}
+	return actions
+}
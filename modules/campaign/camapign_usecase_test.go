@@ -1,21 +1,257 @@
 package campaign
 
 import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/audit/audittest"
+	"crowdfunding-minpro-alterra/modules/category"
+	"crowdfunding-minpro-alterra/modules/notification"
 	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/mailer"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 type MockRepository struct {
 	FindAllFunc               func() ([]Campaign, error)
+	SearchFunc                func(input GetCampaignsInput) ([]Campaign, int64, error)
 	FindByUserIDFunc          func(userID int) ([]Campaign, error)
 	FindByIDFunc              func(ID int) (Campaign, error)
 	SaveFunc                  func(campaign Campaign) (Campaign, error)
 	UpdateFunc                func(campaign Campaign) (Campaign, error)
 	CreateImageFunc           func(campaignImage CampaignImage) (CampaignImage, error)
-	MarkAllImagesAsNonPrimaryFunc func(campaignID int) (bool, error)
+	FindExpiredFunc           func(now time.Time) ([]Campaign, error)
+	FindOrCreateTagsFunc      func(names []string) ([]Tag, error)
+	ReplaceTagsFunc           func(campaign Campaign, tags []Tag) error
+	FacetsFunc                func(input GetCampaignsInput) (Facets, error)
+	FindRewardByIDFunc        func(ID int) (Reward, error)
+	SaveRewardFunc            func(reward Reward) (Reward, error)
+	UpdateRewardFunc          func(reward Reward) (Reward, error)
+	FindUpdatesFunc           func(campaignID int, includeDonorsOnly bool) ([]CampaignUpdate, error)
+	FindUpdateByIDFunc        func(ID int) (CampaignUpdate, error)
+	SaveUpdateFunc            func(update CampaignUpdate) (CampaignUpdate, error)
+	// Slugs maps slugs in use, current or old, to their campaign.
+	Slugs                     map[string]int
+	SlugHistory               map[string]int
+	DeletedImageIDs           []int
+	PrimaryImageID            int
+	ImagePositions            []CampaignImage
+	FollowerIDs               []int
+	DonorIDs                  []int
+	PendingDonorIDs           []int
+	// PendingDonations is cleared when the campaign is cancelled.
+	PendingDonations          int64
+}
+
+func (m *MockRepository) FindBySlug(slug string) (Campaign, error) {
+	if m.FindByIDFunc != nil && m.Slugs[slug] != 0 && m.SlugHistory[slug] == 0 {
+		return m.FindByIDFunc(m.Slugs[slug])
+	}
+	return Campaign{}, nil
+}
+
+func (m *MockRepository) FindSlugHistory(slug string) (CampaignSlug, error) {
+	if m.SlugHistory[slug] != 0 {
+		return CampaignSlug{ID: 1, CampaignID: m.SlugHistory[slug], Slug: slug}, nil
+	}
+	return CampaignSlug{}, nil
+}
+
+func (m *MockRepository) IsSlugTaken(slug string, campaignID int) (bool, error) {
+	owner, ok := m.Slugs[slug]
+	return ok && owner != campaignID, nil
+}
+
+func (m *MockRepository) ChangeSlug(campaign Campaign, slug string) (Campaign, error) {
+	if m.Slugs == nil {
+		m.Slugs = map[string]int{}
+		m.SlugHistory = map[string]int{}
+	}
+	m.Slugs[campaign.Slug] = campaign.ID
+	m.SlugHistory[campaign.Slug] = campaign.ID
+	m.Slugs[slug] = campaign.ID
+	delete(m.SlugHistory, slug)
+	campaign.Slug = slug
+	return campaign, nil
+}
+
+// DeleteImage promotes the next image of the gallery FindByIDFunc returns
+// when the primary one is deleted, like the repository does.
+func (m *MockRepository) DeleteImage(campaignImage CampaignImage) error {
+	m.DeletedImageIDs = append(m.DeletedImageIDs, campaignImage.ID)
+	if campaignImage.IsPrimary == 1 && m.FindByIDFunc != nil {
+		campaign, _ := m.FindByIDFunc(campaignImage.CampaignID)
+		for _, next := range campaign.CampaignImages {
+			if next.ID != campaignImage.ID {
+				m.PrimaryImageID = next.ID
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MockRepository) SetPrimaryImage(campaignID int, imageID int) error {
+	m.PrimaryImageID = imageID
+	return nil
+}
+
+func (m *MockRepository) UpdateImagePositions(images []CampaignImage) error {
+	m.ImagePositions = images
+	return nil
+}
+
+func (m *MockRepository) FindUpdates(campaignID int, includeDonorsOnly bool) ([]CampaignUpdate, error) {
+	if m.FindUpdatesFunc != nil {
+		return m.FindUpdatesFunc(campaignID, includeDonorsOnly)
+	}
+	return nil, nil
+}
+
+func (m *MockRepository) FindUpdateByID(ID int) (CampaignUpdate, error) {
+	if m.FindUpdateByIDFunc != nil {
+		return m.FindUpdateByIDFunc(ID)
+	}
+	return CampaignUpdate{}, nil
+}
+
+func (m *MockRepository) SaveUpdate(update CampaignUpdate) (CampaignUpdate, error) {
+	if m.SaveUpdateFunc != nil {
+		return m.SaveUpdateFunc(update)
+	}
+	update.ID = 1
+	return update, nil
+}
+
+func (m *MockRepository) CreateUpdateImage(image CampaignUpdateImage) (CampaignUpdateImage, error) {
+	return image, nil
+}
+
+func (m *MockRepository) Follow(follower CampaignFollower) error {
+	m.FollowerIDs = append(m.FollowerIDs, follower.UserID)
+	return nil
+}
+
+func (m *MockRepository) Unfollow(campaignID int, userID int) error {
+	return nil
+}
+
+func (m *MockRepository) FindFollowerIDs(campaignID int) ([]int, error) {
+	return m.FollowerIDs, nil
+}
+
+func (m *MockRepository) FindDonorIDs(campaignID int) ([]int, error) {
+	return m.DonorIDs, nil
+}
+
+func (m *MockRepository) FindPendingDonorIDs(campaignID int) ([]int, error) {
+	return m.PendingDonorIDs, nil
+}
+
+// UpdateStatus saves through Update, like the repository only if the stored
+// campaign still has status from.
+func (m *MockRepository) UpdateStatus(campaign Campaign, from string) (Campaign, error) {
+	if m.FindByIDFunc != nil {
+		stored, err := m.FindByIDFunc(campaign.ID)
+		if err != nil {
+			return campaign, err
+		}
+		if stored.Status != from {
+			return campaign, ErrStatusChanged
+		}
+	}
+	return m.Update(campaign)
+}
+
+func (m *MockRepository) AddDonation(campaignID int, amount int) error {
+	return nil
+}
+
+func (m *MockRepository) RemoveDonation(campaignID int, amount int) (bool, error) {
+	return false, nil
+}
+
+func (m *MockRepository) Cancel(campaign Campaign, from string) (Campaign, int64, error) {
+	cancelledCampaign, err := m.UpdateStatus(campaign, from)
+	if err != nil {
+		return cancelledCampaign, 0, err
+	}
+	cancelled := m.PendingDonations
+	m.PendingDonations = 0
+	return cancelledCampaign, cancelled, nil
+}
+
+func (m *MockRepository) IsDonor(campaignID int, userID int) (bool, error) {
+	for _, donorID := range m.DonorIDs {
+		if donorID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockRepository) FindRewardByID(ID int) (Reward, error) {
+	if m.FindRewardByIDFunc != nil {
+		return m.FindRewardByIDFunc(ID)
+	}
+	return Reward{}, nil
+}
+
+func (m *MockRepository) SaveReward(reward Reward) (Reward, error) {
+	if m.SaveRewardFunc != nil {
+		return m.SaveRewardFunc(reward)
+	}
+	return reward, nil
+}
+
+func (m *MockRepository) UpdateReward(reward Reward) (Reward, error) {
+	if m.UpdateRewardFunc != nil {
+		return m.UpdateRewardFunc(reward)
+	}
+	return reward, nil
+}
+
+func (m *MockRepository) ReserveReward(ID int) (bool, error) {
+	return true, nil
+}
+
+func (m *MockRepository) ReleaseReward(ID int) error {
+	return nil
+}
+
+func (m *MockRepository) FindOrCreateTags(names []string) ([]Tag, error) {
+	if m.FindOrCreateTagsFunc != nil {
+		return m.FindOrCreateTagsFunc(names)
+	}
+
+	tags := []Tag{}
+	for i, name := range names {
+		tags = append(tags, Tag{ID: i + 1, Name: name})
+	}
+	return tags, nil
+}
+
+func (m *MockRepository) ReplaceTags(campaign Campaign, tags []Tag) error {
+	if m.ReplaceTagsFunc != nil {
+		return m.ReplaceTagsFunc(campaign, tags)
+	}
+	return nil
+}
+
+func (m *MockRepository) Facets(input GetCampaignsInput) (Facets, error) {
+	if m.FacetsFunc != nil {
+		return m.FacetsFunc(input)
+	}
+	return Facets{}, nil
+}
+
+func (m *MockRepository) FindExpired(now time.Time) ([]Campaign, error) {
+	if m.FindExpiredFunc != nil {
+		return m.FindExpiredFunc(now)
+	}
+	return nil, nil
 }
 
 func (m *MockRepository) FindAll() ([]Campaign, error) {
@@ -25,6 +261,13 @@ func (m *MockRepository) FindAll() ([]Campaign, error) {
 	return nil, nil
 }
 
+func (m *MockRepository) Search(input GetCampaignsInput) ([]Campaign, int64, error) {
+	if m.SearchFunc != nil {
+		return m.SearchFunc(input)
+	}
+	return nil, 0, nil
+}
+
 func (m *MockRepository) FindByUserID(userID int) ([]Campaign, error) {
 	if m.FindByUserIDFunc != nil {
 		return m.FindByUserIDFunc(userID)
@@ -60,16 +303,62 @@ func (m *MockRepository) CreateImage(campaignImage CampaignImage) (CampaignImage
 	return CampaignImage{}, nil
 }
 
-func (m *MockRepository) MarkAllImagesAsNonPrimary(campaignID int) (bool, error) {
-	if m.MarkAllImagesAsNonPrimaryFunc != nil {
-		return m.MarkAllImagesAsNonPrimaryFunc(campaignID)
+
+
+type MockCategoryRepository struct {
+	category.Repository
+	Categories map[int]category.Category
+}
+
+func (m *MockCategoryRepository) FindByID(ID int) (category.Category, error) {
+	return m.Categories[ID], nil
+}
+
+type MockNotificationService struct {
+	notification.Service
+	Sent []notification.Notification
+}
+
+func (m *MockNotificationService) Notify(userIDs []int, message notification.Notification) error {
+	for _, userID := range userIDs {
+		message.UserID = userID
+		m.Sent = append(m.Sent, message)
 	}
-	return false, nil
+	return nil
+}
+
+// testFakes are the collaborators of a service built by newTestService, for
+// tests that inspect what it looked up, recorded or sent.
+type testFakes struct {
+	categories    *MockCategoryRepository
+	audit         *audittest.Service
+	mailer        *MockMailer
+	notifications *MockNotificationService
+}
+
+func newTestService(repo Repository) (*service, testFakes) {
+	fakes := testFakes{
+		categories:    &MockCategoryRepository{},
+		audit:         &audittest.Service{},
+		mailer:        &MockMailer{},
+		notifications: &MockNotificationService{},
+	}
+
+	return NewService(repo, fakes.categories, fakes.audit, fakes.mailer, fakes.notifications), fakes
+}
+
+type MockMailer struct {
+	Messages []mailer.Message
+}
+
+func (m *MockMailer) Send(message mailer.Message) error {
+	m.Messages = append(m.Messages, message)
+	return nil
 }
 
 func TestGetCampaigns(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service, _ := newTestService(repo)
 
 	t.Run("Test GetCampaigns for specific user", func(t *testing.T) {
 		mockUserID := 1
@@ -145,7 +434,7 @@ func TestGetCampaigns(t *testing.T) {
 
 func TestGetCampaignByID(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service, _ := newTestService(repo)
 
 	t.Run("Test GetCampaignByID for existing campaign", func(t *testing.T) {
 		mockCampaignID := 1
@@ -202,7 +491,8 @@ func TestGetCampaignByID(t *testing.T) {
 
 func TestCreateCampaign(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service, fakes := newTestService(repo)
+	endDate := time.Now().AddDate(0, 1, 0)
 
 	t.Run("Test CreateCampaign success", func(t *testing.T) {
 		mockInput := CreateCampaignInput{
@@ -210,6 +500,7 @@ func TestCreateCampaign(t *testing.T) {
 			ShortDescription: "Short description",
 			Description:      "Description",
 			GoalAmount:       1000,
+			EndDate:          &endDate,
 			User:             user.User{ID: 1},
 		}
 
@@ -222,6 +513,8 @@ func TestCreateCampaign(t *testing.T) {
 		}
 
 		repo.SaveFunc = func(campaign Campaign) (Campaign, error) {
+			assert.Equal(t, StatusPendingReview, campaign.Status)
+			assert.NotNil(t, campaign.SubmittedAt)
 			return expectedCampaign, nil
 		}
 
@@ -230,6 +523,9 @@ func TestCreateCampaign(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, newCampaign)
 		assert.Equal(t, expectedCampaign, newCampaign)
+		assert.Len(t, fakes.audit.Records, 1)
+		assert.Equal(t, audit.ActionCampaignCreate, fakes.audit.Records[0].Action)
+		assert.Equal(t, audit.TargetCampaign, fakes.audit.Records[0].TargetType)
 	})
 
 	t.Run("Test CreateCampaign with error", func(t *testing.T) {
@@ -237,17 +533,242 @@ func TestCreateCampaign(t *testing.T) {
 			return Campaign{}, errors.New("unable to save campaign")
 		}
 
-		newCampaign, err := service.CreateCampaign(CreateCampaignInput{})
+		newCampaign, err := service.CreateCampaign(CreateCampaignInput{EndDate: &endDate})
 
 		assert.Error(t, err)
 		assert.EqualError(t, err, "unable to save campaign")
 		assert.Equal(t, Campaign{}, newCampaign)
 	})
+
+	t.Run("Test CreateCampaign with past end date", func(t *testing.T) {
+		pastDate := time.Now().Add(-time.Hour)
+
+		_, err := service.CreateCampaign(CreateCampaignInput{EndDate: &pastDate})
+
+		assert.EqualError(t, err, "End date must be in the future")
+	})
+}
+
+func TestCreateCampaignWithCategoryAndTags(t *testing.T) {
+	repo := &MockRepository{}
+	service, fakes := newTestService(repo)
+	fakes.categories.Categories = map[int]category.Category{
+		3: {ID: 3, Name: "Disaster Relief", Slug: "disaster-relief"},
+	}
+	endDate := time.Now().AddDate(0, 1, 0)
+
+	t.Run("tags are normalized", func(t *testing.T) {
+		repo.SaveFunc = func(campaign Campaign) (Campaign, error) {
+			assert.Equal(t, 3, *campaign.CategoryID)
+			assert.Equal(t, []Tag{{ID: 1, Name: "flood"}, {ID: 2, Name: "east java"}}, campaign.Tags)
+			return campaign, nil
+		}
+
+		_, err := service.CreateCampaign(CreateCampaignInput{
+			EndDate:    &endDate,
+			CategoryID: 3,
+			Tags:       []string{" Flood", "east  Java", "flood", ""},
+		})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		_, err := service.CreateCampaign(CreateCampaignInput{EndDate: &endDate, CategoryID: 4})
+
+		assert.EqualError(t, err, "No category found with that ID")
+	})
+}
+
+func TestCreateCampaignWithRewards(t *testing.T) {
+	repo := &MockRepository{}
+	service, _ := newTestService(repo)
+	endDate := time.Now().AddDate(0, 1, 0)
+	delivery := time.Now().AddDate(0, 2, 0)
+
+	repo.SaveFunc = func(campaign Campaign) (Campaign, error) {
+		assert.Len(t, campaign.Rewards, 2)
+		assert.Equal(t, "T-shirt", campaign.Rewards[1].Title)
+		assert.Equal(t, 50, campaign.Rewards[1].Quantity)
+		return campaign, nil
+	}
+
+	_, err := service.CreateCampaign(CreateCampaignInput{
+		EndDate: &endDate,
+		Rewards: []CreateRewardInput{
+			{Title: "Thank-you card", MinimumAmount: 50000},
+			{Title: " T-shirt ", MinimumAmount: 250000, Quantity: 50, EstimatedDelivery: &delivery},
+		},
+	})
+	assert.NoError(t, err)
+
+	past := time.Now().AddDate(0, 0, -1)
+	_, err = service.CreateCampaign(CreateCampaignInput{
+		EndDate: &endDate,
+		Rewards: []CreateRewardInput{{Title: "Mug", MinimumAmount: 100000, EstimatedDelivery: &past}},
+	})
+	assert.EqualError(t, err, "Estimated delivery must be in the future")
+}
+
+func TestUpdateReward(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Status: StatusActive, EndDate: &endDate})
+	service, fakes := newTestService(repo)
+	owner := user.User{ID: 1}
+
+	repo.FindRewardByIDFunc = func(ID int) (Reward, error) {
+		switch ID {
+		case 5:
+			return Reward{ID: 5, CampaignID: 1, Title: "T-shirt", MinimumAmount: 250000, Quantity: 50, Claimed: 20}, nil
+		case 6:
+			return Reward{ID: 6, CampaignID: 2, Title: "Mug", MinimumAmount: 100000}, nil
+		}
+		return Reward{}, nil
+	}
+
+	t.Run("not below claimed", func(t *testing.T) {
+		_, err := service.UpdateReward(GetRewardDetailInput{CampaignID: 1, ID: 5}, CreateRewardInput{Title: "T-shirt", MinimumAmount: 250000, Quantity: 10, User: owner})
+		assert.EqualError(t, err, "Quantity cannot be lower than the number of rewards already claimed")
+	})
+
+	t.Run("reward of another campaign", func(t *testing.T) {
+		_, err := service.UpdateReward(GetRewardDetailInput{CampaignID: 1, ID: 6}, CreateRewardInput{Title: "Mug", MinimumAmount: 100000, User: owner})
+		assert.EqualError(t, err, "No reward found with that ID")
+	})
+
+	t.Run("not an owner", func(t *testing.T) {
+		_, err := service.UpdateReward(GetRewardDetailInput{CampaignID: 1, ID: 5}, CreateRewardInput{Title: "T-shirt", MinimumAmount: 250000, User: user.User{ID: 2}})
+		assert.EqualError(t, err, "Not an owner of the campaign.")
+	})
+
+	t.Run("success", func(t *testing.T) {
+		updatedReward, err := service.UpdateReward(GetRewardDetailInput{CampaignID: 1, ID: 5}, CreateRewardInput{Title: "Hoodie", MinimumAmount: 300000, Quantity: 30, User: owner})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Hoodie", updatedReward.Title)
+		assert.Equal(t, 20, updatedReward.Claimed)
+		assert.Equal(t, 10, updatedReward.Remaining())
+		assert.Equal(t, audit.ActionRewardUpdate, fakes.audit.Records[0].Action)
+	})
+}
+
+func TestRewardAvailability(t *testing.T) {
+	assert.True(t, Reward{Quantity: 0, Claimed: 100}.IsAvailable())
+	assert.True(t, Reward{Quantity: 5, Claimed: 4}.IsAvailable())
+	assert.False(t, Reward{Quantity: 5, Claimed: 5}.IsAvailable())
+	assert.Equal(t, 0, Reward{Quantity: 5, Claimed: 7}.Remaining())
+	assert.Nil(t, FormatReward(Reward{Quantity: 0}).Remaining)
+}
+
+func TestCreateCampaignUpdate(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Name: "Clean water", Status: StatusEnded, EndDate: &endDate})
+	repo.DonorIDs = []int{2, 3, 1}
+	repo.FollowerIDs = []int{3, 4}
+	service, fakes := newTestService(repo)
+	owner := user.User{ID: 1}
+
+	t.Run("not an owner", func(t *testing.T) {
+		_, err := service.CreateCampaignUpdate(GetCampaignDetailInput{ID: 1}, CreateCampaignUpdateInput{Title: "Hi", Body: "News", User: user.User{ID: 2}})
+		assert.EqualError(t, err, "Not an owner of the campaign.")
+	})
+
+	t.Run("public update reaches donors and followers", func(t *testing.T) {
+		fakes.notifications.Sent = nil
+
+		update, err := service.CreateCampaignUpdate(GetCampaignDetailInput{ID: 1}, CreateCampaignUpdateInput{Title: "Wells finished", Body: "All three wells are done.", User: owner})
+
+		assert.NoError(t, err)
+		assert.Equal(t, VisibilityPublic, update.Visibility)
+
+		recipients := []int{}
+		for _, sent := range fakes.notifications.Sent {
+			recipients = append(recipients, sent.UserID)
+		}
+		// The owner is left out; Notify itself drops duplicates.
+		assert.Equal(t, []int{2, 3, 3, 4}, recipients)
+		assert.Equal(t, "Clean water: Wells finished", fakes.notifications.Sent[0].Title)
+	})
+
+	t.Run("donors-only update skips followers", func(t *testing.T) {
+		fakes.notifications.Sent = nil
+
+		_, err := service.CreateCampaignUpdate(GetCampaignDetailInput{ID: 1}, CreateCampaignUpdateInput{Title: "Receipts", Body: "Invoices attached.", Visibility: VisibilityDonorsOnly, User: owner})
+
+		assert.NoError(t, err)
+		assert.Len(t, fakes.notifications.Sent, 2)
+	})
+}
+
+func TestGetCampaignUpdates(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Status: StatusActive, EndDate: &endDate})
+	repo.DonorIDs = []int{2}
+	service, _ := newTestService(repo)
+
+	var included bool
+	repo.FindUpdatesFunc = func(campaignID int, includeDonorsOnly bool) ([]CampaignUpdate, error) {
+		included = includeDonorsOnly
+		return []CampaignUpdate{}, nil
+	}
+
+	for _, tc := range []struct {
+		name   string
+		viewer int
+		want   bool
+	}{
+		{"owner", 1, true},
+		{"donor", 2, true},
+		{"other user", 3, false},
+		{"anonymous", 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.GetCampaignUpdates(GetCampaignUpdatesInput{ID: 1, User: user.User{ID: tc.viewer}})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, included)
+		})
+	}
+}
+
+func TestSaveCampaignUpdateImage(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Status: StatusActive, EndDate: &endDate})
+	service, _ := newTestService(repo)
+
+	repo.FindUpdateByIDFunc = func(ID int) (CampaignUpdate, error) {
+		if ID == 3 {
+			return CampaignUpdate{ID: 3, CampaignID: 1, Images: make([]CampaignUpdateImage, maxUpdateImages)}, nil
+		}
+		return CampaignUpdate{ID: ID, CampaignID: 1}, nil
+	}
+
+	_, err := service.SaveCampaignUpdateImage(CreateCampaignUpdateImageInput{CampaignID: 1, UpdateID: 2, User: user.User{ID: 1}}, "https://img.example.com/a.jpg")
+	assert.NoError(t, err)
+
+	_, err = service.SaveCampaignUpdateImage(CreateCampaignUpdateImageInput{CampaignID: 1, UpdateID: 3, User: user.User{ID: 1}}, "https://img.example.com/b.jpg")
+	assert.EqualError(t, err, "An update can have at most 5 images")
+}
+
+func TestGetCampaignFacets(t *testing.T) {
+	repo := &MockRepository{}
+	service, _ := newTestService(repo)
+
+	repo.FacetsFunc = func(input GetCampaignsInput) (Facets, error) {
+		assert.Equal(t, "east java", input.Tag)
+		return Facets{Tags: []TagFacet{{Name: "east java", Count: 2}}}, nil
+	}
+
+	facets, err := service.GetCampaignFacets(GetCampaignsInput{Tag: " East Java ", Statuses: PublicStatuses})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), facets.Tags[0].Count)
+	assert.Equal(t, []CategoryFacetFormatter{}, FormatCampaignFacets(facets).Categories)
 }
 
 func TestUpdateCampaign(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service, _ := newTestService(repo)
 
 	t.Run("Test UpdateCampaign success", func(t *testing.T) {
 		mockInputID := 1
@@ -290,6 +811,8 @@ func TestUpdateCampaign(t *testing.T) {
 
 		updatedCampaign, err := service.UpdateCampaign(GetCampaignDetailInput{ID: mockInputID}, mockInputData)
 
+		expectedUpdatedCampaign.Slug = "updated-campaign"
+
 		assert.NoError(t, err)
 		assert.NotNil(t, updatedCampaign)
 		assert.Equal(t, expectedUpdatedCampaign, updatedCampaign)
@@ -310,7 +833,7 @@ func TestUpdateCampaign(t *testing.T) {
 
 func TestUpdateCampaign_NotOwner(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service, _ := newTestService(repo)
 
 	mockCampaignID := 1
 	mockUserID := 2 
@@ -329,7 +852,7 @@ func TestUpdateCampaign_NotOwner(t *testing.T) {
 
 func TestSaveCampaignImage(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service, _ := newTestService(repo)
 
 	t.Run("Test SaveCampaignImage success", func(t *testing.T) {
 		mockCampaignID := 1
@@ -395,7 +918,7 @@ func TestSaveCampaignImage(t *testing.T) {
 
 func TestSaveCampaignImage_NotOwner(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service, _ := newTestService(repo)
 
 	mockUser := user.User{
 		ID:   1,
@@ -426,3 +949,567 @@ func TestSaveCampaignImage_NotOwner(t *testing.T) {
 	assert.EqualError(t, err, "Not an owner of the campaign.")
 }
 
+
+func TestSearchCampaigns(t *testing.T) {
+	repo := &MockRepository{}
+	service, _ := newTestService(repo)
+
+	t.Run("Test SearchCampaigns applies defaults", func(t *testing.T) {
+		var searched GetCampaignsInput
+
+		repo.SearchFunc = func(input GetCampaignsInput) ([]Campaign, int64, error) {
+			searched = input
+			return []Campaign{{ID: 1}, {ID: 2}}, 42, nil
+		}
+
+		campaigns, total, input, err := service.SearchCampaigns(GetCampaignsInput{Query: "  water  "})
+
+		assert.NoError(t, err)
+		assert.Len(t, campaigns, 2)
+		assert.Equal(t, int64(42), total)
+		assert.Equal(t, 1, input.Page)
+		assert.Equal(t, defaultCampaignsPageLimit, input.Limit)
+		assert.Equal(t, SortNewest, input.Sort)
+		assert.Equal(t, "water", searched.Query)
+	})
+
+	t.Run("Test SearchCampaigns with invalid progress range", func(t *testing.T) {
+		repo.SearchFunc = func(input GetCampaignsInput) ([]Campaign, int64, error) {
+			t.Fatal("repository should not be searched")
+			return nil, 0, nil
+		}
+
+		_, _, _, err := service.SearchCampaigns(GetCampaignsInput{MinProgress: 80, MaxProgress: 20})
+
+		assert.EqualError(t, err, "Minimum progress cannot be greater than maximum progress")
+	})
+}
+
+func TestCampaignProgress(t *testing.T) {
+	assert.Equal(t, 0.0, Campaign{GoalAmount: 0, CurrentAmount: 100}.Progress())
+	assert.Equal(t, 33.33, Campaign{GoalAmount: 300, CurrentAmount: 100}.Progress())
+	assert.Equal(t, 150.0, Campaign{GoalAmount: 200, CurrentAmount: 300}.Progress())
+}
+
+func newStatefulRepository(campaign Campaign) *MockRepository {
+	repo := &MockRepository{}
+
+	repo.FindByIDFunc = func(ID int) (Campaign, error) {
+		if ID == campaign.ID {
+			return campaign, nil
+		}
+		return Campaign{}, nil
+	}
+	repo.UpdateFunc = func(updatedCampaign Campaign) (Campaign, error) {
+		campaign = updatedCampaign
+		return campaign, nil
+	}
+
+	return repo
+}
+
+func TestCampaignLifecycle(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Status: StatusDraft, EndDate: &endDate})
+	service, fakes := newTestService(repo)
+
+	_, err := service.SubmitCampaign(SubmitCampaignInput{ID: 1, User: user.User{ID: 2}})
+	assert.EqualError(t, err, "Not an owner of the campaign.")
+
+	_, err = service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: StatusActive})
+	assert.EqualError(t, err, "Campaign cannot move from draft to active")
+
+	submittedCampaign, err := service.SubmitCampaign(SubmitCampaignInput{ID: 1, User: user.User{ID: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPendingReview, submittedCampaign.Status)
+
+	activeCampaign, err := service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: StatusActive, Actor: audit.Actor{ID: 9}})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusActive, activeCampaign.Status)
+	assert.True(t, activeCampaign.AcceptsDonations(time.Now()))
+	assert.Equal(t, 9, activeCampaign.ReviewedBy)
+	assert.NotNil(t, activeCampaign.ReviewedAt)
+
+	_, err = service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: "paused"})
+	assert.EqualError(t, err, "Invalid campaign status")
+
+	assert.Len(t, fakes.audit.Records, 2)
+	assert.Equal(t, audit.ActionCampaignStatus, fakes.audit.Records[1].Action)
+	assert.Equal(t, StatusActive, fakes.audit.Records[1].Metadata["to"])
+}
+
+func TestReviewCampaign(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	owner := user.User{ID: 1, Name: "Owner", Email: "owner@example.com"}
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, User: owner, Name: "Clean water", Status: StatusPendingReview, EndDate: &endDate})
+	service, fakes := newTestService(repo)
+	moderator := audit.Actor{ID: 9}
+
+	t.Run("reject requires a reason", func(t *testing.T) {
+		_, err := service.RejectCampaign(ReviewCampaignInput{ID: 1, Reason: "  ", Actor: moderator})
+		assert.EqualError(t, err, "A reason is required to reject a campaign")
+	})
+
+	t.Run("reject sends the campaign back to draft", func(t *testing.T) {
+		rejectedCampaign, err := service.RejectCampaign(ReviewCampaignInput{ID: 1, Reason: "Add a budget breakdown", Actor: moderator})
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusDraft, rejectedCampaign.Status)
+		assert.Equal(t, "Add a budget breakdown", rejectedCampaign.RejectionReason)
+		assert.Equal(t, 9, rejectedCampaign.ReviewedBy)
+		assert.Len(t, fakes.mailer.Messages, 1)
+		assert.Equal(t, "owner@example.com", fakes.mailer.Messages[0].To)
+		assert.Contains(t, fakes.mailer.Messages[0].Body, "Add a budget breakdown")
+		assert.Equal(t, audit.ActionCampaignReject, fakes.audit.Records[0].Action)
+	})
+
+	t.Run("only campaigns waiting for review can be approved", func(t *testing.T) {
+		_, err := service.ApproveCampaign(ReviewCampaignInput{ID: 1, Actor: moderator})
+		assert.EqualError(t, err, "Campaign is not waiting for review")
+	})
+
+	t.Run("owner resubmits and it is approved", func(t *testing.T) {
+		_, err := service.SubmitCampaign(SubmitCampaignInput{ID: 1, User: owner})
+		assert.NoError(t, err)
+
+		_, err = service.ApproveCampaign(ReviewCampaignInput{ID: 1, Actor: audit.Actor{ID: owner.ID}})
+		assert.EqualError(t, err, "You cannot review your own campaign")
+
+		_, err = service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: StatusActive, Actor: audit.Actor{ID: owner.ID}})
+		assert.EqualError(t, err, "You cannot review your own campaign")
+
+		approvedCampaign, err := service.ApproveCampaign(ReviewCampaignInput{ID: 1, Actor: moderator})
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusActive, approvedCampaign.Status)
+		assert.Empty(t, approvedCampaign.RejectionReason)
+		assert.True(t, approvedCampaign.IsPublic())
+		assert.Len(t, fakes.mailer.Messages, 2)
+		assert.Equal(t, audit.ActionCampaignApprove, fakes.audit.Records[len(fakes.audit.Records)-1].Action)
+	})
+}
+
+func TestApprovedCampaignEdits(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	laterDate := endDate.AddDate(0, 0, 7)
+	owner := user.User{ID: 1}
+	approved := Campaign{ID: 1, UserID: owner.ID, Name: "Clean Water", ShortDescription: "Wells", Description: "Wells for Sumba", GoalAmount: 10000, Status: StatusActive, EndDate: &endDate}
+	repo := newStatefulRepository(approved)
+	service, _ := newTestService(repo)
+
+	t.Run("reviewed fields are locked", func(t *testing.T) {
+		_, err := service.UpdateCampaign(GetCampaignDetailInput{ID: 1}, CreateCampaignInput{Name: "Clean Water", ShortDescription: "Wells", Description: "Something else entirely", GoalAmount: 10000, EndDate: &endDate, User: owner})
+
+		assert.EqualError(t, err, "The name, descriptions, goal and category of an approved campaign cannot be changed")
+	})
+
+	t.Run("end date and tags can change", func(t *testing.T) {
+		updatedCampaign, err := service.UpdateCampaign(GetCampaignDetailInput{ID: 1}, CreateCampaignInput{Name: "Clean Water", ShortDescription: "Wells", Description: "Wells for Sumba", GoalAmount: 10000, EndDate: &laterDate, Tags: []string{"water"}, User: owner})
+
+		assert.NoError(t, err)
+		assert.True(t, laterDate.Equal(*updatedCampaign.EndDate))
+	})
+
+	t.Run("no new images", func(t *testing.T) {
+		_, err := service.SaveCampaignImage(CreateCampaignImageInput{CampaignID: 1, User: owner}, "new.jpg")
+
+		assert.EqualError(t, err, "Images cannot be added to an approved campaign")
+	})
+}
+
+func TestGetReviewQueue(t *testing.T) {
+	repo := &MockRepository{}
+	service, _ := newTestService(repo)
+
+	repo.SearchFunc = func(input GetCampaignsInput) ([]Campaign, int64, error) {
+		assert.Equal(t, StatusPendingReview, input.Status)
+		assert.Empty(t, input.Statuses)
+		assert.Equal(t, SortSubmitted, input.Sort)
+		return []Campaign{{ID: 1, Status: StatusPendingReview}}, 1, nil
+	}
+
+	campaigns, total, _, err := service.GetReviewQueue(GetCampaignsInput{Statuses: PublicStatuses})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, campaigns, 1)
+}
+
+func TestEndExpiredCampaigns(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	repo := &MockRepository{}
+	service, _ := newTestService(repo)
+
+	updated := []Campaign{}
+
+	repo.FindExpiredFunc = func(now time.Time) ([]Campaign, error) {
+		return []Campaign{
+			{ID: 1, Status: StatusActive, EndDate: &past},
+			{ID: 2, Status: StatusGoalReached, EndDate: &past},
+		}, nil
+	}
+	repo.UpdateFunc = func(campaign Campaign) (Campaign, error) {
+		updated = append(updated, campaign)
+		return campaign, nil
+	}
+
+	endedCampaigns, err := service.EndExpiredCampaigns(time.Now())
+
+	assert.NoError(t, err)
+	assert.Len(t, endedCampaigns, 2)
+	assert.Equal(t, StatusEnded, updated[0].Status)
+	assert.Equal(t, StatusEnded, updated[1].Status)
+	assert.False(t, updated[0].AcceptsDonations(time.Now()))
+}
+
+func TestEndExpiredCampaigns_ContinuesPastFailures(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	repo := &MockRepository{}
+	service, fakes := newTestService(repo)
+
+	stored := map[int]Campaign{
+		1: {ID: 1, Status: StatusActive, EndDate: &past},
+		2: {ID: 2, Status: StatusEnded, EndDate: &past},
+		3: {ID: 3, Status: StatusActive, EndDate: &past},
+		4: {ID: 4, Status: StatusActive, EndDate: &past},
+	}
+
+	repo.FindExpiredFunc = func(now time.Time) ([]Campaign, error) {
+		// Campaign 2 was ended by another instance after it was read.
+		return []Campaign{stored[1], {ID: 2, Status: StatusActive, EndDate: &past}, stored[3], stored[4]}, nil
+	}
+	repo.FindByIDFunc = func(ID int) (Campaign, error) {
+		return stored[ID], nil
+	}
+	repo.UpdateFunc = func(campaign Campaign) (Campaign, error) {
+		if campaign.ID == 3 {
+			return campaign, errors.New("deadlock found")
+		}
+		stored[campaign.ID] = campaign
+		return campaign, nil
+	}
+
+	endedCampaigns, err := service.EndExpiredCampaigns(time.Now())
+
+	assert.EqualError(t, err, "campaign 3: deadlock found")
+	assert.Len(t, endedCampaigns, 2)
+	assert.Equal(t, 1, endedCampaigns[0].ID)
+	assert.Equal(t, 4, endedCampaigns[1].ID)
+	assert.Equal(t, StatusEnded, stored[4].Status)
+	assert.Len(t, fakes.audit.Records, 2)
+}
+
+func TestCampaignSlugs(t *testing.T) {
+	repo := &MockRepository{
+		Slugs:       map[string]int{"clean-water": 1, "clean-water-2": 2, "old-name": 3},
+		SlugHistory: map[string]int{"old-name": 3},
+	}
+	service, _ := newTestService(repo)
+	endDate := time.Now().AddDate(0, 1, 0)
+
+	t.Run("suffixes taken slugs", func(t *testing.T) {
+		repo.SaveFunc = func(campaign Campaign) (Campaign, error) {
+			return campaign, nil
+		}
+
+		newCampaign, err := service.CreateCampaign(CreateCampaignInput{Name: "Clean Water!", EndDate: &endDate, User: user.User{ID: 7}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "clean-water-3", newCampaign.Slug)
+	})
+
+	t.Run("old slugs stay reserved", func(t *testing.T) {
+		newCampaign, err := service.CreateCampaign(CreateCampaignInput{Name: "Old Name", EndDate: &endDate, User: user.User{ID: 7}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "old-name-2", newCampaign.Slug)
+	})
+
+	t.Run("rename moves the slug and keeps the old one", func(t *testing.T) {
+		campaigns := map[int]Campaign{2: {ID: 2, UserID: 7, Name: "Clean Water", Slug: "clean-water-2", EndDate: &endDate, Status: StatusDraft}}
+		repo.FindByIDFunc = func(ID int) (Campaign, error) {
+			return campaigns[ID], nil
+		}
+		repo.UpdateFunc = func(campaign Campaign) (Campaign, error) {
+			return campaign, nil
+		}
+
+		updatedCampaign, err := service.UpdateCampaign(GetCampaignDetailInput{ID: 2}, CreateCampaignInput{Name: "Water for Sumba", EndDate: &endDate, User: user.User{ID: 7}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "water-for-sumba", updatedCampaign.Slug)
+		campaigns[2] = updatedCampaign
+
+		current, err := service.GetCampaignBySlug(GetCampaignSlugInput{Slug: "water-for-sumba"})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, current.ID)
+
+		previous, err := service.GetCampaignBySlug(GetCampaignSlugInput{Slug: "clean-water-2"})
+		assert.NoError(t, err)
+		assert.Equal(t, "water-for-sumba", previous.Slug)
+	})
+
+	t.Run("same slug after rename is kept", func(t *testing.T) {
+		updatedCampaign, err := service.UpdateCampaign(GetCampaignDetailInput{ID: 2}, CreateCampaignInput{Name: "Water For Sumba", EndDate: &endDate, User: user.User{ID: 7}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "water-for-sumba", updatedCampaign.Slug)
+	})
+
+	t.Run("retries when another campaign saves the slug first", func(t *testing.T) {
+		attempts := 0
+		repo.SaveFunc = func(campaign Campaign) (Campaign, error) {
+			attempts++
+			if attempts == 1 {
+				repo.Slugs[campaign.Slug] = 8
+				return campaign, ErrSlugTaken
+			}
+			return campaign, nil
+		}
+
+		newCampaign, err := service.CreateCampaign(CreateCampaignInput{Name: "Solar Lamps", EndDate: &endDate, User: user.User{ID: 7}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, "solar-lamps-2", newCampaign.Slug)
+	})
+
+	t.Run("gives up after repeated conflicts", func(t *testing.T) {
+		repo.SaveFunc = func(campaign Campaign) (Campaign, error) {
+			return campaign, ErrSlugTaken
+		}
+
+		_, err := service.CreateCampaign(CreateCampaignInput{Name: "Solar Lamps", EndDate: &endDate, User: user.User{ID: 7}})
+
+		assert.ErrorIs(t, err, ErrSlugTaken)
+	})
+
+	t.Run("unknown slug", func(t *testing.T) {
+		_, err := service.GetCampaignBySlug(GetCampaignSlugInput{Slug: "missing"})
+		assert.EqualError(t, err, "No campaign found with that slug")
+	})
+}
+
+func TestManageCampaignImages(t *testing.T) {
+	owner := user.User{ID: 1}
+	gallery := []CampaignImage{
+		{ID: 11, CampaignID: 1, FileName: "a.jpg", IsPrimary: 1, Position: 1},
+		{ID: 12, CampaignID: 1, FileName: "b.jpg", Position: 2},
+		{ID: 13, CampaignID: 1, FileName: "c.jpg", Position: 3},
+	}
+	repo := &MockRepository{
+		FindByIDFunc: func(ID int) (Campaign, error) {
+			return Campaign{ID: 1, UserID: owner.ID, CampaignImages: gallery}, nil
+		},
+		CreateImageFunc: func(campaignImage CampaignImage) (CampaignImage, error) {
+			return campaignImage, nil
+		},
+	}
+	service, _ := newTestService(repo)
+
+	t.Run("new images go last", func(t *testing.T) {
+		newImage, err := service.SaveCampaignImage(CreateCampaignImageInput{CampaignID: 1, PublicID: "campaigns/d", User: owner}, "d.jpg")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, newImage.Position)
+		assert.Equal(t, 0, newImage.IsPrimary)
+		assert.Equal(t, "campaigns/d", newImage.PublicID)
+	})
+
+	t.Run("image limit", func(t *testing.T) {
+		full := Campaign{ID: 1, UserID: owner.ID, CampaignImages: make([]CampaignImage, maxCampaignImages)}
+		fullRepo := &MockRepository{FindByIDFunc: func(ID int) (Campaign, error) { return full, nil }}
+		fullService, _ := newTestService(fullRepo)
+
+		_, err := fullService.SaveCampaignImage(CreateCampaignImageInput{CampaignID: 1, User: owner}, "e.jpg")
+
+		assert.EqualError(t, err, "A campaign can have at most 10 images")
+	})
+
+	t.Run("deleting the primary image promotes the next one", func(t *testing.T) {
+		deletedImage, err := service.DeleteCampaignImage(GetCampaignImageDetailInput{CampaignID: 1, ID: 11}, owner, audit.Actor{ID: owner.ID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 11, deletedImage.ID)
+		assert.Equal(t, []int{11}, repo.DeletedImageIDs)
+		assert.Equal(t, 12, repo.PrimaryImageID)
+	})
+
+	t.Run("set primary", func(t *testing.T) {
+		primaryImage, err := service.SetPrimaryCampaignImage(GetCampaignImageDetailInput{CampaignID: 1, ID: 13}, owner, audit.Actor{ID: owner.ID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, primaryImage.IsPrimary)
+		assert.Equal(t, 13, repo.PrimaryImageID)
+	})
+
+	t.Run("move", func(t *testing.T) {
+		images, err := service.MoveCampaignImage(GetCampaignImageDetailInput{CampaignID: 1, ID: 13}, MoveCampaignImageInput{Position: 1, User: owner})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 13, images[0].ID)
+		assert.Equal(t, 11, images[1].ID)
+		assert.Equal(t, 3, images[2].Position)
+		assert.Equal(t, images, repo.ImagePositions)
+
+		_, err = service.MoveCampaignImage(GetCampaignImageDetailInput{CampaignID: 1, ID: 13}, MoveCampaignImageInput{Position: 4, User: owner})
+		assert.EqualError(t, err, "Position must be between 1 and 3")
+	})
+
+	t.Run("unknown image or not the owner", func(t *testing.T) {
+		_, err := service.DeleteCampaignImage(GetCampaignImageDetailInput{CampaignID: 1, ID: 99}, owner, audit.Actor{})
+		assert.EqualError(t, err, "No image found with that ID")
+
+		_, err = service.SetPrimaryCampaignImage(GetCampaignImageDetailInput{CampaignID: 1, ID: 12}, user.User{ID: 2}, audit.Actor{})
+		assert.EqualError(t, err, "Not an owner of the campaign.")
+	})
+}
+
+func TestFormatCampaignPrimaryImage(t *testing.T) {
+	campaign := Campaign{CampaignImages: []CampaignImage{
+		{ID: 1, FileName: "first.jpg", Position: 1},
+		{ID: 2, FileName: "primary.jpg", IsPrimary: 1, Position: 2},
+	}}
+
+	assert.Equal(t, "primary.jpg", FormatCampaign(campaign).ImageURL)
+	assert.Equal(t, "primary.jpg", FormatCampaignDetail(campaign).ImageURL)
+
+	campaign.CampaignImages[1].IsPrimary = 0
+	assert.Equal(t, "first.jpg", FormatCampaign(campaign).ImageURL)
+}
+
+func TestCloseCampaign(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Status: StatusPendingReview, EndDate: &endDate})
+	repo.PendingDonations = 2
+	service, fakes := newTestService(repo)
+
+	_, err := service.CloseCampaign(CloseCampaignInput{ID: 1, User: user.User{ID: 1}})
+	assert.EqualError(t, err, "Only running campaigns can be closed")
+
+	_, err = service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: StatusActive})
+	assert.NoError(t, err)
+
+	_, err = service.CloseCampaign(CloseCampaignInput{ID: 1, User: user.User{ID: 2}})
+	assert.EqualError(t, err, "Not an owner of the campaign.")
+
+	closedCampaign, err := service.CloseCampaign(CloseCampaignInput{ID: 1, User: user.User{ID: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusEnded, closedCampaign.Status)
+	assert.Equal(t, int64(2), repo.PendingDonations, "pending donations of a closed campaign may still be paid")
+	assert.Equal(t, audit.ActionCampaignClose, fakes.audit.Records[len(fakes.audit.Records)-1].Action)
+}
+
+func TestCancelCampaign(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Name: "Clean Water", Status: StatusActive, EndDate: &endDate})
+	repo.DonorIDs = []int{2}
+	repo.PendingDonorIDs = []int{3}
+	repo.FollowerIDs = []int{1, 4}
+	repo.PendingDonations = 2
+	service, fakes := newTestService(repo)
+
+	_, err := service.CancelCampaign(CancelCampaignInput{ID: 1, Reason: "  ", User: user.User{ID: 1}})
+	assert.EqualError(t, err, "A reason is required to cancel a campaign")
+
+	cancelledCampaign, err := service.CancelCampaign(CancelCampaignInput{ID: 1, Reason: " The venue fell through. ", User: user.User{ID: 1}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelledCampaign.Status)
+	assert.Equal(t, StatusActive, cancelledCampaign.CancelledFrom)
+	assert.NotNil(t, cancelledCampaign.CancelledAt)
+	assert.Equal(t, "The venue fell through.", cancelledCampaign.CancellationReason)
+	assert.True(t, cancelledCampaign.IsViewable())
+	assert.False(t, cancelledCampaign.IsPublic())
+	assert.False(t, cancelledCampaign.IsEditable())
+
+	assert.Equal(t, int64(0), repo.PendingDonations)
+	record := fakes.audit.Records[len(fakes.audit.Records)-1]
+	assert.Equal(t, audit.ActionCampaignCancel, record.Action)
+	assert.Equal(t, int64(2), record.Metadata["cancelled_donations"])
+
+	recipients := []int{}
+	for _, sent := range fakes.notifications.Sent {
+		assert.Equal(t, notification.TypeCampaignCancelled, sent.Type)
+		assert.Equal(t, "The venue fell through.", sent.Body)
+		recipients = append(recipients, sent.UserID)
+	}
+	assert.Equal(t, []int{2, 3, 4}, recipients)
+
+	_, err = service.CancelCampaign(CancelCampaignInput{ID: 1, Reason: "Again", User: user.User{ID: 1}})
+	assert.EqualError(t, err, "Campaign cannot move from cancelled to cancelled")
+}
+
+func TestUpdateCampaignStatus_Cancel(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Name: "Clean Water", Status: StatusActive, EndDate: &endDate})
+	repo.DonorIDs = []int{2}
+	service, fakes := newTestService(repo)
+
+	_, err := service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: StatusCancelled, Actor: audit.Actor{ID: 9}})
+	assert.EqualError(t, err, "A reason is required to cancel a campaign")
+
+	cancelledCampaign, err := service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: StatusCancelled, Reason: "Fraud report confirmed", Actor: audit.Actor{ID: 9}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelledCampaign.Status)
+	assert.Equal(t, "Fraud report confirmed", cancelledCampaign.CancellationReason)
+	assert.Equal(t, audit.ActionCampaignCancel, fakes.audit.Records[len(fakes.audit.Records)-1].Action)
+	assert.Len(t, fakes.notifications.Sent, 1)
+	assert.Equal(t, 2, fakes.notifications.Sent[0].UserID)
+}
+
+func TestCancelCampaign_RetryAfterFailure(t *testing.T) {
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Name: "Clean Water", Status: StatusActive})
+	repo.PendingDonations = 2
+	update := repo.UpdateFunc
+	repo.UpdateFunc = func(campaign Campaign) (Campaign, error) {
+		return Campaign{}, errors.New("connection lost")
+	}
+	service, _ := newTestService(repo)
+
+	_, err := service.CancelCampaign(CancelCampaignInput{ID: 1, Reason: "Duplicate", User: user.User{ID: 1}})
+	assert.EqualError(t, err, "connection lost")
+	assert.Equal(t, int64(2), repo.PendingDonations, "a failed cancel keeps its donations pending")
+
+	repo.UpdateFunc = update
+	cancelledCampaign, err := service.CancelCampaign(CancelCampaignInput{ID: 1, Reason: "Duplicate", User: user.User{ID: 1}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelledCampaign.Status)
+	assert.Equal(t, int64(0), repo.PendingDonations)
+}
+
+func TestCancelledDraftIsNotViewable(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Status: StatusDraft, EndDate: &endDate})
+	service, _ := newTestService(repo)
+
+	cancelledCampaign, err := service.CancelCampaign(CancelCampaignInput{ID: 1, Reason: "Duplicate", User: user.User{ID: 1}})
+
+	assert.NoError(t, err)
+	assert.False(t, cancelledCampaign.IsViewable())
+}
+
+func TestArchiveCampaign(t *testing.T) {
+	endDate := time.Now().AddDate(0, 0, 7)
+	repo := newStatefulRepository(Campaign{ID: 1, UserID: 1, Status: StatusActive, EndDate: &endDate})
+	service, fakes := newTestService(repo)
+
+	_, err := service.ArchiveCampaign(ArchiveCampaignInput{ID: 1, User: user.User{ID: 1}})
+	assert.EqualError(t, err, "Only draft, ended or cancelled campaigns can be archived")
+
+	_, err = service.CloseCampaign(CloseCampaignInput{ID: 1, User: user.User{ID: 1}})
+	assert.NoError(t, err)
+
+	archivedCampaign, err := service.ArchiveCampaign(ArchiveCampaignInput{ID: 1, User: user.User{ID: 1}})
+	assert.NoError(t, err)
+	assert.True(t, archivedCampaign.IsArchived())
+	assert.Equal(t, audit.ActionCampaignArchive, fakes.audit.Records[len(fakes.audit.Records)-1].Action)
+
+	_, err = service.ArchiveCampaign(ArchiveCampaignInput{ID: 1, User: user.User{ID: 1}})
+	assert.EqualError(t, err, "Campaign is already archived")
+
+	_, err = service.UpdateCampaignStatus(UpdateCampaignStatusInput{ID: 1, Status: StatusCancelled, Reason: "Duplicate"})
+	assert.EqualError(t, err, "Campaign is archived")
+}
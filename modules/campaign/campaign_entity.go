@@ -1,33 +1,300 @@
 package campaign
 
 import (
+	"crowdfunding-minpro-alterra/modules/category"
 	"crowdfunding-minpro-alterra/modules/user"
+	"math"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatusDraft         = "draft"
+	StatusPendingReview = "pending_review"
+	StatusActive        = "active"
+	StatusGoalReached   = "goal_reached"
+	StatusEnded         = "ended"
+	StatusCancelled     = "cancelled"
 )
 
+// statusTransitions lists the states each state may move to. Ended and
+// cancelled campaigns are final. A campaign that reached its goal goes back
+// to active when a reversed payment takes it below the goal again.
+var statusTransitions = map[string][]string{
+	StatusDraft:         {StatusPendingReview, StatusCancelled},
+	StatusPendingReview: {StatusDraft, StatusActive, StatusCancelled},
+	StatusActive:        {StatusGoalReached, StatusEnded, StatusCancelled},
+	StatusGoalReached:   {StatusActive, StatusEnded, StatusCancelled},
+	StatusEnded:         {},
+	StatusCancelled:     {},
+}
+
+const (
+	VisibilityPublic     = "public"
+	VisibilityDonorsOnly = "donors_only"
+)
+
+// PublicStatuses are the states of approved campaigns, the only ones shown
+// to the public.
+var PublicStatuses = []string{StatusActive, StatusGoalReached, StatusEnded}
+
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
 type Campaign struct {
 	ID               int          `gorm:"column:id;primaryKey"`
 	UserID           int          `gorm:"column:user_id"`
 	Name             string       `gorm:"column:name"`
 	ShortDescription string       `gorm:"column:short_description"`
 	Description      string       `gorm:"column:description;type:TEXT"`
-	// Perks            string       `gorm:"column:perks;type:TEXT"`
 	BackerCount      int          `gorm:"column:backer_count"`
 	GoalAmount       int          `gorm:"column:goal_amount"`
 	CurrentAmount    int          `gorm:"column:current_amount"`
-	Slug             string       `gorm:"column:slug"`
+	Slug             string       `gorm:"column:slug;type:varchar(255);uniqueIndex"`
+	EndDate          *time.Time   `gorm:"column:end_date;index"`
+	Status           string       `gorm:"column:status;type:varchar(32);default:active;index"`
+	SubmittedAt      *time.Time   `gorm:"column:submitted_at"`
+	ReviewedAt       *time.Time   `gorm:"column:reviewed_at"`
+	ReviewedBy       int          `gorm:"column:reviewed_by"`
+	RejectionReason  string       `gorm:"column:rejection_reason;type:varchar(500)"`
+	// CancelledFrom is the status the campaign had when it was cancelled;
+	// the reason is shown publicly if it had been public.
+	CancelledFrom    string       `gorm:"column:cancelled_from;type:varchar(32)"`
+	CancelledAt      *time.Time   `gorm:"column:cancelled_at"`
+	CancellationReason string    `gorm:"column:cancellation_reason;type:varchar(500)"`
+	ArchivedAt       *time.Time   `gorm:"column:archived_at;index"`
+	CategoryID       *int         `gorm:"column:category_id;index"`
 	CreatedAt        time.Time    `gorm:"column:created_at"`
 	UpdatedAt        time.Time    `gorm:"column:updated_at"`
 	CampaignImages   []CampaignImage `gorm:"foreignKey:CampaignID"`
+	Rewards          []Reward     `gorm:"foreignKey:CampaignID"`
+	// Updates holds the public updates only; see FindUpdates for the rest.
+	Updates          []CampaignUpdate `gorm:"foreignKey:CampaignID"`
 	User             user.User    `gorm:"foreignKey:UserID"`
+	Category         category.Category `gorm:"foreignKey:CategoryID"`
+	Tags             []Tag        `gorm:"many2many:campaign_tags"`
 }
 
-type CampaignImage struct {
+// Tag is a free-form label owners attach to their campaigns. Names are
+// stored lowercase so "Flood" and "flood" are the same tag.
+type Tag struct {
+	ID   int    `gorm:"column:id;primaryKey"`
+	Name string `gorm:"column:name;type:varchar(32);uniqueIndex"`
+}
+
+// Facets counts matching campaigns per category and per tag.
+type Facets struct {
+	Categories []CategoryFacet
+	Tags       []TagFacet
+}
+
+type CategoryFacet struct {
+	Slug  string
+	Name  string
+	Count int64
+}
+
+type TagFacet struct {
+	Name  string
+	Count int64
+}
+
+// Progress is the share of the goal raised so far, in percent, rounded to
+// two decimals. It can exceed 100 once a campaign is overfunded.
+func (c Campaign) Progress() float64 {
+	if c.GoalAmount <= 0 {
+		return 0
+	}
+
+	return math.Round(float64(c.CurrentAmount)*10000/float64(c.GoalAmount)) / 100
+}
+
+func (c Campaign) CanTransitionTo(status string) bool {
+	for _, next := range statusTransitions[c.Status] {
+		if next == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c Campaign) IsPublic() bool {
+	for _, status := range PublicStatuses {
+		if c.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsViewable reports whether the campaign page can be shown to anyone.
+// Besides public campaigns that includes cancelled ones that had been
+// public, as a read-only record for their donors.
+func (c Campaign) IsViewable() bool {
+	if c.IsPublic() {
+		return true
+	}
+
+	if c.Status != StatusCancelled {
+		return false
+	}
+
+	for _, status := range PublicStatuses {
+		if c.CancelledFrom == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanBeArchived reports whether the campaign is over or never went live.
+func (c Campaign) CanBeArchived() bool {
+	return c.Status == StatusDraft || c.Status == StatusEnded || c.Status == StatusCancelled
+}
+
+func (c Campaign) IsArchived() bool {
+	return c.ArchivedAt != nil
+}
+
+// HasEnded reports whether the end date has passed. Campaigns created before
+// end dates were required have none and never end on their own.
+func (c Campaign) HasEnded(now time.Time) bool {
+	return c.EndDate != nil && !now.Before(*c.EndDate)
+}
+
+func (c Campaign) AcceptsDonations(now time.Time) bool {
+	return c.Status == StatusActive && !c.HasEnded(now)
+}
+
+// PrimaryImage returns the image marked as primary, or else the first one
+// in gallery order.
+func (c Campaign) PrimaryImage() (CampaignImage, bool) {
+	for _, image := range c.CampaignImages {
+		if image.IsPrimary == 1 {
+			return image, true
+		}
+	}
+
+	if len(c.CampaignImages) > 0 {
+		return c.CampaignImages[0], true
+	}
+
+	return CampaignImage{}, false
+}
+
+// IsApproved reports whether a moderator approved the campaign and it is
+// still running. The owner can no longer change what was reviewed.
+func (c Campaign) IsApproved() bool {
+	return c.Status == StatusActive || c.Status == StatusGoalReached
+}
+
+// IsEditable reports whether the owner may still change the details.
+func (c Campaign) IsEditable() bool {
+	return c.Status != StatusEnded && c.Status != StatusCancelled && !c.IsArchived()
+}
+
+// AddDonation counts a paid donation. Payments are counted even if the
+// campaign closed while they were pending; an active campaign that reaches
+// its goal stops taking new donations.
+func (c *Campaign) AddDonation(amount int) {
+	c.BackerCount = c.BackerCount + 1
+	c.CurrentAmount = c.CurrentAmount + amount
+
+	if c.Status == StatusActive && c.GoalAmount > 0 && c.CurrentAmount >= c.GoalAmount {
+		c.Status = StatusGoalReached
+	}
+}
+
+// CampaignSlug is a slug a campaign had before it was renamed. Old slugs
+// stay reserved so links using them keep redirecting to the campaign.
+type CampaignSlug struct {
 	ID         int       `gorm:"column:id;primaryKey"`
-	CampaignID int       `gorm:"column:campaign_id"`
-	FileName   string    `gorm:"column:file_name"`
-	IsPrimary  int       `gorm:"column:is_primary"`
+	CampaignID int       `gorm:"column:campaign_id;index"`
+	Slug       string    `gorm:"column:slug;type:varchar(255);uniqueIndex"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+// CampaignImage is a picture in a campaign's gallery. Images are shown in
+// Position order; PublicID identifies the file in the storage backend so it
+// can be removed once the image is deleted.
+type CampaignImage struct {
+	ID         int            `gorm:"column:id;primaryKey"`
+	CampaignID int            `gorm:"column:campaign_id"`
+	FileName   string         `gorm:"column:file_name"`
+	PublicID   string         `gorm:"column:public_id;type:varchar(255)"`
+	IsPrimary  int            `gorm:"column:is_primary"`
+	Position   int            `gorm:"column:position"`
+	CreatedAt  time.Time      `gorm:"column:created_at"`
+	UpdatedAt  time.Time      `gorm:"column:updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at;index"`
+}
+
+// Reward is a tier donors can pick when they give at least MinimumAmount.
+// A Quantity of 0 means the tier is unlimited. Claimed counts donations
+// holding the reward: it is reserved when the donation is created and given
+// back if the payment is cancelled or expires.
+type Reward struct {
+	ID                int        `gorm:"column:id;primaryKey"`
+	CampaignID        int        `gorm:"column:campaign_id;index"`
+	Title             string     `gorm:"column:title;type:varchar(100)"`
+	Description       string     `gorm:"column:description;type:TEXT"`
+	MinimumAmount     int        `gorm:"column:minimum_amount"`
+	Quantity          int        `gorm:"column:quantity"`
+	Claimed           int        `gorm:"column:claimed"`
+	EstimatedDelivery *time.Time `gorm:"column:estimated_delivery"`
+	CreatedAt         time.Time  `gorm:"column:created_at"`
+	UpdatedAt         time.Time  `gorm:"column:updated_at"`
+}
+
+func (r Reward) IsLimited() bool {
+	return r.Quantity > 0
+}
+
+// Remaining is how many are left of a limited reward.
+func (r Reward) Remaining() int {
+	if r.Claimed >= r.Quantity {
+		return 0
+	}
+
+	return r.Quantity - r.Claimed
+}
+
+func (r Reward) IsAvailable() bool {
+	return !r.IsLimited() || r.Remaining() > 0
+}
+
+// CampaignUpdate is a news post by the owner about how the campaign is
+// going. Donors-only updates are shown to the owner and to users with a
+// paid donation.
+type CampaignUpdate struct {
+	ID         int                   `gorm:"column:id;primaryKey"`
+	CampaignID int                   `gorm:"column:campaign_id;index"`
+	UserID     int                   `gorm:"column:user_id"`
+	Title      string                `gorm:"column:title;type:varchar(150)"`
+	Body       string                `gorm:"column:body;type:TEXT"`
+	Visibility string                `gorm:"column:visibility;type:varchar(16);default:public"`
+	CreatedAt  time.Time             `gorm:"column:created_at;index"`
+	UpdatedAt  time.Time             `gorm:"column:updated_at"`
+	Images     []CampaignUpdateImage `gorm:"foreignKey:CampaignUpdateID"`
+}
+
+type CampaignUpdateImage struct {
+	ID               int       `gorm:"column:id;primaryKey"`
+	CampaignUpdateID int       `gorm:"column:campaign_update_id;index"`
+	FileName         string    `gorm:"column:file_name"`
+	CreatedAt        time.Time `gorm:"column:created_at"`
+}
+
+// CampaignFollower is a user who asked to hear about a campaign's updates
+// without necessarily donating.
+type CampaignFollower struct {
+	CampaignID int       `gorm:"column:campaign_id;primaryKey;autoIncrement:false"`
+	UserID     int       `gorm:"column:user_id;primaryKey;autoIncrement:false;index"`
 	CreatedAt  time.Time `gorm:"column:created_at"`
-	UpdatedAt  time.Time `gorm:"column:updated_at"`
-	DeletedAt  *time.Time `gorm:"column:deleted_at"`
 }
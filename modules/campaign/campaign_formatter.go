@@ -1,14 +1,28 @@
 package campaign
 
+import (
+	"crowdfunding-minpro-alterra/modules/category"
+	"time"
+)
+
 type CampaignFormatter struct {
-	ID               int    `json:"id"`
-	UserID           int    `json:"user_id"`
-	Name             string `json:"name"`
-	ShortDescription string `json:"short_description"`
-	ImageURL         string `json:"image_url"`
-	GoalAmount       int    `json:"goal_amount"`
-	CurrentAmount    int    `json:"current_amount"`
-	Slug             string `json:"slug"`
+	ID                 int                         `json:"id"`
+	UserID             int                         `json:"user_id"`
+	Name               string                      `json:"name"`
+	ShortDescription   string                      `json:"short_description"`
+	ImageURL           string                      `json:"image_url"`
+	GoalAmount         int                         `json:"goal_amount"`
+	CurrentAmount      int                         `json:"current_amount"`
+	Slug               string                      `json:"slug"`
+	Progress           float64                     `json:"progress"`
+	EndDate            *time.Time                  `json:"end_date"`
+	Status             string                      `json:"status"`
+	RejectionReason    string                      `json:"rejection_reason,omitempty"`
+	CancellationReason string                      `json:"cancellation_reason,omitempty"`
+	CancelledAt        *time.Time                  `json:"cancelled_at,omitempty"`
+	ArchivedAt         *time.Time                  `json:"archived_at,omitempty"`
+	Category           *category.CategoryFormatter `json:"category"`
+	Tags               []string                    `json:"tags"`
 }
 
 func FormatCampaign(campaign Campaign) CampaignFormatter {
@@ -20,10 +34,19 @@ func FormatCampaign(campaign Campaign) CampaignFormatter {
 	campaignFormatter.GoalAmount = campaign.GoalAmount
 	campaignFormatter.CurrentAmount = campaign.CurrentAmount
 	campaignFormatter.Slug = campaign.Slug
+	campaignFormatter.Progress = campaign.Progress()
+	campaignFormatter.EndDate = campaign.EndDate
+	campaignFormatter.Status = campaign.Status
+	campaignFormatter.RejectionReason = campaign.RejectionReason
+	campaignFormatter.CancellationReason = campaign.CancellationReason
+	campaignFormatter.CancelledAt = campaign.CancelledAt
+	campaignFormatter.ArchivedAt = campaign.ArchivedAt
+	campaignFormatter.Category = formatCampaignCategory(campaign)
+	campaignFormatter.Tags = formatCampaignTags(campaign)
 	campaignFormatter.ImageURL = ""
 
-	if len(campaign.CampaignImages) > 0 {
-		campaignFormatter.ImageURL = campaign.CampaignImages[0].FileName
+	if image, ok := campaign.PrimaryImage(); ok {
+		campaignFormatter.ImageURL = image.FileName
 	}
 
 	return campaignFormatter
@@ -41,19 +64,27 @@ func FormatCampaigns(campaigns []Campaign) []CampaignFormatter {
 }
 
 type CampaignDetailFormatter struct {
-	ID               int    `json:"id"`
-	Name             string `json:"name"`
-	ShortDescription string `json:"short_description"`
-	Description      string `json:"description"`
-	ImageURL         string `json:"image_url"`
-	GoalAmount       int    `json:"goal_amount"`
-	CurrentAmount    int    `json:"current_amount"`
-	BackerCount      int    `json:"backer_count"`
-	UserID           int    `json:"user_id"`
-	Slug             string `json:"slug"`
-	// Perks            []string `json:"perks"`
-	User   CampaignUserFormatter    `json:"user"`
-	Images []CampaignImageFormatter `json:"images"`
+	ID                 int                         `json:"id"`
+	Name               string                      `json:"name"`
+	ShortDescription   string                      `json:"short_description"`
+	Description        string                      `json:"description"`
+	ImageURL           string                      `json:"image_url"`
+	GoalAmount         int                         `json:"goal_amount"`
+	CurrentAmount      int                         `json:"current_amount"`
+	BackerCount        int                         `json:"backer_count"`
+	UserID             int                         `json:"user_id"`
+	Slug               string                      `json:"slug"`
+	Progress           float64                     `json:"progress"`
+	EndDate            *time.Time                  `json:"end_date"`
+	Status             string                      `json:"status"`
+	CancellationReason string                      `json:"cancellation_reason,omitempty"`
+	CancelledAt        *time.Time                  `json:"cancelled_at,omitempty"`
+	Category           *category.CategoryFormatter `json:"category"`
+	Tags               []string                    `json:"tags"`
+	User               CampaignUserFormatter       `json:"user"`
+	Images             []CampaignImageFormatter    `json:"images"`
+	Rewards            []RewardFormatter           `json:"rewards"`
+	Updates            []CampaignUpdateFormatter   `json:"updates"`
 }
 
 type CampaignUserFormatter struct {
@@ -62,8 +93,10 @@ type CampaignUserFormatter struct {
 }
 
 type CampaignImageFormatter struct {
+	ID        int    `json:"id"`
 	ImageURL  string `json:"image_url"`
 	IsPrimary bool   `json:"is_primary"`
+	Position  int    `json:"position"`
 }
 
 func FormatCampaignDetail(campaign Campaign) CampaignDetailFormatter {
@@ -77,20 +110,19 @@ func FormatCampaignDetail(campaign Campaign) CampaignDetailFormatter {
 	campaignDetailFormatter.BackerCount = campaign.BackerCount
 	campaignDetailFormatter.UserID = campaign.UserID
 	campaignDetailFormatter.Slug = campaign.Slug
+	campaignDetailFormatter.Progress = campaign.Progress()
+	campaignDetailFormatter.EndDate = campaign.EndDate
+	campaignDetailFormatter.Status = campaign.Status
+	campaignDetailFormatter.CancellationReason = campaign.CancellationReason
+	campaignDetailFormatter.CancelledAt = campaign.CancelledAt
+	campaignDetailFormatter.Category = formatCampaignCategory(campaign)
+	campaignDetailFormatter.Tags = formatCampaignTags(campaign)
 	campaignDetailFormatter.ImageURL = ""
 
-	if len(campaign.CampaignImages) > 0 {
-		campaignDetailFormatter.ImageURL = campaign.CampaignImages[0].FileName
+	if image, ok := campaign.PrimaryImage(); ok {
+		campaignDetailFormatter.ImageURL = image.FileName
 	}
 
-	// var perks []string
-
-	// for _, perk := range strings.Split(campaign.Perks, ",") {
-	// 	perks = append(perks, strings.TrimSpace(perk))
-	// }
-
-	// campaignDetailFormatter.Perks = perks
-
 	user := campaign.User
 
 	campaignUserFormatter := CampaignUserFormatter{}
@@ -99,23 +131,162 @@ func FormatCampaignDetail(campaign Campaign) CampaignDetailFormatter {
 
 	campaignDetailFormatter.User = campaignUserFormatter
 
-	images := []CampaignImageFormatter{}
+	campaignDetailFormatter.Images = FormatCampaignImages(campaign.CampaignImages)
 
-	for _, image := range campaign.CampaignImages {
-		campaignImageFormatter := CampaignImageFormatter{}
-		campaignImageFormatter.ImageURL = image.FileName
+	rewards := []RewardFormatter{}
 
-		isPrimary := false
+	for _, reward := range campaign.Rewards {
+		rewards = append(rewards, FormatReward(reward))
+	}
+
+	campaignDetailFormatter.Rewards = rewards
+	campaignDetailFormatter.Updates = FormatCampaignUpdates(campaign.Updates)
 
-		if image.IsPrimary == 1 {
-			isPrimary = true
-		}
-		campaignImageFormatter.IsPrimary = isPrimary
+	return campaignDetailFormatter
+}
 
-		images = append(images, campaignImageFormatter)
+func FormatCampaignImage(image CampaignImage) CampaignImageFormatter {
+	campaignImageFormatter := CampaignImageFormatter{}
+	campaignImageFormatter.ID = image.ID
+	campaignImageFormatter.ImageURL = image.FileName
+	campaignImageFormatter.Position = image.Position
+
+	isPrimary := false
+
+	if image.IsPrimary == 1 {
+		isPrimary = true
 	}
+	campaignImageFormatter.IsPrimary = isPrimary
 
-	campaignDetailFormatter.Images = images
+	return campaignImageFormatter
+}
 
-	return campaignDetailFormatter
+func FormatCampaignImages(images []CampaignImage) []CampaignImageFormatter {
+	imagesFormatter := []CampaignImageFormatter{}
+
+	for _, image := range images {
+		imagesFormatter = append(imagesFormatter, FormatCampaignImage(image))
+	}
+
+	return imagesFormatter
+}
+
+type RewardFormatter struct {
+	ID                int        `json:"id"`
+	Title             string     `json:"title"`
+	Description       string     `json:"description"`
+	MinimumAmount     int        `json:"minimum_amount"`
+	Quantity          int        `json:"quantity"`
+	Remaining         *int       `json:"remaining"`
+	EstimatedDelivery *time.Time `json:"estimated_delivery"`
+}
+
+// FormatReward leaves remaining null for unlimited rewards.
+func FormatReward(reward Reward) RewardFormatter {
+	rewardFormatter := RewardFormatter{}
+	rewardFormatter.ID = reward.ID
+	rewardFormatter.Title = reward.Title
+	rewardFormatter.Description = reward.Description
+	rewardFormatter.MinimumAmount = reward.MinimumAmount
+	rewardFormatter.Quantity = reward.Quantity
+	rewardFormatter.EstimatedDelivery = reward.EstimatedDelivery
+
+	if reward.IsLimited() {
+		remaining := reward.Remaining()
+		rewardFormatter.Remaining = &remaining
+	}
+
+	return rewardFormatter
+}
+
+type CampaignUpdateFormatter struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	Visibility string    `json:"visibility"`
+	Images     []string  `json:"images"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func FormatCampaignUpdate(update CampaignUpdate) CampaignUpdateFormatter {
+	updateFormatter := CampaignUpdateFormatter{}
+	updateFormatter.ID = update.ID
+	updateFormatter.Title = update.Title
+	updateFormatter.Body = update.Body
+	updateFormatter.Visibility = update.Visibility
+	updateFormatter.CreatedAt = update.CreatedAt
+
+	images := []string{}
+
+	for _, image := range update.Images {
+		images = append(images, image.FileName)
+	}
+
+	updateFormatter.Images = images
+
+	return updateFormatter
+}
+
+func FormatCampaignUpdates(updates []CampaignUpdate) []CampaignUpdateFormatter {
+	updatesFormatter := []CampaignUpdateFormatter{}
+
+	for _, update := range updates {
+		updatesFormatter = append(updatesFormatter, FormatCampaignUpdate(update))
+	}
+
+	return updatesFormatter
+}
+
+// formatCampaignCategory returns nil for campaigns without a category.
+func formatCampaignCategory(campaign Campaign) *category.CategoryFormatter {
+	if campaign.CategoryID == nil || campaign.Category.ID == 0 {
+		return nil
+	}
+
+	categoryFormatter := category.FormatCategory(campaign.Category)
+
+	return &categoryFormatter
+}
+
+func formatCampaignTags(campaign Campaign) []string {
+	tags := []string{}
+
+	for _, tag := range campaign.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return tags
+}
+
+type CampaignFacetsFormatter struct {
+	Categories []CategoryFacetFormatter `json:"categories"`
+	Tags       []TagFacetFormatter      `json:"tags"`
+}
+
+type CategoryFacetFormatter struct {
+	Slug  string `json:"slug"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type TagFacetFormatter struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+func FormatCampaignFacets(facets Facets) CampaignFacetsFormatter {
+	facetsFormatter := CampaignFacetsFormatter{
+		Categories: []CategoryFacetFormatter{},
+		Tags:       []TagFacetFormatter{},
+	}
+
+	for _, facet := range facets.Categories {
+		facetsFormatter.Categories = append(facetsFormatter.Categories, CategoryFacetFormatter{facet.Slug, facet.Name, facet.Count})
+	}
+
+	for _, facet := range facets.Tags {
+		facetsFormatter.Tags = append(facetsFormatter.Tags, TagFacetFormatter{facet.Name, facet.Count})
+	}
+
+	return facetsFormatter
 }
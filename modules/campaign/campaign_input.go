@@ -1,22 +1,146 @@
 package campaign
 
-import "crowdfunding-minpro-alterra/modules/user"
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/user"
+	"time"
+)
 
 type GetCampaignDetailInput struct {
 	ID int `uri:"id" binding:"required"`
 }
 
+type GetCampaignSlugInput struct {
+	Slug string `uri:"slug" binding:"required"`
+}
+
 type CreateCampaignInput struct {
 	Name             string `json:"name" binding:"required"`
 	ShortDescription string `json:"short_description" binding:"required"`
 	Description      string `json:"description" binding:"required"`
 	GoalAmount       int    `json:"goal_amount" binding:"required"`
-	// Perks            string `json:"perks" binding:"required"`
+	EndDate          *time.Time `json:"end_date" binding:"required"`
+	CategoryID       int        `json:"category_id"`
+	Tags             []string   `json:"tags" binding:"max=10,dive,max=32"`
+	// Rewards are only read when the campaign is created; afterwards tiers
+	// are managed through CreateReward and UpdateReward.
+	Rewards          []CreateRewardInput `json:"rewards" binding:"max=20,dive"`
 	User             user.User
+	Actor            audit.Actor `json:"-" form:"-"`
+}
+
+type CreateRewardInput struct {
+	Title             string      `json:"title" binding:"required,max=100"`
+	Description       string      `json:"description"`
+	MinimumAmount     int         `json:"minimum_amount" binding:"required,min=1"`
+	Quantity          int         `json:"quantity" binding:"min=0"`
+	EstimatedDelivery *time.Time  `json:"estimated_delivery"`
+	User              user.User   `json:"-"`
+	Actor             audit.Actor `json:"-" form:"-"`
+}
+
+type GetRewardDetailInput struct {
+	CampaignID int `uri:"id" binding:"required"`
+	ID         int `uri:"reward_id" binding:"required"`
+}
+
+type CreateCampaignUpdateInput struct {
+	Title      string      `json:"title" binding:"required,max=150"`
+	Body       string      `json:"body" binding:"required"`
+	Visibility string      `json:"visibility" binding:"omitempty,oneof=public donors_only"`
+	User       user.User   `json:"-"`
+	Actor      audit.Actor `json:"-" form:"-"`
+}
+
+type CreateCampaignUpdateImageInput struct {
+	CampaignID int `uri:"id" binding:"required"`
+	UpdateID   int `uri:"update_id" binding:"required"`
+	User       user.User
+	Actor      audit.Actor `json:"-" form:"-"`
+}
+
+type GetCampaignUpdatesInput struct {
+	ID   int `uri:"id" binding:"required"`
+	User user.User
+}
+
+type FollowCampaignInput struct {
+	ID   int `uri:"id" binding:"required"`
+	User user.User
 }
 
 type CreateCampaignImageInput struct {
 	CampaignID int `form:"campaign_id" binding:"required"`
 	IsPrimary bool `form:"is_primary"`
+	PublicID string `form:"-"`
 	User user.User
-}
\ No newline at end of file
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type GetCampaignImageDetailInput struct {
+	CampaignID int `uri:"id" binding:"required"`
+	ID         int `uri:"image_id" binding:"required"`
+}
+
+type MoveCampaignImageInput struct {
+	Position int         `json:"position" binding:"required,min=1"`
+	User     user.User   `json:"-"`
+	Actor    audit.Actor `json:"-" form:"-"`
+}
+
+type SubmitCampaignInput struct {
+	ID    int `uri:"id" binding:"required"`
+	User  user.User
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type CloseCampaignInput struct {
+	ID    int `uri:"id" binding:"required"`
+	User  user.User
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type CancelCampaignInput struct {
+	ID     int
+	Reason string      `json:"reason" binding:"required,max=500"`
+	User   user.User   `json:"-"`
+	Actor  audit.Actor `json:"-" form:"-"`
+}
+
+type ArchiveCampaignInput struct {
+	ID    int `uri:"id" binding:"required"`
+	User  user.User
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type UpdateCampaignStatusInput struct {
+	ID     int
+	Status string `json:"status" binding:"required"`
+	// Reason is required when the status is cancelled.
+	Reason string `json:"reason" binding:"max=500"`
+	Actor  audit.Actor `json:"-" form:"-"`
+}
+
+type ReviewCampaignInput struct {
+	ID     int
+	Reason string `json:"reason" binding:"required,max=500"`
+	Actor  audit.Actor `json:"-" form:"-"`
+}
+
+type GetCampaignsInput struct {
+	Page        int     `form:"page" binding:"omitempty,min=1"`
+	Limit       int     `form:"limit" binding:"omitempty,min=1,max=100"`
+	UserID      int     `form:"user_id"`
+	Query       string  `form:"q"`
+	Sort        string  `form:"sort" binding:"omitempty,oneof=newest most_funded closest_to_goal ending_soon"`
+	MinProgress float64 `form:"min_progress" binding:"omitempty,min=0"`
+	MaxProgress float64 `form:"max_progress" binding:"omitempty,min=0"`
+	Status      string  `form:"status"`
+	Category    string  `form:"category"`
+	Tag         string  `form:"tag"`
+	// Statuses limits the results to campaigns in these states; it is set
+	// by the server, never from the query string.
+	Statuses []string `form:"-"`
+	// IncludeArchived also returns campaigns their owners archived.
+	IncludeArchived bool `form:"-"`
+}
@@ -1,17 +1,69 @@
 package campaign
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type Repository interface {
 	FindAll() ([]Campaign, error)
+	Search(input GetCampaignsInput) ([]Campaign, int64, error)
 	FindByUserID(userID int) ([]Campaign, error)
 	FindByID(ID int) (Campaign, error)
+	FindBySlug(slug string) (Campaign, error)
+	FindSlugHistory(slug string) (CampaignSlug, error)
+	IsSlugTaken(slug string, campaignID int) (bool, error)
+	ChangeSlug(campaign Campaign, slug string) (Campaign, error)
 	Save(campaign Campaign) (Campaign, error)
 	Update(campaign Campaign) (Campaign, error)
 	CreateImage(campaignImage CampaignImage) (CampaignImage, error)
-	MarkAllImagesAsNonPrimary(campaignID int) (bool, error)
+	DeleteImage(campaignImage CampaignImage) error
+	SetPrimaryImage(campaignID int, imageID int) error
+	UpdateImagePositions(images []CampaignImage) error
+	FindExpired(now time.Time) ([]Campaign, error)
+	FindOrCreateTags(names []string) ([]Tag, error)
+	ReplaceTags(campaign Campaign, tags []Tag) error
+	Facets(input GetCampaignsInput) (Facets, error)
+	FindRewardByID(ID int) (Reward, error)
+	SaveReward(reward Reward) (Reward, error)
+	UpdateReward(reward Reward) (Reward, error)
+	ReserveReward(ID int) (bool, error)
+	ReleaseReward(ID int) error
+	FindUpdates(campaignID int, includeDonorsOnly bool) ([]CampaignUpdate, error)
+	FindUpdateByID(ID int) (CampaignUpdate, error)
+	SaveUpdate(update CampaignUpdate) (CampaignUpdate, error)
+	CreateUpdateImage(image CampaignUpdateImage) (CampaignUpdateImage, error)
+	Follow(follower CampaignFollower) error
+	Unfollow(campaignID int, userID int) error
+	FindFollowerIDs(campaignID int) ([]int, error)
+	FindDonorIDs(campaignID int) ([]int, error)
+	IsDonor(campaignID int, userID int) (bool, error)
+	FindPendingDonorIDs(campaignID int) ([]int, error)
+	UpdateStatus(campaign Campaign, from string) (Campaign, error)
+	Cancel(campaign Campaign, from string) (Campaign, int64, error)
+	AddDonation(campaignID int, amount int) error
+	RemoveDonation(campaignID int, amount int) (bool, error)
 }
 
+// ErrSlugTaken is returned when another campaign saved the same slug first.
+var ErrSlugTaken = errors.New("Slug is already taken")
+
+// ErrStatusChanged is returned when the campaign's status was changed by
+// someone else after it was read.
+var ErrStatusChanged = errors.New("Campaign status was changed by another request")
+
+// ErrImageLimit is returned when a campaign already has the most images
+// it may have.
+var ErrImageLimit = fmt.Errorf("A campaign can have at most %d images", maxCampaignImages)
+
+// statusColumns are the columns a status change writes. Only these are
+// saved, so a transition never puts back stale copies of other fields.
+var statusColumns = []string{"status", "submitted_at", "reviewed_at", "reviewed_by", "rejection_reason", "cancelled_from", "cancelled_at", "cancellation_reason", "updated_at"}
+
 type repository struct {
 	db *gorm.DB
 }
@@ -23,7 +75,7 @@ func NewRepository(db *gorm.DB) *repository {
 func (r *repository) FindAll() ([]Campaign, error) {
 	var campaigns []Campaign
 
-	err := r.db.Preload("CampaignImages", "campaign_images.is_primary = 1").Find(&campaigns).Error
+	err := r.db.Where("archived_at IS NULL").Preload("CampaignImages", GalleryOrder).Find(&campaigns).Error
 
 	if err != nil {
 		return campaigns, err
@@ -32,10 +84,134 @@ func (r *repository) FindAll() ([]Campaign, error) {
 	return campaigns, err
 }
 
+// progressColumn is the share of the goal raised so far, in percent.
+const progressColumn = "(current_amount * 100.0 / NULLIF(goal_amount, 0))"
+
+func (r *repository) Search(input GetCampaignsInput) ([]Campaign, int64, error) {
+	var campaigns []Campaign
+	var total int64
+
+	query := r.filter(input)
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return campaigns, total, err
+	}
+
+	err = query.Order(searchOrder(input.Sort)).Offset((input.Page-1)*input.Limit).Limit(input.Limit).Preload("CampaignImages", GalleryOrder).Preload("Category").Preload("Tags").Find(&campaigns).Error
+	if err != nil {
+		return campaigns, total, err
+	}
+
+	return campaigns, total, nil
+}
+
+// Facets counts the campaigns matching input per category and per tag. The
+// category counts ignore the category filter and the tag counts ignore the
+// tag filter, so a client can show the other choices next to the selected
+// one.
+func (r *repository) Facets(input GetCampaignsInput) (Facets, error) {
+	var facets Facets
+
+	withoutCategory := input
+	withoutCategory.Category = ""
+
+	err := r.db.Table("categories").
+		Select("categories.slug, categories.name, COUNT(*) AS count").
+		Joins("JOIN campaigns ON campaigns.category_id = categories.id").
+		Where("campaigns.id IN (?)", r.filter(withoutCategory).Select("id")).
+		Group("categories.id, categories.slug, categories.name").
+		Order("count desc, categories.name").
+		Scan(&facets.Categories).Error
+	if err != nil {
+		return facets, err
+	}
+
+	withoutTag := input
+	withoutTag.Tag = ""
+
+	err = r.db.Table("tags").
+		Select("tags.name, COUNT(*) AS count").
+		Joins("JOIN campaign_tags ON campaign_tags.tag_id = tags.id").
+		Where("campaign_tags.campaign_id IN (?)", r.filter(withoutTag).Select("id")).
+		Group("tags.id, tags.name").
+		Order("count desc, tags.name").
+		Scan(&facets.Tags).Error
+	if err != nil {
+		return facets, err
+	}
+
+	return facets, nil
+}
+
+func (r *repository) filter(input GetCampaignsInput) *gorm.DB {
+	query := r.db.Model(&Campaign{})
+
+	if !input.IncludeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+
+	if input.UserID != 0 {
+		query = query.Where("user_id = ?", input.UserID)
+	}
+
+	if input.Status != "" {
+		query = query.Where("status = ?", input.Status)
+	}
+
+	if len(input.Statuses) > 0 {
+		query = query.Where("status IN ?", input.Statuses)
+	}
+
+	if input.Query != "" {
+		like := "%" + input.Query + "%"
+		query = query.Where("name LIKE ? OR short_description LIKE ? OR description LIKE ?", like, like, like)
+	}
+
+	if input.MinProgress > 0 {
+		query = query.Where(progressColumn+" >= ?", input.MinProgress)
+	}
+
+	if input.MaxProgress > 0 {
+		query = query.Where(progressColumn+" <= ?", input.MaxProgress)
+	}
+
+	if input.Category != "" {
+		query = query.Where("category_id IN (SELECT id FROM categories WHERE slug = ?)", input.Category)
+	}
+
+	if input.Tag != "" {
+		query = query.Where("id IN (SELECT campaign_tags.campaign_id FROM campaign_tags JOIN tags ON tags.id = campaign_tags.tag_id WHERE tags.name = ?)", input.Tag)
+	}
+
+	// Campaigns that already ended are not "ending soon".
+	if input.Sort == SortEndingSoon {
+		query = query.Where("end_date IS NULL OR end_date > ?", time.Now())
+	}
+
+	return query
+}
+
+func searchOrder(sort string) string {
+	switch sort {
+	case SortMostFunded:
+		return "current_amount desc, id desc"
+	case SortClosestToGoal:
+		// Campaigns still short of their goal come first, nearest one first.
+		return "CASE WHEN current_amount >= goal_amount THEN 1 ELSE 0 END, goal_amount - current_amount, id desc"
+	case SortEndingSoon:
+		return "end_date IS NULL, end_date, id desc"
+	case SortSubmitted:
+		return "submitted_at, id"
+	default:
+		return "created_at desc, id desc"
+	}
+}
+
 func (r *repository) FindByUserID(userID int) ([]Campaign, error) {
 	var campaigns []Campaign
 
-	err := r.db.Where("user_id = ?", userID).Preload("CampaignImages", "campaign_images.is_primary = 1").Find(&campaigns).Error
+	err := r.db.Where("user_id = ? AND archived_at IS NULL", userID).Preload("CampaignImages", GalleryOrder).Preload("Category").Preload("Tags").Find(&campaigns).Error
 
 	if err != nil {
 		return campaigns, err
@@ -47,7 +223,7 @@ func (r *repository) FindByUserID(userID int) ([]Campaign, error) {
 func (r *repository) FindByID(ID int) (Campaign, error) {
 	var campaign Campaign
 
-	err := r.db.Preload("User").Preload("CampaignImages").Where("id = ?", ID).Find(&campaign).Error
+	err := r.detail().Where("id = ?", ID).Find(&campaign).Error
 
 	if err != nil {
 		return campaign, err
@@ -56,8 +232,90 @@ func (r *repository) FindByID(ID int) (Campaign, error) {
 	return campaign, err
 }
 
+func (r *repository) FindBySlug(slug string) (Campaign, error) {
+	var campaign Campaign
+
+	err := r.detail().Where("slug = ?", slug).Find(&campaign).Error
+	if err != nil {
+		return campaign, err
+	}
+
+	return campaign, nil
+}
+
+// detail preloads everything shown on the campaign detail page.
+func (r *repository) detail() *gorm.DB {
+	return r.db.Preload("User").Preload("CampaignImages", GalleryOrder).Preload("Category").Preload("Tags").Preload("Rewards", func(db *gorm.DB) *gorm.DB {
+		return db.Order("minimum_amount, id")
+	}).Preload("Updates", func(db *gorm.DB) *gorm.DB {
+		return db.Where("visibility = ?", VisibilityPublic).Order("created_at desc, id desc")
+	}).Preload("Updates.Images")
+}
+
+func (r *repository) FindSlugHistory(slug string) (CampaignSlug, error) {
+	var campaignSlug CampaignSlug
+
+	err := r.db.Where("slug = ?", slug).Find(&campaignSlug).Error
+	if err != nil {
+		return campaignSlug, err
+	}
+
+	return campaignSlug, nil
+}
+
+// IsSlugTaken reports whether another campaign uses slug, now or before a
+// rename. The campaign's own old slugs are free for it to take back.
+func (r *repository) IsSlugTaken(slug string, campaignID int) (bool, error) {
+	var count int64
+
+	err := r.db.Model(&Campaign{}).Where("slug = ? AND id <> ?", slug, campaignID).Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err = r.db.Model(&CampaignSlug{}).Where("slug = ? AND campaign_id <> ?", slug, campaignID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// ChangeSlug gives the campaign a new slug and keeps the current one in the
+// history so it redirects to the new one.
+func (r *repository) ChangeSlug(campaign Campaign, slug string) (Campaign, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&CampaignSlug{CampaignID: campaign.ID, Slug: campaign.Slug}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("campaign_id = ? AND slug = ?", campaign.ID, slug).Delete(&CampaignSlug{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Campaign{}).Where("id = ?", campaign.ID).Update("slug", slug).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return campaign, ErrSlugTaken
+	}
+
+	if err != nil {
+		return campaign, err
+	}
+
+	campaign.Slug = slug
+
+	return campaign, nil
+}
+
 func (r *repository) Save(campaign Campaign) (Campaign, error) {
 	err := r.db.Create(&campaign).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return campaign, ErrSlugTaken
+	}
+
 	if err != nil {
 		return campaign, err
 	}
@@ -65,8 +323,11 @@ func (r *repository) Save(campaign Campaign) (Campaign, error) {
 	return campaign, nil
 }
 
+// Update saves the owner's edits. The status and review fields and the
+// donation counters are left out; they only change through UpdateStatus and
+// AddDonation, so a stale copy cannot overwrite them.
 func (r *repository) Update(campaign Campaign) (Campaign, error) {
-	err := r.db.Save(&campaign).Error
+	err := r.db.Omit("status", "submitted_at", "reviewed_at", "reviewed_by", "rejection_reason", "cancelled_from", "cancelled_at", "cancellation_reason", "current_amount", "backer_count").Save(&campaign).Error
 
 	if err != nil {
 		return campaign, err
@@ -75,8 +336,43 @@ func (r *repository) Update(campaign Campaign) (Campaign, error) {
 	return campaign, nil
 }
 
+// GalleryOrder sorts preloaded campaign images the way the gallery shows
+// them, which is also the order PrimaryImage falls back on.
+func GalleryOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// CreateImage adds an image unless the campaign already has
+// maxCampaignImages. A primary image replaces the current one.
 func (r *repository) CreateImage(campaignImage CampaignImage) (CampaignImage, error) {
-	err := r.db.Create(&campaignImage).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Locking the campaign makes uploads to it wait for each other, so
+		// two of them cannot both slip under the limit.
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", campaignImage.CampaignID).Find(&Campaign{}).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+
+		err = tx.Model(&CampaignImage{}).Where("campaign_id = ?", campaignImage.CampaignID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count >= maxCampaignImages {
+			return ErrImageLimit
+		}
+
+		if campaignImage.IsPrimary == 1 {
+			err = tx.Model(&CampaignImage{}).Where("campaign_id = ?", campaignImage.CampaignID).Update("is_primary", 0).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&campaignImage).Error
+	})
 	if err != nil {
 		return campaignImage, err
 	}
@@ -84,13 +380,396 @@ func (r *repository) CreateImage(campaignImage CampaignImage) (CampaignImage, er
 	return campaignImage, nil
 }
 
-func (r *repository) MarkAllImagesAsNonPrimary(campaignID int) (bool, error) {
-	err := r.db.Model(&CampaignImage{}).Where("campaign_id = ?", campaignID).Update("is_primary", false).Error
+// DeleteImage removes the image. If it was the primary one, the next image
+// in gallery order becomes primary in the same transaction.
+func (r *repository) DeleteImage(campaignImage CampaignImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&campaignImage).Error
+		if err != nil {
+			return err
+		}
+
+		if campaignImage.IsPrimary != 1 {
+			return nil
+		}
+
+		var next CampaignImage
+
+		err = GalleryOrder(tx.Where("campaign_id = ?", campaignImage.CampaignID)).Limit(1).Find(&next).Error
+		if err != nil || next.ID == 0 {
+			return err
+		}
+
+		return tx.Model(&CampaignImage{}).Where("id = ?", next.ID).Update("is_primary", 1).Error
+	})
+}
+
+// SetPrimaryImage makes imageID the only primary image of the campaign.
+func (r *repository) SetPrimaryImage(campaignID int, imageID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&CampaignImage{}).Where("campaign_id = ? AND id <> ?", campaignID, imageID).Update("is_primary", 0).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&CampaignImage{}).Where("campaign_id = ? AND id = ?", campaignID, imageID).Update("is_primary", 1).Error
+	})
+}
+
+// UpdateImagePositions saves the position of every image in one go, so a
+// failed reorder leaves the old order intact.
+func (r *repository) UpdateImagePositions(images []CampaignImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, image := range images {
+			err := tx.Model(&CampaignImage{}).Where("id = ?", image.ID).Update("position", image.Position).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
 
+// FindExpired returns running campaigns whose end date has passed.
+func (r *repository) FindExpired(now time.Time) ([]Campaign, error) {
+	var campaigns []Campaign
+
+	err := r.db.Where("status IN ? AND end_date <= ?", []string{StatusActive, StatusGoalReached}, now).Find(&campaigns).Error
+	if err != nil {
+		return campaigns, err
+	}
+
+	return campaigns, nil
+}
+
+// FindOrCreateTags returns the tags with the given names, creating the ones
+// that do not exist yet. Tags created concurrently by another request are
+// skipped on insert and picked up by the select that follows.
+func (r *repository) FindOrCreateTags(names []string) ([]Tag, error) {
+	tags := []Tag{}
+
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	newTags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		newTags = append(newTags, Tag{Name: name})
+	}
+
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error
+	if err != nil {
+		return tags, err
+	}
+
+	var found []Tag
+
+	err = r.db.Where("name IN ?", names).Find(&found).Error
+	if err != nil {
+		return tags, err
+	}
+
+	byName := map[string]Tag{}
+	for _, tag := range found {
+		byName[tag.Name] = tag
+	}
+
+	for _, name := range names {
+		tag, ok := byName[name]
+		if !ok {
+			return tags, errors.New("Failed to create tag " + name)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+func (r *repository) ReplaceTags(campaign Campaign, tags []Tag) error {
+	return r.db.Model(&campaign).Association("Tags").Replace(tags)
+}
+
+func (r *repository) FindRewardByID(ID int) (Reward, error) {
+	var reward Reward
+
+	err := r.db.Where("id = ?", ID).Find(&reward).Error
+	if err != nil {
+		return reward, err
+	}
+
+	return reward, nil
+}
+
+func (r *repository) SaveReward(reward Reward) (Reward, error) {
+	err := r.db.Create(&reward).Error
+	if err != nil {
+		return reward, err
+	}
+
+	return reward, nil
+}
+
+// UpdateReward saves the details of a reward but leaves claimed alone, so
+// it cannot undo reservations made since the reward was loaded.
+func (r *repository) UpdateReward(reward Reward) (Reward, error) {
+	err := r.db.Model(&reward).Select("title", "description", "minimum_amount", "quantity", "estimated_delivery", "updated_at").Updates(&reward).Error
+	if err != nil {
+		return reward, err
+	}
+
+	return reward, nil
+}
+
+// ReserveReward claims one reward if any are left. The check and the
+// increment are a single statement so concurrent donations cannot oversell
+// a limited reward.
+func (r *repository) ReserveReward(ID int) (bool, error) {
+	result := r.db.Model(&Reward{}).Where("id = ? AND (quantity = 0 OR claimed < quantity)", ID).Update("claimed", gorm.Expr("claimed + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) ReleaseReward(ID int) error {
+	return r.db.Model(&Reward{}).Where("id = ? AND claimed > 0", ID).Update("claimed", gorm.Expr("claimed - 1")).Error
+}
+
+func (r *repository) FindUpdates(campaignID int, includeDonorsOnly bool) ([]CampaignUpdate, error) {
+	var updates []CampaignUpdate
+
+	query := r.db.Where("campaign_id = ?", campaignID)
+
+	if !includeDonorsOnly {
+		query = query.Where("visibility = ?", VisibilityPublic)
+	}
+
+	err := query.Preload("Images").Order("created_at desc, id desc").Find(&updates).Error
+	if err != nil {
+		return updates, err
+	}
+
+	return updates, nil
+}
+
+func (r *repository) FindUpdateByID(ID int) (CampaignUpdate, error) {
+	var update CampaignUpdate
+
+	err := r.db.Preload("Images").Where("id = ?", ID).Find(&update).Error
+	if err != nil {
+		return update, err
+	}
+
+	return update, nil
+}
+
+func (r *repository) SaveUpdate(update CampaignUpdate) (CampaignUpdate, error) {
+	err := r.db.Create(&update).Error
+	if err != nil {
+		return update, err
+	}
+
+	return update, nil
+}
+
+func (r *repository) CreateUpdateImage(image CampaignUpdateImage) (CampaignUpdateImage, error) {
+	err := r.db.Create(&image).Error
+	if err != nil {
+		return image, err
+	}
+
+	return image, nil
+}
+
+// Follow is idempotent: following a campaign twice keeps one row.
+func (r *repository) Follow(follower CampaignFollower) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follower).Error
+}
+
+func (r *repository) Unfollow(campaignID int, userID int) error {
+	return r.db.Where("campaign_id = ? AND user_id = ?", campaignID, userID).Delete(&CampaignFollower{}).Error
+}
+
+func (r *repository) FindFollowerIDs(campaignID int) ([]int, error) {
+	var userIDs []int
+
+	err := r.db.Model(&CampaignFollower{}).Where("campaign_id = ?", campaignID).Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return userIDs, err
+	}
+
+	return userIDs, nil
+}
+
+// FindDonorIDs returns the users with a paid donation to the campaign. Like
+// IsDonor it reads the donations table directly since the donation package
+// imports this one.
+func (r *repository) FindDonorIDs(campaignID int) ([]int, error) {
+	var userIDs []int
+
+	err := r.db.Table("donations").Where("campaign_id = ? AND status = ?", campaignID, "paid").Distinct().Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return userIDs, err
+	}
+
+	return userIDs, nil
+}
+
+func (r *repository) IsDonor(campaignID int, userID int) (bool, error) {
+	var count int64
+
+	err := r.db.Table("donations").Where("campaign_id = ? AND user_id = ? AND status = ?", campaignID, userID, "paid").Count(&count).Error
 	if err != nil {
 		return false, err
 	}
 
-	return true, nil
+	return count > 0, nil
+}
+
+func (r *repository) FindPendingDonorIDs(campaignID int) ([]int, error) {
+	var userIDs []int
+
+	err := r.db.Table("donations").Where("campaign_id = ? AND status = ?", campaignID, "pending").Distinct().Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return userIDs, err
+	}
+
+	return userIDs, nil
+}
+
+// UpdateStatus writes the campaign's status and review fields if its status
+// in the database is still from, and returns ErrStatusChanged otherwise.
+func (r *repository) UpdateStatus(campaign Campaign, from string) (Campaign, error) {
+	err := updateStatus(r.db, campaign, from)
+	if err != nil {
+		return campaign, err
+	}
+
+	return campaign, nil
 }
 
+func updateStatus(tx *gorm.DB, campaign Campaign, from string) error {
+	result := tx.Model(&Campaign{}).Where("id = ? AND status = ?", campaign.ID, from).Select(statusColumns).Updates(&campaign)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrStatusChanged
+	}
+
+	return nil
+}
+
+// Cancel changes the status like UpdateStatus and, in the same transaction,
+// cancels the campaign's unpaid donations and gives back the rewards they
+// were holding. It also returns how many donations it cancelled.
+func (r *repository) Cancel(campaign Campaign, from string) (Campaign, int64, error) {
+	var cancelled int64
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := updateStatus(tx, campaign, from)
+		if err != nil {
+			return err
+		}
+
+		cancelled, err = cancelPendingDonations(tx, campaign.ID)
+
+		return err
+	})
+	if err != nil {
+		return campaign, 0, err
+	}
+
+	return campaign, cancelled, nil
+}
+
+// AddDonation counts a paid donation the way Campaign.AddDonation does, but
+// in the database, so payments settling at the same time all add up.
+func (r *repository) AddDonation(campaignID int, amount int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Campaign{}).Where("id = ?", campaignID).Updates(map[string]interface{}{
+			"backer_count":   gorm.Expr("backer_count + 1"),
+			"current_amount": gorm.Expr("current_amount + ?", amount),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Campaign{}).
+			Where("id = ? AND status = ? AND goal_amount > 0 AND current_amount >= goal_amount", campaignID, StatusActive).
+			Update("status", StatusGoalReached).Error
+	})
+}
+
+// RemoveDonation takes back a donation AddDonation counted, for a payment
+// that was reversed. A campaign that drops below its goal takes donations
+// again, the goal_reached to active transition of statusTransitions; the
+// result reports whether that happened.
+func (r *repository) RemoveDonation(campaignID int, amount int) (bool, error) {
+	reopened := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Campaign{}).Where("id = ?", campaignID).Updates(map[string]interface{}{
+			"backer_count":   gorm.Expr("GREATEST(backer_count - 1, 0)"),
+			"current_amount": gorm.Expr("GREATEST(current_amount - ?, 0)", amount),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&Campaign{}).
+			Where("id = ? AND status = ? AND current_amount < goal_amount", campaignID, StatusGoalReached).
+			Update("status", StatusActive)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		reopened = result.RowsAffected == 1
+		return nil
+	})
+
+	return reopened, err
+}
+
+// cancelPendingDonations locks the donations first so a payment settling
+// meanwhile either lands before them or finds them cancelled.
+func cancelPendingDonations(tx *gorm.DB, campaignID int) (int64, error) {
+	var pending []struct {
+		ID       int
+		RewardID *int
+	}
+
+	err := tx.Table("donations").Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, reward_id").Where("campaign_id = ? AND status = ?", campaignID, "pending").Scan(&pending).Error
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	donationIDs := []int{}
+	claimed := map[int]int{}
+
+	for _, donation := range pending {
+		donationIDs = append(donationIDs, donation.ID)
+
+		if donation.RewardID != nil {
+			claimed[*donation.RewardID]++
+		}
+	}
+
+	for rewardID, count := range claimed {
+		err = tx.Model(&Reward{}).Where("id = ?", rewardID).Update("claimed", gorm.Expr("GREATEST(claimed - ?, 0)", count)).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	result := tx.Table("donations").Where("id IN ?", donationIDs).Updates(map[string]interface{}{
+		"status":     "cancelled",
+		"updated_at": time.Now(),
+	})
+
+	return result.RowsAffected, result.Error
+}
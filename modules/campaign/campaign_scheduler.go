@@ -0,0 +1,49 @@
+package campaign
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type scheduler struct {
+	service  Service
+	interval time.Duration
+}
+
+// NewScheduler returns a scheduler that ends expired campaigns every
+// interval. Several instances may run it at once: each campaign is ended
+// with a conditional status update, so only one of them ends it and records
+// the change.
+func NewScheduler(service Service, interval time.Duration) *scheduler {
+	return &scheduler{service, interval}
+}
+
+// Run blocks until ctx is cancelled.
+func (s *scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) tick(now time.Time) {
+	// Errors do not stop the others from being ended; they are retried on
+	// the next tick.
+	endedCampaigns, err := s.service.EndExpiredCampaigns(now)
+	if err != nil {
+		log.Println("Failed to end expired campaigns:", err)
+	}
+
+	if len(endedCampaigns) > 0 {
+		log.Printf("Ended %d expired campaigns", len(endedCampaigns))
+	}
+}
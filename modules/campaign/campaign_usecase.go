@@ -1,27 +1,82 @@
 package campaign
 
 import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/category"
+	"crowdfunding-minpro-alterra/modules/notification"
+	"crowdfunding-minpro-alterra/modules/user"
+	"crowdfunding-minpro-alterra/utils/mailer"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gosimple/slug"
 )
 
+const (
+	SortNewest        = "newest"
+	SortMostFunded    = "most_funded"
+	SortClosestToGoal = "closest_to_goal"
+	SortEndingSoon    = "ending_soon"
+	// SortSubmitted orders the review queue, oldest submission first.
+	SortSubmitted     = "submitted"
+
+	defaultCampaignsPageLimit = 20
+
+	maxUpdateImages = 5
+
+	maxCampaignImages = 10
+
+	// maxSlugLength leaves room for a numeric suffix in the slug column.
+	maxSlugLength = 240
+	// maxSlugAttempts bounds how often a slug lost to a concurrent save is
+	// picked again.
+	maxSlugAttempts = 5
+)
+
 type Service interface {
 	GetCampaigns(UserID int) ([]Campaign, error)
+	SearchCampaigns(input GetCampaignsInput) ([]Campaign, int64, GetCampaignsInput, error)
 	GetCampaignByID(input GetCampaignDetailInput) (Campaign, error)
+	GetCampaignBySlug(input GetCampaignSlugInput) (Campaign, error)
 	CreateCampaign(input CreateCampaignInput) (Campaign, error)
 	UpdateCampaign(inputID GetCampaignDetailInput, inputData CreateCampaignInput) (Campaign, error)
 
 	SaveCampaignImage(input CreateCampaignImageInput, fileLocation string) (CampaignImage, error)
+	DeleteCampaignImage(input GetCampaignImageDetailInput, currentUser user.User, actor audit.Actor) (CampaignImage, error)
+	SetPrimaryCampaignImage(input GetCampaignImageDetailInput, currentUser user.User, actor audit.Actor) (CampaignImage, error)
+	MoveCampaignImage(inputID GetCampaignImageDetailInput, inputData MoveCampaignImageInput) ([]CampaignImage, error)
+
+	SubmitCampaign(input SubmitCampaignInput) (Campaign, error)
+	UpdateCampaignStatus(input UpdateCampaignStatusInput) (Campaign, error)
+	GetReviewQueue(input GetCampaignsInput) ([]Campaign, int64, GetCampaignsInput, error)
+	ApproveCampaign(input ReviewCampaignInput) (Campaign, error)
+	RejectCampaign(input ReviewCampaignInput) (Campaign, error)
+	CloseCampaign(input CloseCampaignInput) (Campaign, error)
+	CancelCampaign(input CancelCampaignInput) (Campaign, error)
+	ArchiveCampaign(input ArchiveCampaignInput) (Campaign, error)
+	EndExpiredCampaigns(now time.Time) ([]Campaign, error)
+	GetCampaignFacets(input GetCampaignsInput) (Facets, error)
+	CreateReward(inputID GetCampaignDetailInput, inputData CreateRewardInput) (Reward, error)
+	UpdateReward(inputID GetRewardDetailInput, inputData CreateRewardInput) (Reward, error)
+	CreateCampaignUpdate(inputID GetCampaignDetailInput, inputData CreateCampaignUpdateInput) (CampaignUpdate, error)
+	SaveCampaignUpdateImage(input CreateCampaignUpdateImageInput, fileLocation string) (CampaignUpdateImage, error)
+	GetCampaignUpdates(input GetCampaignUpdatesInput) ([]CampaignUpdate, error)
+	FollowCampaign(input FollowCampaignInput) error
+	UnfollowCampaign(input FollowCampaignInput) error
 }
 
 type service struct {
-	repository Repository
+	repository          Repository
+	categoryRepository  category.Repository
+	auditService        audit.Service
+	mailer              mailer.Mailer
+	notificationService notification.Service
 }
 
-func NewService(repository Repository) *service {
-	return &service{repository}
+func NewService(repository Repository, categoryRepository category.Repository, auditService audit.Service, mailer mailer.Mailer, notificationService notification.Service) *service {
+	return &service{repository, categoryRepository, auditService, mailer, notificationService}
 }
 
 func (s *service) GetCampaigns(userID int) ([]Campaign, error) {
@@ -44,6 +99,50 @@ func (s *service) GetCampaigns(userID int) ([]Campaign, error) {
 	return campaigns, nil
 }
 
+// SearchCampaigns returns one page of campaigns and the total number of
+// matches, along with the input after defaults were applied.
+func (s *service) SearchCampaigns(input GetCampaignsInput) ([]Campaign, int64, GetCampaignsInput, error) {
+	if input.Page == 0 {
+		input.Page = 1
+	}
+
+	if input.Limit == 0 {
+		input.Limit = defaultCampaignsPageLimit
+	}
+
+	if input.Sort == "" {
+		input.Sort = SortNewest
+	}
+
+	input.Query = strings.TrimSpace(input.Query)
+	input.Tag = normalizeTag(input.Tag)
+
+	if input.MaxProgress > 0 && input.MinProgress > input.MaxProgress {
+		return []Campaign{}, 0, input, errors.New("Minimum progress cannot be greater than maximum progress")
+	}
+
+	campaigns, total, err := s.repository.Search(input)
+	if err != nil {
+		return campaigns, total, input, err
+	}
+
+	return campaigns, total, input, nil
+}
+
+// GetCampaignFacets counts the campaigns matching input per category and
+// per tag.
+func (s *service) GetCampaignFacets(input GetCampaignsInput) (Facets, error) {
+	input.Query = strings.TrimSpace(input.Query)
+	input.Tag = normalizeTag(input.Tag)
+
+	facets, err := s.repository.Facets(input)
+	if err != nil {
+		return facets, err
+	}
+
+	return facets, nil
+}
+
 func (s *service) GetCampaignByID(input GetCampaignDetailInput) (Campaign, error) {
 	campaign, err := s.repository.FindByID(input.ID)
 
@@ -54,26 +153,94 @@ func (s *service) GetCampaignByID(input GetCampaignDetailInput) (Campaign, error
 	return campaign, nil
 }
 
+// GetCampaignBySlug finds a campaign by its current slug or by one it had
+// before it was renamed. Callers can tell the two apart by comparing the
+// slug of the result with the one they asked for.
+func (s *service) GetCampaignBySlug(input GetCampaignSlugInput) (Campaign, error) {
+	campaign, err := s.repository.FindBySlug(input.Slug)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID != 0 {
+		return campaign, nil
+	}
+
+	campaignSlug, err := s.repository.FindSlugHistory(input.Slug)
+	if err != nil {
+		return Campaign{}, err
+	}
+
+	if campaignSlug.ID == 0 {
+		return Campaign{}, errors.New("No campaign found with that slug")
+	}
+
+	campaign, err = s.repository.FindByID(campaignSlug.CampaignID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, errors.New("No campaign found with that slug")
+	}
+
+	return campaign, nil
+}
+
 func (s *service) CreateCampaign(input CreateCampaignInput) (Campaign, error) {
+	err := validateEndDate(input.EndDate, time.Now())
+	if err != nil {
+		return Campaign{}, err
+	}
+
 	campaign := Campaign{}
 
 	campaign.Name = input.Name
 	campaign.ShortDescription = input.ShortDescription
 	campaign.Description = input.Description
-	// campaign.Perks = input.Perks
 	campaign.GoalAmount = input.GoalAmount
+	campaign.EndDate = input.EndDate
 	campaign.UserID = input.User.ID
-	campaign.Slug = slug.Make(input.Name)
 
-	slugCandidate := fmt.Sprintf("%s %d", input.Name, input.User.ID)
-	campaign.Slug = slug.Make(slugCandidate)
+	campaign.CategoryID, err = s.findCategoryID(input.CategoryID)
+	if err != nil {
+		return campaign, err
+	}
+
+	campaign.Tags, err = s.repository.FindOrCreateTags(normalizeTags(input.Tags))
+	if err != nil {
+		return campaign, err
+	}
+
+	for _, rewardInput := range input.Rewards {
+		reward, err := applyRewardInput(Reward{}, rewardInput, time.Now())
+		if err != nil {
+			return campaign, err
+		}
+
+		campaign.Rewards = append(campaign.Rewards, reward)
+	}
+
+	// New campaigns go straight to the review queue; they only become
+	// public once a moderator approved them.
+	submittedAt := time.Now()
+	campaign.Status = StatusPendingReview
+	campaign.SubmittedAt = &submittedAt
+
+	var newCampaign Campaign
 
-	newCampaign, err := s.repository.Save(campaign)
+	err = s.saveWithUniqueSlug(input.Name, 0, func(slug string) error {
+		campaign.Slug = slug
+		newCampaign, err = s.repository.Save(campaign)
+		return err
+	})
 
 	if err != nil {
 		return newCampaign, err
 	}
 
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, input.Actor, audit.ActionCampaignCreate, newCampaign.ID, map[string]interface{}{"name": newCampaign.Name, "goal_amount": newCampaign.GoalAmount})
+
 	return newCampaign, err
 }
 
@@ -88,11 +255,51 @@ func (s *service) UpdateCampaign(inputID GetCampaignDetailInput, inputData Creat
 		return campaign, errors.New("Not an owner of the campaign.")
 	}
 
+	if !campaign.IsEditable() {
+		return campaign, errors.New("Campaign can no longer be edited")
+	}
+
+	if !sameTime(campaign.EndDate, inputData.EndDate) {
+		err = validateEndDate(inputData.EndDate, time.Now())
+		if err != nil {
+			return campaign, err
+		}
+	}
+
+	categoryID, err := s.findCategoryID(inputData.CategoryID)
+	if err != nil {
+		return campaign, err
+	}
+
+	// What a moderator approved stays as approved. Only the end date and
+	// the tags of a running campaign can still change.
+	if campaign.IsApproved() && (inputData.Name != campaign.Name ||
+		inputData.ShortDescription != campaign.ShortDescription ||
+		inputData.Description != campaign.Description ||
+		inputData.GoalAmount != campaign.GoalAmount ||
+		!sameID(categoryID, campaign.CategoryID)) {
+		return campaign, errors.New("The name, descriptions, goal and category of an approved campaign cannot be changed")
+	}
+
+	renamed := inputData.Name != campaign.Name
+
 	campaign.Name = inputData.Name
 	campaign.ShortDescription = inputData.ShortDescription
 	campaign.Description = inputData.Description
-	// campaign.Perks = inputData.Perks
 	campaign.GoalAmount = inputData.GoalAmount
+	campaign.EndDate = inputData.EndDate
+	campaign.CategoryID = categoryID
+
+	// Drop the preloaded category, otherwise saving the campaign would put
+	// its ID back into category_id.
+	campaign.Category = category.Category{}
+
+	tags, err := s.repository.FindOrCreateTags(normalizeTags(inputData.Tags))
+	if err != nil {
+		return campaign, err
+	}
+
+	campaign.Tags = tags
 
 	updateCampaign, err := s.repository.Update(campaign)
 
@@ -100,6 +307,29 @@ func (s *service) UpdateCampaign(inputID GetCampaignDetailInput, inputData Creat
 		return updateCampaign, err
 	}
 
+	err = s.repository.ReplaceTags(updateCampaign, tags)
+	if err != nil {
+		return updateCampaign, err
+	}
+
+	// Renaming moves the campaign to a slug for its new name; the old one
+	// keeps redirecting to it.
+	if renamed {
+		err = s.saveWithUniqueSlug(updateCampaign.Name, updateCampaign.ID, func(slug string) error {
+			if slug == updateCampaign.Slug {
+				return nil
+			}
+
+			updateCampaign, err = s.repository.ChangeSlug(updateCampaign, slug)
+			return err
+		})
+		if err != nil {
+			return updateCampaign, err
+		}
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, inputData.Actor, audit.ActionCampaignUpdate, updateCampaign.ID, map[string]interface{}{"name": updateCampaign.Name, "goal_amount": updateCampaign.GoalAmount, "slug": updateCampaign.Slug})
+
 	return updateCampaign, nil
 }
 
@@ -114,22 +344,38 @@ func (s *service) SaveCampaignImage(input CreateCampaignImageInput, fileLocation
 		return CampaignImage{}, errors.New("Not an owner of the campaign.")
 	}
 
+	// New images would go public without review. Existing ones can still be
+	// removed and reordered.
+	if campaign.IsApproved() {
+		return CampaignImage{}, errors.New("Images cannot be added to an approved campaign")
+	}
+
+	if len(campaign.CampaignImages) >= maxCampaignImages {
+		return CampaignImage{}, ErrImageLimit
+	}
+
 	isPrimary := 0
-	
-	if input.IsPrimary {
+
+	// The first image of a campaign is its primary one until the owner
+	// picks another.
+	if input.IsPrimary || len(campaign.CampaignImages) == 0 {
 		isPrimary = 1
+	}
 
-		_, err := s.repository.MarkAllImagesAsNonPrimary(input.CampaignID)
+	position := 1
 
-		if err != nil {
-			return CampaignImage{}, err
+	for _, image := range campaign.CampaignImages {
+		if image.Position >= position {
+			position = image.Position + 1
 		}
 	}
 
 	campaignImage := CampaignImage{}
 	campaignImage.CampaignID = input.CampaignID
 	campaignImage.IsPrimary = isPrimary
+	campaignImage.Position = position
 	campaignImage.FileName = fileLocation
+	campaignImage.PublicID = input.PublicID
 
 	newCampaignImage, err := s.repository.CreateImage(campaignImage)
 
@@ -137,5 +383,846 @@ func (s *service) SaveCampaignImage(input CreateCampaignImageInput, fileLocation
 		return newCampaignImage, err
 	}
 
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, input.Actor, audit.ActionCampaignImage, input.CampaignID, map[string]interface{}{"image_id": newCampaignImage.ID, "is_primary": input.IsPrimary})
+
 	return newCampaignImage, nil
-}
\ No newline at end of file
+}
+
+// DeleteCampaignImage removes an image from the gallery and returns it so
+// the caller can remove the file from storage. When the primary image goes,
+// the next one in order takes its place.
+func (s *service) DeleteCampaignImage(input GetCampaignImageDetailInput, currentUser user.User, actor audit.Actor) (CampaignImage, error) {
+	campaign, image, err := s.findOwnCampaignImage(input, currentUser)
+	if err != nil {
+		return image, err
+	}
+
+	err = s.repository.DeleteImage(image)
+	if err != nil {
+		return image, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, actor, audit.ActionImageDelete, campaign.ID, map[string]interface{}{"image_id": image.ID})
+
+	return image, nil
+}
+
+func (s *service) SetPrimaryCampaignImage(input GetCampaignImageDetailInput, currentUser user.User, actor audit.Actor) (CampaignImage, error) {
+	campaign, image, err := s.findOwnCampaignImage(input, currentUser)
+	if err != nil {
+		return image, err
+	}
+
+	err = s.repository.SetPrimaryImage(campaign.ID, image.ID)
+	if err != nil {
+		return image, err
+	}
+
+	image.IsPrimary = 1
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, actor, audit.ActionImagePrimary, campaign.ID, map[string]interface{}{"image_id": image.ID})
+
+	return image, nil
+}
+
+// MoveCampaignImage puts an image at the given 1-based position and shifts
+// the others to make room. It returns the gallery in its new order.
+func (s *service) MoveCampaignImage(inputID GetCampaignImageDetailInput, inputData MoveCampaignImageInput) ([]CampaignImage, error) {
+	campaign, image, err := s.findOwnCampaignImage(inputID, inputData.User)
+	if err != nil {
+		return []CampaignImage{}, err
+	}
+
+	if inputData.Position > len(campaign.CampaignImages) {
+		return campaign.CampaignImages, fmt.Errorf("Position must be between 1 and %d", len(campaign.CampaignImages))
+	}
+
+	images := []CampaignImage{}
+
+	for _, other := range campaign.CampaignImages {
+		if other.ID != image.ID {
+			images = append(images, other)
+		}
+	}
+
+	index := inputData.Position - 1
+	images = append(images[:index], append([]CampaignImage{image}, images[index:]...)...)
+
+	for i := range images {
+		images[i].Position = i + 1
+	}
+
+	err = s.repository.UpdateImagePositions(images)
+	if err != nil {
+		return campaign.CampaignImages, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, inputData.Actor, audit.ActionImageMove, campaign.ID, map[string]interface{}{"image_id": image.ID, "position": inputData.Position})
+
+	return images, nil
+}
+
+// findOwnCampaignImage loads one of the owner's campaigns together with one
+// of its images.
+func (s *service) findOwnCampaignImage(input GetCampaignImageDetailInput, owner user.User) (Campaign, CampaignImage, error) {
+	campaign, err := s.findOwnCampaign(input.CampaignID, owner)
+	if err != nil {
+		return campaign, CampaignImage{}, err
+	}
+
+	for _, image := range campaign.CampaignImages {
+		if image.ID == input.ID {
+			return campaign, image, nil
+		}
+	}
+
+	return campaign, CampaignImage{}, errors.New("No image found with that ID")
+}
+
+// CreateReward adds a reward tier to one of the owner's campaigns.
+func (s *service) CreateReward(inputID GetCampaignDetailInput, inputData CreateRewardInput) (Reward, error) {
+	campaign, err := s.findOwnCampaign(inputID.ID, inputData.User)
+	if err != nil {
+		return Reward{}, err
+	}
+
+	if !campaign.IsEditable() {
+		return Reward{}, errors.New("Campaign can no longer be edited")
+	}
+
+	reward, err := applyRewardInput(Reward{CampaignID: campaign.ID}, inputData, time.Now())
+	if err != nil {
+		return reward, err
+	}
+
+	newReward, err := s.repository.SaveReward(reward)
+	if err != nil {
+		return newReward, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, inputData.Actor, audit.ActionRewardCreate, campaign.ID, map[string]interface{}{"reward_id": newReward.ID, "title": newReward.Title})
+
+	return newReward, nil
+}
+
+// UpdateReward changes a reward tier. A limited reward cannot be cut below
+// the number already claimed; a new minimum only applies to new donations.
+func (s *service) UpdateReward(inputID GetRewardDetailInput, inputData CreateRewardInput) (Reward, error) {
+	campaign, err := s.findOwnCampaign(inputID.CampaignID, inputData.User)
+	if err != nil {
+		return Reward{}, err
+	}
+
+	if !campaign.IsEditable() {
+		return Reward{}, errors.New("Campaign can no longer be edited")
+	}
+
+	reward, err := s.repository.FindRewardByID(inputID.ID)
+	if err != nil {
+		return reward, err
+	}
+
+	if reward.ID == 0 || reward.CampaignID != campaign.ID {
+		return reward, errors.New("No reward found with that ID")
+	}
+
+	if inputData.Quantity > 0 && inputData.Quantity < reward.Claimed {
+		return reward, errors.New("Quantity cannot be lower than the number of rewards already claimed")
+	}
+
+	reward, err = applyRewardInput(reward, inputData, time.Now())
+	if err != nil {
+		return reward, err
+	}
+
+	updatedReward, err := s.repository.UpdateReward(reward)
+	if err != nil {
+		return updatedReward, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, inputData.Actor, audit.ActionRewardUpdate, campaign.ID, map[string]interface{}{"reward_id": updatedReward.ID, "title": updatedReward.Title})
+
+	return updatedReward, nil
+}
+
+// SlugFrom turns a campaign name into the plain slug for it, short enough to
+// take a numeric suffix.
+func SlugFrom(name string) string {
+	base := slug.Make(name)
+
+	if len(base) > maxSlugLength {
+		base = strings.TrimRight(base[:maxSlugLength], "-")
+	}
+
+	if base == "" {
+		base = "campaign"
+	}
+
+	return base
+}
+
+// saveWithUniqueSlug picks a free slug for name and hands it to save. When
+// another campaign with the same name claimed that slug in the meantime,
+// the unique index rejects it and the next free one is tried.
+func (s *service) saveWithUniqueSlug(name string, campaignID int, save func(slug string) error) error {
+	for attempt := 1; ; attempt++ {
+		candidate, err := s.uniqueSlug(name, campaignID)
+		if err != nil {
+			return err
+		}
+
+		err = save(candidate)
+		if !errors.Is(err, ErrSlugTaken) || attempt == maxSlugAttempts {
+			return err
+		}
+	}
+}
+
+// uniqueSlug makes a slug from name that no other campaign uses or used,
+// adding "-2", "-3" and so on when the plain one is taken.
+func (s *service) uniqueSlug(name string, campaignID int) (string, error) {
+	base := SlugFrom(name)
+	candidate := base
+
+	for suffix := 2; ; suffix++ {
+		taken, err := s.repository.IsSlugTaken(candidate, campaignID)
+		if err != nil {
+			return "", err
+		}
+
+		if !taken {
+			return candidate, nil
+		}
+
+		candidate = fmt.Sprintf("%s-%d", base, suffix)
+	}
+}
+
+func (s *service) findOwnCampaign(ID int, owner user.User) (Campaign, error) {
+	campaign, err := s.repository.FindByID(ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, errors.New("No campaign found with that ID")
+	}
+
+	if campaign.UserID != owner.ID {
+		return campaign, errors.New("Not an owner of the campaign.")
+	}
+
+	return campaign, nil
+}
+
+// CreateCampaignUpdate posts news about one of the owner's campaigns and
+// notifies its donors, and for public updates its followers too. Owners can
+// keep posting after the campaign ended, which is when donors most want to
+// hear how the money was used.
+func (s *service) CreateCampaignUpdate(inputID GetCampaignDetailInput, inputData CreateCampaignUpdateInput) (CampaignUpdate, error) {
+	campaign, err := s.findOwnCampaign(inputID.ID, inputData.User)
+	if err != nil {
+		return CampaignUpdate{}, err
+	}
+
+	update := CampaignUpdate{}
+	update.CampaignID = campaign.ID
+	update.UserID = inputData.User.ID
+	update.Title = strings.TrimSpace(inputData.Title)
+	update.Body = inputData.Body
+	update.Visibility = inputData.Visibility
+
+	if update.Visibility == "" {
+		update.Visibility = VisibilityPublic
+	}
+
+	newUpdate, err := s.repository.SaveUpdate(update)
+	if err != nil {
+		return newUpdate, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, inputData.Actor, audit.ActionCampaignPost, campaign.ID, map[string]interface{}{"update_id": newUpdate.ID, "visibility": newUpdate.Visibility})
+
+	// As with e-mail notices, a failed fan-out does not undo the post.
+	_ = s.notifySupporters(campaign, newUpdate)
+
+	return newUpdate, nil
+}
+
+func (s *service) notifySupporters(campaign Campaign, update CampaignUpdate) error {
+	recipients, err := s.repository.FindDonorIDs(campaign.ID)
+	if err != nil {
+		return err
+	}
+
+	if update.Visibility == VisibilityPublic {
+		followerIDs, err := s.repository.FindFollowerIDs(campaign.ID)
+		if err != nil {
+			return err
+		}
+
+		recipients = append(recipients, followerIDs...)
+	}
+
+	userIDs := []int{}
+	for _, userID := range recipients {
+		if userID != campaign.UserID {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return s.notificationService.Notify(userIDs, notification.Notification{
+		Type:       notification.TypeCampaignUpdate,
+		Title:      fmt.Sprintf("%s: %s", campaign.Name, update.Title),
+		Body:       update.Body,
+		CampaignID: campaign.ID,
+	})
+}
+
+func (s *service) SaveCampaignUpdateImage(input CreateCampaignUpdateImageInput, fileLocation string) (CampaignUpdateImage, error) {
+	campaign, err := s.findOwnCampaign(input.CampaignID, input.User)
+	if err != nil {
+		return CampaignUpdateImage{}, err
+	}
+
+	update, err := s.repository.FindUpdateByID(input.UpdateID)
+	if err != nil {
+		return CampaignUpdateImage{}, err
+	}
+
+	if update.ID == 0 || update.CampaignID != campaign.ID {
+		return CampaignUpdateImage{}, errors.New("No update found with that ID")
+	}
+
+	if len(update.Images) >= maxUpdateImages {
+		return CampaignUpdateImage{}, fmt.Errorf("An update can have at most %d images", maxUpdateImages)
+	}
+
+	image := CampaignUpdateImage{}
+	image.CampaignUpdateID = update.ID
+	image.FileName = fileLocation
+
+	newImage, err := s.repository.CreateUpdateImage(image)
+	if err != nil {
+		return newImage, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, input.Actor, audit.ActionCampaignImage, campaign.ID, map[string]interface{}{"update_id": update.ID, "image_id": newImage.ID})
+
+	return newImage, nil
+}
+
+// GetCampaignUpdates lists the updates the user may read, newest first. An
+// anonymous user, with an ID of 0, only gets the public ones.
+func (s *service) GetCampaignUpdates(input GetCampaignUpdatesInput) ([]CampaignUpdate, error) {
+	campaign, err := s.findVisibleCampaign(input.ID, input.User)
+	if err != nil {
+		return []CampaignUpdate{}, err
+	}
+
+	includeDonorsOnly := input.User.ID != 0 && campaign.UserID == input.User.ID
+
+	if !includeDonorsOnly && input.User.ID != 0 {
+		includeDonorsOnly, err = s.repository.IsDonor(campaign.ID, input.User.ID)
+		if err != nil {
+			return []CampaignUpdate{}, err
+		}
+	}
+
+	updates, err := s.repository.FindUpdates(campaign.ID, includeDonorsOnly)
+	if err != nil {
+		return updates, err
+	}
+
+	return updates, nil
+}
+
+func (s *service) FollowCampaign(input FollowCampaignInput) error {
+	campaign, err := s.findVisibleCampaign(input.ID, input.User)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Follow(CampaignFollower{CampaignID: campaign.ID, UserID: input.User.ID})
+}
+
+func (s *service) UnfollowCampaign(input FollowCampaignInput) error {
+	return s.repository.Unfollow(input.ID, input.User.ID)
+}
+
+// findVisibleCampaign finds a campaign the user may look at: any viewable
+// campaign, or one of their own.
+func (s *service) findVisibleCampaign(ID int, viewer user.User) (Campaign, error) {
+	campaign, err := s.repository.FindByID(ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 || (!campaign.IsViewable() && campaign.UserID != viewer.ID) {
+		return Campaign{}, errors.New("No campaign found with that ID")
+	}
+
+	return campaign, nil
+}
+
+// applyRewardInput copies the tier details from input onto reward.
+func applyRewardInput(reward Reward, input CreateRewardInput, now time.Time) (Reward, error) {
+	if input.MinimumAmount <= 0 {
+		return reward, errors.New("Reward minimum amount must be greater than zero")
+	}
+
+	if input.EstimatedDelivery != nil && !sameTime(reward.EstimatedDelivery, input.EstimatedDelivery) && !input.EstimatedDelivery.After(now) {
+		return reward, errors.New("Estimated delivery must be in the future")
+	}
+
+	reward.Title = strings.TrimSpace(input.Title)
+	reward.Description = input.Description
+	reward.MinimumAmount = input.MinimumAmount
+	reward.Quantity = input.Quantity
+	reward.EstimatedDelivery = input.EstimatedDelivery
+
+	return reward, nil
+}
+
+// SubmitCampaign sends the owner's draft to review.
+func (s *service) SubmitCampaign(input SubmitCampaignInput) (Campaign, error) {
+	campaign, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, errors.New("No campaign found with that ID")
+	}
+
+	if campaign.UserID != input.User.ID {
+		return campaign, errors.New("Not an owner of the campaign.")
+	}
+
+	submittedAt := time.Now()
+	campaign.SubmittedAt = &submittedAt
+
+	return s.transition(campaign, StatusPendingReview, input.Actor, audit.ActionCampaignSubmit, nil)
+}
+
+// GetReviewQueue returns campaigns waiting for review, oldest submission
+// first.
+func (s *service) GetReviewQueue(input GetCampaignsInput) ([]Campaign, int64, GetCampaignsInput, error) {
+	input.Status = StatusPendingReview
+	input.Statuses = nil
+	input.Sort = SortSubmitted
+
+	return s.SearchCampaigns(input)
+}
+
+func (s *service) ApproveCampaign(input ReviewCampaignInput) (Campaign, error) {
+	campaign, err := s.findForReview(input.ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	err = markReviewed(&campaign, input.Actor, "")
+	if err != nil {
+		return campaign, err
+	}
+
+	approvedCampaign, err := s.transition(campaign, StatusActive, input.Actor, audit.ActionCampaignApprove, nil)
+	if err != nil {
+		return approvedCampaign, err
+	}
+
+	// As with other notifications, a failed delivery does not undo the
+	// review; the owner also sees the status in their campaign list.
+	_ = s.sendReviewNotice(approvedCampaign, "Your campaign has been approved",
+		fmt.Sprintf("Hi %s,\n\nYour campaign \"%s\" has been approved and is now public.\n", approvedCampaign.User.Name, approvedCampaign.Name))
+
+	return approvedCampaign, nil
+}
+
+// RejectCampaign sends a campaign back to draft with the reason, so the
+// owner can fix it and submit it again.
+func (s *service) RejectCampaign(input ReviewCampaignInput) (Campaign, error) {
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return Campaign{}, errors.New("A reason is required to reject a campaign")
+	}
+
+	campaign, err := s.findForReview(input.ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	err = markReviewed(&campaign, input.Actor, input.Reason)
+	if err != nil {
+		return campaign, err
+	}
+
+	rejectedCampaign, err := s.transition(campaign, StatusDraft, input.Actor, audit.ActionCampaignReject, map[string]interface{}{"reason": input.Reason})
+	if err != nil {
+		return rejectedCampaign, err
+	}
+
+	_ = s.sendReviewNotice(rejectedCampaign, "Your campaign needs changes",
+		fmt.Sprintf("Hi %s,\n\nYour campaign \"%s\" was not approved for the following reason:\n\n%s\n\nYou can update the campaign and submit it for review again.\n", rejectedCampaign.User.Name, rejectedCampaign.Name, input.Reason))
+
+	return rejectedCampaign, nil
+}
+
+func (s *service) findForReview(ID int) (Campaign, error) {
+	campaign, err := s.repository.FindByID(ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, errors.New("No campaign found with that ID")
+	}
+
+	if campaign.Status != StatusPendingReview {
+		return campaign, errors.New("Campaign is not waiting for review")
+	}
+
+	return campaign, nil
+}
+
+// markReviewed records who reviewed the campaign and why it was rejected,
+// if it was. Moderators cannot review their own campaigns.
+func markReviewed(campaign *Campaign, actor audit.Actor, reason string) error {
+	if campaign.UserID == actor.ID {
+		return errors.New("You cannot review your own campaign")
+	}
+
+	reviewedAt := time.Now()
+	campaign.ReviewedAt = &reviewedAt
+	campaign.ReviewedBy = actor.ID
+	campaign.RejectionReason = reason
+
+	return nil
+}
+
+func (s *service) sendReviewNotice(campaign Campaign, subject string, body string) error {
+	if campaign.User.Email == "" {
+		return nil
+	}
+
+	message := mailer.Message{
+		To:      campaign.User.Email,
+		Subject: subject,
+		Body:    body,
+	}
+
+	return s.mailer.Send(message)
+}
+
+func (s *service) UpdateCampaignStatus(input UpdateCampaignStatusInput) (Campaign, error) {
+	if !IsValidStatus(input.Status) {
+		return Campaign{}, errors.New("Invalid campaign status")
+	}
+
+	campaign, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, errors.New("No campaign found with that ID")
+	}
+
+	// Cancelling takes the same path as CancelCampaign, so a moderator has
+	// to give a reason and supporters are told.
+	if input.Status == StatusCancelled {
+		return s.cancel(campaign, input.Reason, input.Actor)
+	}
+
+	// Moving a campaign out of review is a review like ApproveCampaign and
+	// RejectCampaign, whichever endpoint it comes through.
+	if campaign.Status == StatusPendingReview && (input.Status == StatusActive || input.Status == StatusDraft) {
+		err = markReviewed(&campaign, input.Actor, "")
+		if err != nil {
+			return campaign, err
+		}
+	}
+
+	return s.transition(campaign, input.Status, input.Actor, audit.ActionCampaignStatus, nil)
+}
+
+// CloseCampaign lets the owner stop taking donations before the end date.
+// Donations already pending still count if their payment goes through.
+func (s *service) CloseCampaign(input CloseCampaignInput) (Campaign, error) {
+	campaign, err := s.findOwnCampaign(input.ID, input.User)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.Status != StatusActive && campaign.Status != StatusGoalReached {
+		return campaign, errors.New("Only running campaigns can be closed")
+	}
+
+	return s.transition(campaign, StatusEnded, input.Actor, audit.ActionCampaignClose, nil)
+}
+
+// CancelCampaign withdraws a campaign for good. Its pending donations are
+// cancelled; a payment that still goes through on one of them is flagged
+// for refund rather than counted. Paid donations are not refunded
+// automatically. The reason is shown on the campaign page, which stays up
+// read-only if the campaign had been public, and donors and followers are
+// notified.
+func (s *service) CancelCampaign(input CancelCampaignInput) (Campaign, error) {
+	campaign, err := s.findOwnCampaign(input.ID, input.User)
+	if err != nil {
+		return campaign, err
+	}
+
+	return s.cancel(campaign, input.Reason, input.Actor)
+}
+
+// cancel is shared by CancelCampaign and UpdateCampaignStatus.
+func (s *service) cancel(campaign Campaign, reason string, actor audit.Actor) (Campaign, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return campaign, errors.New("A reason is required to cancel a campaign")
+	}
+
+	// Look the supporters up first; pending donors can no longer be told
+	// apart once their donations are cancelled.
+	recipients, err := s.findSupporterIDs(campaign)
+	if err != nil {
+		return campaign, err
+	}
+
+	campaign.CancellationReason = reason
+
+	cancelledCampaign, err := s.transition(campaign, StatusCancelled, actor, audit.ActionCampaignCancel, map[string]interface{}{"reason": reason})
+	if err != nil {
+		return cancelledCampaign, err
+	}
+
+	_ = s.notificationService.Notify(recipients, notification.Notification{
+		Type:       notification.TypeCampaignCancelled,
+		Title:      fmt.Sprintf("%s has been cancelled", cancelledCampaign.Name),
+		Body:       reason,
+		CampaignID: cancelledCampaign.ID,
+	})
+
+	return cancelledCampaign, nil
+}
+
+// ArchiveCampaign hides a finished or never launched campaign from every
+// listing, the owner's included. Archived campaigns can no longer change.
+func (s *service) ArchiveCampaign(input ArchiveCampaignInput) (Campaign, error) {
+	campaign, err := s.findOwnCampaign(input.ID, input.User)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.IsArchived() {
+		return campaign, errors.New("Campaign is already archived")
+	}
+
+	if !campaign.CanBeArchived() {
+		return campaign, errors.New("Only draft, ended or cancelled campaigns can be archived")
+	}
+
+	archivedAt := time.Now()
+	campaign.ArchivedAt = &archivedAt
+
+	archivedCampaign, err := s.repository.Update(campaign)
+	if err != nil {
+		return archivedCampaign, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, input.Actor, audit.ActionCampaignArchive, archivedCampaign.ID, map[string]interface{}{"status": archivedCampaign.Status})
+
+	return archivedCampaign, nil
+}
+
+// findSupporterIDs returns everyone with a paid or pending donation to the
+// campaign and its followers, leaving out the owner.
+func (s *service) findSupporterIDs(campaign Campaign) ([]int, error) {
+	donorIDs, err := s.repository.FindDonorIDs(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingDonorIDs, err := s.repository.FindPendingDonorIDs(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	followerIDs, err := s.repository.FindFollowerIDs(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []int{}
+	for _, userID := range append(append(donorIDs, pendingDonorIDs...), followerIDs...) {
+		if userID != campaign.UserID {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return userIDs, nil
+}
+
+// EndExpiredCampaigns ends every running campaign whose end date has passed
+// and returns the ones it ended, along with the errors of those it could
+// not end. It is called periodically by the scheduler.
+func (s *service) EndExpiredCampaigns(now time.Time) ([]Campaign, error) {
+	campaigns, err := s.repository.FindExpired(now)
+	if err != nil {
+		return nil, err
+	}
+
+	endedCampaigns := []Campaign{}
+	var errs []error
+
+	// One campaign failing does not hold up the rest. A campaign whose status
+	// changed meanwhile, for instance because another instance ended it
+	// first, is skipped.
+	for _, campaign := range campaigns {
+		endedCampaign, err := s.transition(campaign, StatusEnded, audit.Actor{}, audit.ActionCampaignStatus, nil)
+		if errors.Is(err, ErrStatusChanged) {
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("campaign %d: %w", campaign.ID, err))
+			continue
+		}
+
+		endedCampaigns = append(endedCampaigns, endedCampaign)
+	}
+
+	return endedCampaigns, errors.Join(errs...)
+}
+
+// transition moves the campaign to status and saves it along with the
+// review and cancellation fields the caller set, recording action with the
+// move in the audit log. Other changes to campaign are not saved.
+func (s *service) transition(campaign Campaign, status string, actor audit.Actor, action string, metadata map[string]interface{}) (Campaign, error) {
+	if campaign.IsArchived() {
+		return campaign, errors.New("Campaign is archived")
+	}
+
+	if !campaign.CanTransitionTo(status) {
+		return campaign, fmt.Errorf("Campaign cannot move from %s to %s", campaign.Status, status)
+	}
+
+	// Review and activation happen ahead of the end date; a campaign whose
+	// end date already passed must be given a new one first.
+	if status == StatusPendingReview || status == StatusActive {
+		err := validateEndDate(campaign.EndDate, time.Now())
+		if err != nil {
+			return campaign, err
+		}
+	}
+
+	previousStatus := campaign.Status
+	campaign.Status = status
+
+	if status == StatusCancelled {
+		cancelledAt := time.Now()
+		campaign.CancelledFrom = previousStatus
+		campaign.CancelledAt = &cancelledAt
+	}
+
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["from"] = previousStatus
+	metadata["to"] = status
+
+	var updatedCampaign Campaign
+	var err error
+
+	// Only the status columns are written, and only if nobody changed the
+	// status since it was read. Whoever cancels, the campaign's pending
+	// donations are cancelled along with it, so a failure leaves both as they
+	// were and can be retried.
+	if status == StatusCancelled {
+		var cancelledDonations int64
+
+		updatedCampaign, cancelledDonations, err = s.repository.Cancel(campaign, previousStatus)
+		metadata["cancelled_donations"] = cancelledDonations
+	} else {
+		updatedCampaign, err = s.repository.UpdateStatus(campaign, previousStatus)
+	}
+
+	if err != nil {
+		return updatedCampaign, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCampaign, actor, action, updatedCampaign.ID, metadata)
+
+	return updatedCampaign, nil
+}
+
+func validateEndDate(endDate *time.Time, now time.Time) error {
+	if endDate == nil {
+		return errors.New("End date is required")
+	}
+
+	if !endDate.After(now) {
+		return errors.New("End date must be in the future")
+	}
+
+	return nil
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+// findCategoryID checks that the category exists. A campaign may have no
+// category, which is what an ID of 0 means.
+func (s *service) findCategoryID(ID int) (*int, error) {
+	if ID == 0 {
+		return nil, nil
+	}
+
+	category, err := s.categoryRepository.FindByID(ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if category.ID == 0 {
+		return nil, errors.New("No category found with that ID")
+	}
+
+	return &category.ID, nil
+}
+
+func sameID(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// normalizeTags lowercases and trims tag names, dropping blanks and
+// duplicates while keeping their order.
+func normalizeTags(names []string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		tag := normalizeTag(name)
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+func normalizeTag(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
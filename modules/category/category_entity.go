@@ -0,0 +1,13 @@
+package category
+
+import "time"
+
+// Category groups campaigns by cause, e.g. education or disaster relief.
+// Categories are managed by admins; campaign owners pick one of them.
+type Category struct {
+	ID        int       `gorm:"column:id;primaryKey"`
+	Name      string    `gorm:"column:name;type:varchar(64)"`
+	Slug      string    `gorm:"column:slug;type:varchar(80);uniqueIndex"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
@@ -0,0 +1,26 @@
+package category
+
+type CategoryFormatter struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func FormatCategory(category Category) CategoryFormatter {
+	formatter := CategoryFormatter{}
+	formatter.ID = category.ID
+	formatter.Name = category.Name
+	formatter.Slug = category.Slug
+
+	return formatter
+}
+
+func FormatCategories(categories []Category) []CategoryFormatter {
+	formatters := []CategoryFormatter{}
+
+	for _, category := range categories {
+		formatters = append(formatters, FormatCategory(category))
+	}
+
+	return formatters
+}
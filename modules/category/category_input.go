@@ -0,0 +1,12 @@
+package category
+
+import "crowdfunding-minpro-alterra/modules/audit"
+
+type GetCategoryDetailInput struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type CreateCategoryInput struct {
+	Name  string      `json:"name" binding:"required,max=64"`
+	Actor audit.Actor `json:"-" form:"-"`
+}
@@ -0,0 +1,139 @@
+package category
+
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"errors"
+	"strings"
+
+	"github.com/gosimple/slug"
+)
+
+type Service interface {
+	GetCategories() ([]Category, error)
+	CreateCategory(input CreateCategoryInput) (Category, error)
+	UpdateCategory(inputID GetCategoryDetailInput, inputData CreateCategoryInput) (Category, error)
+	DeleteCategory(inputID GetCategoryDetailInput, actor audit.Actor) error
+}
+
+type service struct {
+	repository   Repository
+	auditService audit.Service
+}
+
+func NewService(repository Repository, auditService audit.Service) *service {
+	return &service{repository, auditService}
+}
+
+func (s *service) GetCategories() ([]Category, error) {
+	categories, err := s.repository.FindAll()
+	if err != nil {
+		return categories, err
+	}
+
+	return categories, nil
+}
+
+func (s *service) CreateCategory(input CreateCategoryInput) (Category, error) {
+	name := strings.TrimSpace(input.Name)
+	categorySlug := slug.Make(name)
+
+	err := s.checkSlugAvailable(categorySlug, 0)
+	if err != nil {
+		return Category{}, err
+	}
+
+	category := Category{}
+	category.Name = name
+	category.Slug = categorySlug
+
+	newCategory, err := s.repository.Save(category)
+	if err != nil {
+		return newCategory, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCategory, input.Actor, audit.ActionCategoryCreate, newCategory.ID, map[string]interface{}{"name": newCategory.Name})
+
+	return newCategory, nil
+}
+
+// UpdateCategory renames a category. Its slug follows the new name, so
+// links filtering by the old slug stop matching.
+func (s *service) UpdateCategory(inputID GetCategoryDetailInput, inputData CreateCategoryInput) (Category, error) {
+	category, err := s.repository.FindByID(inputID.ID)
+	if err != nil {
+		return category, err
+	}
+
+	if category.ID == 0 {
+		return category, errors.New("No category found with that ID")
+	}
+
+	name := strings.TrimSpace(inputData.Name)
+	categorySlug := slug.Make(name)
+
+	err = s.checkSlugAvailable(categorySlug, category.ID)
+	if err != nil {
+		return category, err
+	}
+
+	previousName := category.Name
+	category.Name = name
+	category.Slug = categorySlug
+
+	updatedCategory, err := s.repository.Update(category)
+	if err != nil {
+		return updatedCategory, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCategory, inputData.Actor, audit.ActionCategoryUpdate, updatedCategory.ID, map[string]interface{}{"from": previousName, "to": updatedCategory.Name})
+
+	return updatedCategory, nil
+}
+
+// DeleteCategory removes a category no campaign uses. Campaigns have to be
+// moved to another category first.
+func (s *service) DeleteCategory(inputID GetCategoryDetailInput, actor audit.Actor) error {
+	category, err := s.repository.FindByID(inputID.ID)
+	if err != nil {
+		return err
+	}
+
+	if category.ID == 0 {
+		return errors.New("No category found with that ID")
+	}
+
+	count, err := s.repository.CountCampaigns(category.ID)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return errors.New("Category is still used by campaigns")
+	}
+
+	err = s.repository.Delete(category)
+	if err != nil {
+		return err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetCategory, actor, audit.ActionCategoryDelete, category.ID, map[string]interface{}{"name": category.Name})
+
+	return nil
+}
+
+func (s *service) checkSlugAvailable(categorySlug string, ID int) error {
+	if categorySlug == "" {
+		return errors.New("Category name is required")
+	}
+
+	category, err := s.repository.FindBySlug(categorySlug)
+	if err != nil {
+		return err
+	}
+
+	if category.ID != 0 && category.ID != ID {
+		return errors.New("Category already exists")
+	}
+
+	return nil
+}
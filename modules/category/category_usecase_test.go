@@ -0,0 +1,113 @@
+package category
+
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/audit/audittest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRepository struct {
+	Categories     map[int]Category
+	CampaignCounts map[int]int64
+	Deleted        []int
+}
+
+func (m *MockRepository) FindAll() ([]Category, error) {
+	categories := []Category{}
+	for _, category := range m.Categories {
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+func (m *MockRepository) FindByID(ID int) (Category, error) {
+	return m.Categories[ID], nil
+}
+
+func (m *MockRepository) FindBySlug(slug string) (Category, error) {
+	for _, category := range m.Categories {
+		if category.Slug == slug {
+			return category, nil
+		}
+	}
+	return Category{}, nil
+}
+
+func (m *MockRepository) Save(category Category) (Category, error) {
+	category.ID = len(m.Categories) + 1
+	m.Categories[category.ID] = category
+	return category, nil
+}
+
+func (m *MockRepository) Update(category Category) (Category, error) {
+	m.Categories[category.ID] = category
+	return category, nil
+}
+
+func (m *MockRepository) Delete(category Category) error {
+	delete(m.Categories, category.ID)
+	m.Deleted = append(m.Deleted, category.ID)
+	return nil
+}
+
+func (m *MockRepository) CountCampaigns(ID int) (int64, error) {
+	return m.CampaignCounts[ID], nil
+}
+
+func TestCreateCategory(t *testing.T) {
+	repo := &MockRepository{Categories: map[int]Category{}}
+	auditService := &audittest.Service{}
+	service := NewService(repo, auditService)
+
+	newCategory, err := service.CreateCategory(CreateCategoryInput{Name: " Disaster Relief "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Disaster Relief", newCategory.Name)
+	assert.Equal(t, "disaster-relief", newCategory.Slug)
+	assert.Equal(t, audit.ActionCategoryCreate, auditService.Records[0].Action)
+	assert.Equal(t, audit.TargetCategory, auditService.Records[0].TargetType)
+
+	_, err = service.CreateCategory(CreateCategoryInput{Name: "disaster relief"})
+	assert.EqualError(t, err, "Category already exists")
+
+	_, err = service.CreateCategory(CreateCategoryInput{Name: "   "})
+	assert.EqualError(t, err, "Category name is required")
+}
+
+func TestUpdateCategory(t *testing.T) {
+	repo := &MockRepository{Categories: map[int]Category{
+		1: {ID: 1, Name: "Health", Slug: "health"},
+		2: {ID: 2, Name: "Education", Slug: "education"},
+	}}
+	service := NewService(repo, &audittest.Service{})
+
+	updatedCategory, err := service.UpdateCategory(GetCategoryDetailInput{ID: 1}, CreateCategoryInput{Name: "Health Care"})
+	assert.NoError(t, err)
+	assert.Equal(t, "health-care", updatedCategory.Slug)
+
+	_, err = service.UpdateCategory(GetCategoryDetailInput{ID: 1}, CreateCategoryInput{Name: "Education"})
+	assert.EqualError(t, err, "Category already exists")
+
+	_, err = service.UpdateCategory(GetCategoryDetailInput{ID: 3}, CreateCategoryInput{Name: "Animals"})
+	assert.EqualError(t, err, "No category found with that ID")
+}
+
+func TestDeleteCategory(t *testing.T) {
+	repo := &MockRepository{
+		Categories: map[int]Category{
+			1: {ID: 1, Name: "Health", Slug: "health"},
+			2: {ID: 2, Name: "Education", Slug: "education"},
+		},
+		CampaignCounts: map[int]int64{1: 4},
+	}
+	service := NewService(repo, &audittest.Service{})
+
+	err := service.DeleteCategory(GetCategoryDetailInput{ID: 1}, audit.Actor{ID: 9})
+	assert.EqualError(t, err, "Category is still used by campaigns")
+
+	err = service.DeleteCategory(GetCategoryDetailInput{ID: 2}, audit.Actor{ID: 9})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, repo.Deleted)
+}
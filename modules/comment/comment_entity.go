@@ -0,0 +1,78 @@
+package comment
+
+import (
+	"crowdfunding-minpro-alterra/modules/campaign"
+	"crowdfunding-minpro-alterra/modules/user"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// editWindow and deleteWindow are how long after posting the author can
+	// still change or remove a comment.
+	editWindow   = 15 * time.Minute
+	deleteWindow = 24 * time.Hour
+)
+
+// Comment is a supporter's message on a campaign. Threads are one level
+// deep: a reply to a reply is attached to the thread's first comment.
+type Comment struct {
+	ID          int               `gorm:"column:id;primaryKey"`
+	CampaignID  int               `gorm:"column:campaign_id;index"`
+	UserID      int               `gorm:"column:user_id;index"`
+	ParentID    *int              `gorm:"column:parent_id;index"`
+	DonationID  *int              `gorm:"column:donation_id"`
+	Body        string            `gorm:"column:body;type:TEXT"`
+	ReportCount int               `gorm:"column:report_count;index"`
+	EditedAt    *time.Time        `gorm:"column:edited_at"`
+	HiddenAt    *time.Time        `gorm:"column:hidden_at"`
+	HiddenBy    int               `gorm:"column:hidden_by"`
+	CreatedAt   time.Time         `gorm:"column:created_at;index"`
+	UpdatedAt   time.Time         `gorm:"column:updated_at"`
+	DeletedAt   gorm.DeletedAt    `gorm:"column:deleted_at;index"`
+	User        user.User         `gorm:"foreignKey:UserID"`
+	Campaign    campaign.Campaign `gorm:"foreignKey:CampaignID"`
+	Replies     []Comment         `gorm:"foreignKey:ParentID"`
+}
+
+func (c Comment) IsHidden() bool {
+	return c.HiddenAt != nil
+}
+
+// IsRemoved reports whether the comment was deleted or hidden. Threads
+// only list one when it still has visible replies, as a placeholder.
+func (c Comment) IsRemoved() bool {
+	return c.IsHidden() || c.DeletedAt.Valid
+}
+
+// IsByCampaignOwner needs Campaign to be loaded.
+func (c Comment) IsByCampaignOwner() bool {
+	return c.Campaign.ID != 0 && c.UserID == c.Campaign.UserID
+}
+
+// HasDonated reports whether the comment is linked to a paid donation.
+func (c Comment) HasDonated() bool {
+	return c.DonationID != nil
+}
+
+func (c Comment) CanEdit(now time.Time) bool {
+	return now.Sub(c.CreatedAt) <= editWindow
+}
+
+func (c Comment) CanDelete(now time.Time) bool {
+	return now.Sub(c.CreatedAt) <= deleteWindow
+}
+
+// Report is one user flagging a comment. A user can report a comment once.
+type Report struct {
+	ID        int       `gorm:"column:id;primaryKey"`
+	CommentID int       `gorm:"column:comment_id;uniqueIndex:idx_comment_reports_comment_user"`
+	UserID    int       `gorm:"column:user_id;uniqueIndex:idx_comment_reports_comment_user"`
+	Reason    string    `gorm:"column:reason;type:varchar(255)"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (Report) TableName() string {
+	return "comment_reports"
+}
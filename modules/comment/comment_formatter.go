@@ -0,0 +1,101 @@
+package comment
+
+import "time"
+
+// removedBody replaces the body of a deleted or hidden comment that is
+// listed for the sake of its replies.
+const removedBody = "[deleted]"
+
+type CommentFormatter struct {
+	ID        int                  `json:"id"`
+	Body      string               `json:"body"`
+	User      CommentUserFormatter `json:"user"`
+	IsOwner   bool                 `json:"is_owner"`
+	Donated   bool                 `json:"donated"`
+	Removed   bool                 `json:"removed"`
+	EditedAt  *time.Time           `json:"edited_at"`
+	CreatedAt time.Time            `json:"created_at"`
+	Replies   []CommentFormatter   `json:"replies"`
+}
+
+type CommentUserFormatter struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+func FormatComment(comment Comment) CommentFormatter {
+	formatter := CommentFormatter{}
+	formatter.ID = comment.ID
+	formatter.Body = comment.Body
+	formatter.IsOwner = comment.IsByCampaignOwner()
+	formatter.Donated = comment.HasDonated()
+	formatter.EditedAt = comment.EditedAt
+	formatter.CreatedAt = comment.CreatedAt
+
+	formatter.User = CommentUserFormatter{
+		ID:       comment.User.ID,
+		Name:     comment.User.Name,
+		ImageURL: comment.User.AvatarFileName,
+	}
+
+	formatter.Replies = FormatComments(comment.Replies)
+
+	// Only the place in the thread is kept; who wrote it and what it said
+	// are not shown.
+	if comment.IsRemoved() {
+		formatter.Body = removedBody
+		formatter.User = CommentUserFormatter{}
+		formatter.IsOwner = false
+		formatter.Donated = false
+		formatter.Removed = true
+		formatter.EditedAt = nil
+	}
+
+	return formatter
+}
+
+func FormatComments(comments []Comment) []CommentFormatter {
+	formatters := []CommentFormatter{}
+
+	for _, comment := range comments {
+		formatters = append(formatters, FormatComment(comment))
+	}
+
+	return formatters
+}
+
+// ReportedCommentFormatter is the moderators' view of a comment, hidden or
+// not.
+type ReportedCommentFormatter struct {
+	ID          int        `json:"id"`
+	CampaignID  int        `json:"campaign_id"`
+	UserID      int        `json:"user_id"`
+	Body        string     `json:"body"`
+	ReportCount int        `json:"report_count"`
+	HiddenAt    *time.Time `json:"hidden_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func FormatReportedComment(comment Comment) ReportedCommentFormatter {
+	formatter := ReportedCommentFormatter{}
+	formatter.ID = comment.ID
+	formatter.CampaignID = comment.CampaignID
+	formatter.UserID = comment.UserID
+	formatter.Body = comment.Body
+	formatter.ReportCount = comment.ReportCount
+	formatter.HiddenAt = comment.HiddenAt
+	formatter.CreatedAt = comment.CreatedAt
+
+	return formatter
+}
+
+func FormatReportedComments(comments []Comment) []ReportedCommentFormatter {
+	formatters := []ReportedCommentFormatter{}
+
+	for _, comment := range comments {
+		formatters = append(formatters, FormatReportedComment(comment))
+	}
+
+	return formatters
+}
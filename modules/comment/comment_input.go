@@ -0,0 +1,42 @@
+package comment
+
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/user"
+)
+
+type GetCommentDetailInput struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type GetCommentsInput struct {
+	CampaignID int `uri:"id" binding:"required"`
+	Page       int `form:"page" binding:"omitempty,min=1"`
+	Limit      int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type CreateCommentInput struct {
+	CampaignID int
+	Body       string      `json:"body" binding:"required,max=2000"`
+	ParentID   int         `json:"parent_id"`
+	DonationID int         `json:"donation_id"`
+	User       user.User   `json:"-"`
+	Actor      audit.Actor `json:"-" form:"-"`
+}
+
+type UpdateCommentInput struct {
+	Body  string      `json:"body" binding:"required,max=2000"`
+	User  user.User   `json:"-"`
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type ReportCommentInput struct {
+	ID     int
+	Reason string    `json:"reason" binding:"required,max=255"`
+	User   user.User `json:"-"`
+}
+
+type GetReportedCommentsInput struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,138 @@
+package comment
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	FindByCampaignID(input GetCommentsInput) ([]Comment, int64, error)
+	FindReported(input GetReportedCommentsInput) ([]Comment, int64, error)
+	FindByID(ID int) (Comment, error)
+	Save(comment Comment) (Comment, error)
+	Update(comment Comment) (Comment, error)
+	Delete(comment Comment) error
+	SaveReport(report Report) (bool, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+// FindByCampaignID returns a page of threads, newest first, each with its
+// visible replies oldest first. A deleted or hidden comment that still has
+// visible replies is included so they are not lost with it; the formatter
+// shows it as a placeholder.
+func (r *repository) FindByCampaignID(input GetCommentsInput) ([]Comment, int64, error) {
+	var comments []Comment
+	var total int64
+
+	query := r.db.Unscoped().Model(&Comment{}).
+		Where("campaign_id = ? AND parent_id IS NULL", input.CampaignID).
+		Where("(comments.deleted_at IS NULL AND comments.hidden_at IS NULL) OR EXISTS (SELECT 1 FROM comments AS replies WHERE replies.parent_id = comments.id AND replies.deleted_at IS NULL AND replies.hidden_at IS NULL)")
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return comments, total, err
+	}
+
+	// The query is unscoped for the placeholders, so deleted rows are left
+	// out of the preloads explicitly.
+	err = query.Order("created_at desc, id desc").Offset((input.Page-1)*input.Limit).Limit(input.Limit).
+		Preload("User", "deleted_at IS NULL").
+		Preload("Campaign").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB {
+			return db.Where("deleted_at IS NULL AND hidden_at IS NULL").Order("created_at, id")
+		}).
+		Preload("Replies.User", "deleted_at IS NULL").
+		Preload("Replies.Campaign").
+		Find(&comments).Error
+	if err != nil {
+		return comments, total, err
+	}
+
+	return comments, total, nil
+}
+
+// FindReported returns reported comments, most reported first, including
+// the hidden ones.
+func (r *repository) FindReported(input GetReportedCommentsInput) ([]Comment, int64, error) {
+	var comments []Comment
+	var total int64
+
+	query := r.db.Model(&Comment{}).Where("report_count > 0")
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return comments, total, err
+	}
+
+	err = query.Order("report_count desc, id desc").Offset((input.Page - 1) * input.Limit).Limit(input.Limit).Find(&comments).Error
+	if err != nil {
+		return comments, total, err
+	}
+
+	return comments, total, nil
+}
+
+func (r *repository) FindByID(ID int) (Comment, error) {
+	var comment Comment
+
+	err := r.db.Preload("User").Preload("Campaign").Where("id = ?", ID).Find(&comment).Error
+	if err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
+
+func (r *repository) Save(comment Comment) (Comment, error) {
+	err := r.db.Omit(clause.Associations).Create(&comment).Error
+	if err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
+
+// Update saves the columns a comment can change after posting. The report
+// count is left alone so reports made in the meantime are kept.
+func (r *repository) Update(comment Comment) (Comment, error) {
+	err := r.db.Model(&comment).Select("body", "edited_at", "hidden_at", "hidden_by", "updated_at").Updates(&comment).Error
+	if err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
+
+func (r *repository) Delete(comment Comment) error {
+	return r.db.Delete(&comment).Error
+}
+
+// SaveReport stores a report and bumps the comment's report count. It
+// returns false if the user had already reported the comment.
+func (r *repository) SaveReport(report Report) (bool, error) {
+	created := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&report)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		created = true
+
+		return tx.Model(&Comment{}).Where("id = ?", report.CommentID).Update("report_count", gorm.Expr("report_count + 1")).Error
+	})
+
+	return created, err
+}
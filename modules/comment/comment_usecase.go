@@ -0,0 +1,313 @@
+package comment
+
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/campaign"
+	"crowdfunding-minpro-alterra/modules/donation"
+	"crowdfunding-minpro-alterra/modules/user"
+	"errors"
+	"strings"
+	"time"
+)
+
+const defaultCommentsPageLimit = 20
+
+type Service interface {
+	GetComments(input GetCommentsInput) ([]Comment, int64, GetCommentsInput, error)
+	CreateComment(input CreateCommentInput) (Comment, error)
+	UpdateComment(inputID GetCommentDetailInput, inputData UpdateCommentInput) (Comment, error)
+	DeleteComment(inputID GetCommentDetailInput, currentUser user.User, actor audit.Actor) error
+	ReportComment(input ReportCommentInput) error
+	GetReportedComments(input GetReportedCommentsInput) ([]Comment, int64, GetReportedCommentsInput, error)
+	HideComment(inputID GetCommentDetailInput, actor audit.Actor) (Comment, error)
+	UnhideComment(inputID GetCommentDetailInput, actor audit.Actor) (Comment, error)
+}
+
+type service struct {
+	repository         Repository
+	campaignRepository campaign.Repository
+	donationRepository donation.Repository
+	auditService       audit.Service
+}
+
+func NewService(repository Repository, campaignRepository campaign.Repository, donationRepository donation.Repository, auditService audit.Service) *service {
+	return &service{repository, campaignRepository, donationRepository, auditService}
+}
+
+// GetComments returns one page of a public campaign's comment threads,
+// newest first, along with the input after defaults were applied.
+func (s *service) GetComments(input GetCommentsInput) ([]Comment, int64, GetCommentsInput, error) {
+	if input.Page == 0 {
+		input.Page = 1
+	}
+
+	if input.Limit == 0 {
+		input.Limit = defaultCommentsPageLimit
+	}
+
+	// Comments of a cancelled campaign stay readable along with its page,
+	// but nobody can add new ones.
+	campaign, err := s.campaignRepository.FindByID(input.CampaignID)
+	if err != nil {
+		return []Comment{}, 0, input, err
+	}
+
+	if campaign.ID == 0 || !campaign.IsViewable() {
+		return []Comment{}, 0, input, errors.New("No campaign found with that ID")
+	}
+
+	comments, total, err := s.repository.FindByCampaignID(input)
+	if err != nil {
+		return comments, total, input, err
+	}
+
+	return comments, total, input, nil
+}
+
+func (s *service) CreateComment(input CreateCommentInput) (Comment, error) {
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return Comment{}, errors.New("Comment cannot be empty")
+	}
+
+	campaign, err := s.findPublicCampaign(input.CampaignID)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	comment := Comment{}
+	comment.CampaignID = campaign.ID
+	comment.UserID = input.User.ID
+	comment.Body = body
+
+	if input.ParentID != 0 {
+		parent, err := s.repository.FindByID(input.ParentID)
+		if err != nil {
+			return Comment{}, err
+		}
+
+		if parent.ID == 0 || parent.CampaignID != campaign.ID || parent.IsHidden() {
+			return Comment{}, errors.New("No comment found with that ID")
+		}
+
+		parentID := parent.ID
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+
+		comment.ParentID = &parentID
+	}
+
+	if input.DonationID != 0 {
+		donation, err := s.donationRepository.GetByID(input.DonationID)
+		if err != nil {
+			return Comment{}, err
+		}
+
+		if donation.ID == 0 || donation.UserID != input.User.ID || donation.CampaignID != campaign.ID || donation.Status != "paid" {
+			return Comment{}, errors.New("Only your own paid donation to this campaign can be linked")
+		}
+
+		comment.DonationID = &donation.ID
+	}
+
+	newComment, err := s.repository.Save(comment)
+	if err != nil {
+		return newComment, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetComment, input.Actor, audit.ActionCommentCreate, newComment.ID, map[string]interface{}{"campaign_id": campaign.ID, "parent_id": newComment.ParentID})
+
+	newComment.User = input.User
+	newComment.Campaign = campaign
+
+	return newComment, nil
+}
+
+func (s *service) UpdateComment(inputID GetCommentDetailInput, inputData UpdateCommentInput) (Comment, error) {
+	comment, err := s.findOwnComment(inputID.ID, inputData.User)
+	if err != nil {
+		return comment, err
+	}
+
+	now := time.Now()
+
+	if comment.IsHidden() || !comment.CanEdit(now) {
+		return comment, errors.New("Comment can no longer be edited")
+	}
+
+	body := strings.TrimSpace(inputData.Body)
+	if body == "" {
+		return comment, errors.New("Comment cannot be empty")
+	}
+
+	comment.Body = body
+	comment.EditedAt = &now
+
+	updatedComment, err := s.repository.Update(comment)
+	if err != nil {
+		return updatedComment, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetComment, inputData.Actor, audit.ActionCommentUpdate, updatedComment.ID, nil)
+
+	return updatedComment, nil
+}
+
+func (s *service) DeleteComment(inputID GetCommentDetailInput, currentUser user.User, actor audit.Actor) error {
+	comment, err := s.findOwnComment(inputID.ID, currentUser)
+	if err != nil {
+		return err
+	}
+
+	if !comment.CanDelete(time.Now()) {
+		return errors.New("Comment can no longer be deleted")
+	}
+
+	err = s.repository.Delete(comment)
+	if err != nil {
+		return err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetComment, actor, audit.ActionCommentDelete, comment.ID, nil)
+
+	return nil
+}
+
+func (s *service) ReportComment(input ReportCommentInput) error {
+	comment, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return err
+	}
+
+	if comment.ID == 0 {
+		return errors.New("No comment found with that ID")
+	}
+
+	// Hidden comments are already with the moderators, and comments on a
+	// campaign the reporter cannot see are not theirs to report.
+	if comment.IsHidden() || !comment.Campaign.IsViewable() {
+		return errors.New("No comment found with that ID")
+	}
+
+	if comment.UserID == input.User.ID {
+		return errors.New("You cannot report your own comment")
+	}
+
+	created, err := s.repository.SaveReport(Report{CommentID: comment.ID, UserID: input.User.ID, Reason: strings.TrimSpace(input.Reason)})
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		return errors.New("You already reported this comment")
+	}
+
+	return nil
+}
+
+func (s *service) GetReportedComments(input GetReportedCommentsInput) ([]Comment, int64, GetReportedCommentsInput, error) {
+	if input.Page == 0 {
+		input.Page = 1
+	}
+
+	if input.Limit == 0 {
+		input.Limit = defaultCommentsPageLimit
+	}
+
+	comments, total, err := s.repository.FindReported(input)
+	if err != nil {
+		return comments, total, input, err
+	}
+
+	return comments, total, input, nil
+}
+
+// HideComment takes a comment out of the public thread without deleting
+// it. A hidden comment with visible replies stays in the thread as a
+// placeholder.
+func (s *service) HideComment(inputID GetCommentDetailInput, actor audit.Actor) (Comment, error) {
+	comment, err := s.findComment(inputID.ID)
+	if err != nil {
+		return comment, err
+	}
+
+	if comment.IsHidden() {
+		return comment, errors.New("Comment is already hidden")
+	}
+
+	now := time.Now()
+	comment.HiddenAt = &now
+	comment.HiddenBy = actor.ID
+
+	hiddenComment, err := s.repository.Update(comment)
+	if err != nil {
+		return hiddenComment, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetComment, actor, audit.ActionCommentHide, hiddenComment.ID, map[string]interface{}{"report_count": hiddenComment.ReportCount})
+
+	return hiddenComment, nil
+}
+
+func (s *service) UnhideComment(inputID GetCommentDetailInput, actor audit.Actor) (Comment, error) {
+	comment, err := s.findComment(inputID.ID)
+	if err != nil {
+		return comment, err
+	}
+
+	if !comment.IsHidden() {
+		return comment, errors.New("Comment is not hidden")
+	}
+
+	comment.HiddenAt = nil
+	comment.HiddenBy = 0
+
+	visibleComment, err := s.repository.Update(comment)
+	if err != nil {
+		return visibleComment, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetComment, actor, audit.ActionCommentUnhide, visibleComment.ID, nil)
+
+	return visibleComment, nil
+}
+
+func (s *service) findComment(ID int) (Comment, error) {
+	comment, err := s.repository.FindByID(ID)
+	if err != nil {
+		return comment, err
+	}
+
+	if comment.ID == 0 {
+		return comment, errors.New("No comment found with that ID")
+	}
+
+	return comment, nil
+}
+
+func (s *service) findOwnComment(ID int, author user.User) (Comment, error) {
+	comment, err := s.findComment(ID)
+	if err != nil {
+		return comment, err
+	}
+
+	if comment.UserID != author.ID {
+		return comment, errors.New("Not the author of the comment.")
+	}
+
+	return comment, nil
+}
+
+func (s *service) findPublicCampaign(ID int) (campaign.Campaign, error) {
+	campaign, err := s.campaignRepository.FindByID(ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 || !campaign.IsPublic() {
+		return campaign, errors.New("No campaign found with that ID")
+	}
+
+	return campaign, nil
+}
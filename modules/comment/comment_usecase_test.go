@@ -0,0 +1,213 @@
+package comment
+
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/audit/audittest"
+	"crowdfunding-minpro-alterra/modules/campaign"
+	"crowdfunding-minpro-alterra/modules/donation"
+	"crowdfunding-minpro-alterra/modules/user"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRepository struct {
+	Comments map[int]Comment
+	Reports  map[[2]int]bool
+}
+
+func (m *MockRepository) FindByCampaignID(input GetCommentsInput) ([]Comment, int64, error) {
+	return []Comment{}, 0, nil
+}
+
+func (m *MockRepository) FindReported(input GetReportedCommentsInput) ([]Comment, int64, error) {
+	return []Comment{}, 0, nil
+}
+
+func (m *MockRepository) FindByID(ID int) (Comment, error) {
+	return m.Comments[ID], nil
+}
+
+func (m *MockRepository) Save(comment Comment) (Comment, error) {
+	comment.ID = len(m.Comments) + 1
+	comment.CreatedAt = time.Now()
+	m.Comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (m *MockRepository) Update(comment Comment) (Comment, error) {
+	m.Comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (m *MockRepository) Delete(comment Comment) error {
+	delete(m.Comments, comment.ID)
+	return nil
+}
+
+func (m *MockRepository) SaveReport(report Report) (bool, error) {
+	key := [2]int{report.CommentID, report.UserID}
+	if m.Reports[key] {
+		return false, nil
+	}
+	m.Reports[key] = true
+	return true, nil
+}
+
+// MockCampaignRepository embeds the interface so only the methods the
+// comment service uses have to be implemented.
+type MockCampaignRepository struct {
+	campaign.Repository
+	Campaign campaign.Campaign
+}
+
+func (m *MockCampaignRepository) FindByID(ID int) (campaign.Campaign, error) {
+	if ID != m.Campaign.ID {
+		return campaign.Campaign{}, nil
+	}
+	return m.Campaign, nil
+}
+
+type MockDonationRepository struct {
+	donation.Repository
+	Donations map[int]donation.Donation
+}
+
+func (m *MockDonationRepository) GetByID(ID int) (donation.Donation, error) {
+	return m.Donations[ID], nil
+}
+
+func newTestService(comments map[int]Comment) (*service, *MockRepository) {
+	repo := &MockRepository{Comments: comments, Reports: map[[2]int]bool{}}
+	campaignRepository := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 1, UserID: 10, Status: campaign.StatusActive}}
+	donationRepository := &MockDonationRepository{Donations: map[int]donation.Donation{
+		5: {ID: 5, CampaignID: 1, UserID: 2, Status: "paid"},
+		6: {ID: 6, CampaignID: 1, UserID: 2, Status: "pending"},
+	}}
+
+	return NewService(repo, campaignRepository, donationRepository, &audittest.Service{}), repo
+}
+
+func TestCreateComment(t *testing.T) {
+	rootID := 1
+	service, _ := newTestService(map[int]Comment{
+		1: {ID: 1, CampaignID: 1, UserID: 3},
+		2: {ID: 2, CampaignID: 1, UserID: 4, ParentID: &rootID},
+	})
+	supporter := user.User{ID: 2}
+
+	t.Run("donated badge", func(t *testing.T) {
+		newComment, err := service.CreateComment(CreateCommentInput{CampaignID: 1, Body: " Good luck! ", DonationID: 5, User: supporter})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Good luck!", newComment.Body)
+		assert.True(t, newComment.HasDonated())
+		assert.False(t, newComment.IsByCampaignOwner())
+	})
+
+	t.Run("unpaid donation", func(t *testing.T) {
+		_, err := service.CreateComment(CreateCommentInput{CampaignID: 1, Body: "Hi", DonationID: 6, User: supporter})
+		assert.EqualError(t, err, "Only your own paid donation to this campaign can be linked")
+	})
+
+	t.Run("owner reply to a reply joins the thread", func(t *testing.T) {
+		reply, err := service.CreateComment(CreateCommentInput{CampaignID: 1, Body: "Thank you!", ParentID: 2, User: user.User{ID: 10}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, *reply.ParentID)
+		assert.True(t, reply.IsByCampaignOwner())
+	})
+
+	t.Run("campaign not public", func(t *testing.T) {
+		_, err := service.CreateComment(CreateCommentInput{CampaignID: 9, Body: "Hi", User: supporter})
+		assert.EqualError(t, err, "No campaign found with that ID")
+	})
+}
+
+func TestUpdateAndDeleteWindows(t *testing.T) {
+	service, repo := newTestService(map[int]Comment{
+		1: {ID: 1, CampaignID: 1, UserID: 2, CreatedAt: time.Now().Add(-time.Minute)},
+		2: {ID: 2, CampaignID: 1, UserID: 2, CreatedAt: time.Now().Add(-time.Hour)},
+		3: {ID: 3, CampaignID: 1, UserID: 2, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	})
+	author := user.User{ID: 2}
+
+	updatedComment, err := service.UpdateComment(GetCommentDetailInput{ID: 1}, UpdateCommentInput{Body: "Edited", User: author})
+	assert.NoError(t, err)
+	assert.NotNil(t, updatedComment.EditedAt)
+
+	_, err = service.UpdateComment(GetCommentDetailInput{ID: 1}, UpdateCommentInput{Body: "Edited", User: user.User{ID: 3}})
+	assert.EqualError(t, err, "Not the author of the comment.")
+
+	_, err = service.UpdateComment(GetCommentDetailInput{ID: 2}, UpdateCommentInput{Body: "Too late", User: author})
+	assert.EqualError(t, err, "Comment can no longer be edited")
+
+	err = service.DeleteComment(GetCommentDetailInput{ID: 3}, author, audit.Actor{ID: 2})
+	assert.EqualError(t, err, "Comment can no longer be deleted")
+
+	err = service.DeleteComment(GetCommentDetailInput{ID: 2}, author, audit.Actor{ID: 2})
+	assert.NoError(t, err)
+	assert.NotContains(t, repo.Comments, 2)
+}
+
+func TestReportAndHideComment(t *testing.T) {
+	service, repo := newTestService(map[int]Comment{
+		1: {ID: 1, CampaignID: 1, UserID: 2, Campaign: campaign.Campaign{ID: 1, Status: campaign.StatusActive}},
+	})
+
+	err := service.ReportComment(ReportCommentInput{ID: 1, Reason: "Spam", User: user.User{ID: 2}})
+	assert.EqualError(t, err, "You cannot report your own comment")
+
+	err = service.ReportComment(ReportCommentInput{ID: 1, Reason: "Spam", User: user.User{ID: 3}})
+	assert.NoError(t, err)
+
+	err = service.ReportComment(ReportCommentInput{ID: 1, Reason: "Spam", User: user.User{ID: 3}})
+	assert.EqualError(t, err, "You already reported this comment")
+
+	hiddenComment, err := service.HideComment(GetCommentDetailInput{ID: 1}, audit.Actor{ID: 9})
+	assert.NoError(t, err)
+	assert.True(t, hiddenComment.IsHidden())
+	assert.Equal(t, 9, repo.Comments[1].HiddenBy)
+
+	_, err = service.HideComment(GetCommentDetailInput{ID: 1}, audit.Actor{ID: 9})
+	assert.EqualError(t, err, "Comment is already hidden")
+
+	visibleComment, err := service.UnhideComment(GetCommentDetailInput{ID: 1}, audit.Actor{ID: 9})
+	assert.NoError(t, err)
+	assert.False(t, visibleComment.IsHidden())
+}
+
+func TestReportComment_RejectsHiddenAndUnviewable(t *testing.T) {
+	hiddenAt := time.Now()
+	service, _ := newTestService(map[int]Comment{
+		1: {ID: 1, CampaignID: 1, UserID: 2, HiddenAt: &hiddenAt, Campaign: campaign.Campaign{ID: 1, Status: campaign.StatusActive}},
+		2: {ID: 2, CampaignID: 2, UserID: 2, Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusDraft}},
+	})
+
+	err := service.ReportComment(ReportCommentInput{ID: 1, Reason: "Spam", User: user.User{ID: 3}})
+	assert.EqualError(t, err, "No comment found with that ID")
+
+	err = service.ReportComment(ReportCommentInput{ID: 2, Reason: "Spam", User: user.User{ID: 3}})
+	assert.EqualError(t, err, "No comment found with that ID")
+}
+
+func TestFormatComment_RemovedKeepsReplies(t *testing.T) {
+	hiddenAt := time.Now()
+	parentID := 1
+	formatted := FormatComment(Comment{
+		ID:       1,
+		Body:     "Offensive",
+		UserID:   2,
+		User:     user.User{ID: 2, Name: "Author"},
+		HiddenAt: &hiddenAt,
+		Replies:  []Comment{{ID: 2, ParentID: &parentID, Body: "Reply", UserID: 3}},
+	})
+
+	assert.True(t, formatted.Removed)
+	assert.Equal(t, "[deleted]", formatted.Body)
+	assert.Equal(t, CommentUserFormatter{}, formatted.User)
+	assert.Len(t, formatted.Replies, 1)
+	assert.Equal(t, "Reply", formatted.Replies[0].Body)
+	assert.False(t, formatted.Replies[0].Removed)
+}
@@ -13,12 +13,18 @@ type Donation struct {
 	ID         int
 	CampaignID int
 	UserID     int
+	RewardID   *int `gorm:"index"`
 	Amount     int
 	Status     string
 	Code       string
 	PaymentURL string
+	// RefundRequired marks a cancelled donation whose payment still went
+	// through, e.g. after its campaign was cancelled. The money has to be
+	// returned to the donor; it never counts toward the campaign.
+	RefundRequired bool `gorm:"index"`
 	User       user.User
 	Campaign   campaign.Campaign
+	Reward     campaign.Reward
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	DeletedAt *time.Time
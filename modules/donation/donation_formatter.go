@@ -6,6 +6,7 @@ type CampaignDonationFormatter struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
 	Amount    int    `json:"amount"`
+	RewardID  *int   `json:"reward_id"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -15,6 +16,7 @@ func FormatCampaignDonation(donation Donation) CampaignDonationFormatter {
 	formatter.ID = donation.ID
 	formatter.Name = donation.User.Name
 	formatter.Amount = donation.Amount
+	formatter.RewardID = donation.RewardID
 	formatter.CreatedAt = donation.CreatedAt
 
 	return formatter
@@ -40,6 +42,8 @@ type UserDonationFormatter struct {
 	ID        int    `json:"id"`
 	Amount    int    `json:"amount"`
 	Status    string `json:"status"`
+	RefundRequired bool `json:"refund_required"`
+	RewardID  *int   `json:"reward_id"`
 	CreatedAt time.Time `json:"created_at"`
 	Campaign  CampaignFormatter `json:"campaign"`
 }
@@ -55,14 +59,16 @@ func FormatUserDonation(donation Donation) UserDonationFormatter {
 	formatter.ID = donation.ID
 	formatter.Amount = donation.Amount
 	formatter.Status = donation.Status
+	formatter.RefundRequired = donation.RefundRequired
+	formatter.RewardID = donation.RewardID
 	formatter.CreatedAt = donation.CreatedAt
 
 	campaignFormatter := CampaignFormatter{}
 	campaignFormatter.Name = donation.Campaign.Name
 	campaignFormatter.ImageURL = ""
 
-	if len(donation.Campaign.CampaignImages) > 0 {
-		campaignFormatter.ImageURL = donation.Campaign.CampaignImages[0].FileName
+	if image, ok := donation.Campaign.PrimaryImage(); ok {
+		campaignFormatter.ImageURL = image.FileName
 	}
 
 	formatter.Campaign = campaignFormatter
@@ -91,6 +97,7 @@ type DonationFormatter struct {
 	CampaignID int    `json:"campaign_id"`
 	UserID    int    `json:"user_id"`
 	Amount    int    `json:"amount"`
+	RewardID  *int   `json:"reward_id"`
 	Status    string `json:"status"`
 	Code      string `json:"code"`
 	PaymentURL string `json:"payment_url"`
@@ -103,6 +110,7 @@ func FormatDonation(donation Donation) DonationFormatter {
 	formatter.CampaignID = donation.CampaignID
 	formatter.UserID = donation.UserID
 	formatter.Amount = donation.Amount
+	formatter.RewardID = donation.RewardID
 	formatter.Status = donation.Status
 	formatter.Code = donation.Code
 	formatter.PaymentURL = donation.PaymentURL
@@ -1,6 +1,9 @@
 package donation
 
-import "crowdfunding-minpro-alterra/modules/user"
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/user"
+)
 
 type GetCampaignDonationsInput struct {
 	ID   int `uri:"id" binding:"required"`
@@ -10,7 +13,9 @@ type GetCampaignDonationsInput struct {
 type CreateDonationInput struct {
 	Amount int `json:"amount" binding:"required"`
 	CampaignID int `json:"campaign_id" binding:"required"`
+	RewardID int `json:"reward_id"`
 	User user.User
+	Actor audit.Actor `json:"-" form:"-"`
 }
 
 type DonationNotificationInput struct {
@@ -18,4 +23,5 @@ type DonationNotificationInput struct {
 	OrderID           string `json:"order_id"`
 	PaymentType       string `json:"payment_type"`
 	FraudStatus       string `json:"fraud_status"`
+	Actor             audit.Actor `json:"-" form:"-"`
 }
\ No newline at end of file
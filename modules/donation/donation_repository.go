@@ -1,6 +1,10 @@
 package donation
 
-import "gorm.io/gorm"
+import (
+	"crowdfunding-minpro-alterra/modules/campaign"
+
+	"gorm.io/gorm"
+)
 
 type repository struct {
 	db *gorm.DB
@@ -12,6 +16,7 @@ type Repository interface {
 	GetByID(ID int) (Donation, error)
 	Save(donation Donation) (Donation, error)
 	Update(donation Donation) (Donation, error)
+	UpdateStatus(donation Donation, from string) (bool, error)
 }
 
 func NewRepository(db *gorm.DB) *repository {
@@ -33,7 +38,7 @@ func (r *repository) GetByCampaignID(CampaignID int) ([]Donation, error) {
 func (r *repository) GetByUserID(UserID int) ([]Donation, error) {
 	var donations []Donation
 
-	err := r.db.Preload("Campaign.CampaignImages", "campaign_images.is_primary = 1").Where("user_id = ?", UserID).Order("created_at desc").Find(&donations).Error
+	err := r.db.Preload("Campaign.CampaignImages", campaign.GalleryOrder).Where("user_id = ?", UserID).Order("created_at desc").Find(&donations).Error
 
 	if err != nil {
 		return donations, err
@@ -72,4 +77,20 @@ func (r *repository) Update(donation Donation) (Donation, error) {
 	}
 
 	return donation, nil
-}
\ No newline at end of file
+}
+
+// UpdateStatus writes the donation's status and refund flag if its status in
+// the database is still from, and reports whether it did. A donation flagged
+// for refund is settled and never changes again.
+func (r *repository) UpdateStatus(donation Donation, from string) (bool, error) {
+	result := r.db.Model(&Donation{}).
+		Where("id = ? AND status = ? AND refund_required = ?", donation.ID, from, false).
+		Select("status", "refund_required", "updated_at").
+		Updates(&donation)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
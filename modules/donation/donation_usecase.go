@@ -1,16 +1,19 @@
 package donation
 
 import (
+	"crowdfunding-minpro-alterra/modules/audit"
 	"crowdfunding-minpro-alterra/modules/campaign"
 	"crowdfunding-minpro-alterra/modules/payment"
 	"errors"
 	"strconv"
+	"time"
 )
 
 type service struct {
 	repository         Repository
 	campaignRepository campaign.Repository
 	paymentService     payment.Service
+	auditService       audit.Service
 }
 
 func (s *service) GetAllTransactions() ([]Donation, error) {
@@ -25,8 +28,8 @@ type Service interface {
 	GetAllTransactions() ([]Donation, error)
 }
 
-func NewService(repository Repository, campaignRepository campaign.Repository, paymentService payment.Service) *service {
-	return &service{repository, campaignRepository, paymentService}
+func NewService(repository Repository, campaignRepository campaign.Repository, paymentService payment.Service, auditService audit.Service) *service {
+	return &service{repository, campaignRepository, paymentService, auditService}
 }
 
 func (s *service) GetDonationsByCampaignID(input GetCampaignDonationsInput) ([]Donation, error) {
@@ -60,6 +63,20 @@ func (s *service) GetDonationsByUserID(userID int) ([]Donation, error) {
 }
 
 func (s *service) CreateDonation(input CreateDonationInput) (Donation, error) {
+	campaign, err := s.campaignRepository.FindByID(input.CampaignID)
+
+	if err != nil {
+		return Donation{}, err
+	}
+
+	if campaign.ID == 0 {
+		return Donation{}, errors.New("No campaign found with that ID")
+	}
+
+	if !campaign.AcceptsDonations(time.Now()) {
+		return Donation{}, errors.New("Campaign is not accepting donations")
+	}
+
 	donation := Donation{}
 
 	donation.CampaignID = input.CampaignID
@@ -68,12 +85,27 @@ func (s *service) CreateDonation(input CreateDonationInput) (Donation, error) {
 	donation.Status = "pending"
 	// donation.Code = ""
 
+	if input.RewardID != 0 {
+		err := s.reserveReward(campaign, input.RewardID, input.Amount)
+		if err != nil {
+			return Donation{}, err
+		}
+
+		donation.RewardID = &input.RewardID
+	}
+
 	newDonation, err := s.repository.Save(donation)
 
 	if err != nil {
+		if donation.RewardID != nil {
+			_ = s.campaignRepository.ReleaseReward(*donation.RewardID)
+		}
+
 		return newDonation, err
 	}
 
+	audit.RecordQuietly(s.auditService, audit.TargetDonation, input.Actor, audit.ActionDonationCreate, newDonation.ID, map[string]interface{}{"campaign_id": newDonation.CampaignID, "amount": newDonation.Amount, "reward_id": newDonation.RewardID})
+
 	paymentDonation := payment.Donation{
 		ID:     newDonation.ID,
 		Amount: newDonation.Amount,
@@ -81,7 +113,17 @@ func (s *service) CreateDonation(input CreateDonationInput) (Donation, error) {
 
 	paymentURL, err := s.paymentService.GetPaymentURL(paymentDonation, input.User)
 
+	// Without a payment link the donation can never be paid, so it is
+	// cancelled and its reward goes back to the pool.
 	if err != nil {
+		cancelled := newDonation
+		cancelled.Status = "cancelled"
+
+		updated, updateErr := s.repository.UpdateStatus(cancelled, newDonation.Status)
+		if updateErr == nil && updated && newDonation.RewardID != nil {
+			_ = s.campaignRepository.ReleaseReward(*newDonation.RewardID)
+		}
+
 		return newDonation, err
 	}
 
@@ -104,6 +146,15 @@ func (s *service) ProcessPayment(input DonationNotificationInput) error {
 		return err
 	}
 
+	donatedCampaign, err := s.campaignRepository.FindByID(donation.CampaignID)
+
+	if err != nil {
+		return err
+	}
+
+	previousStatus := donation.Status
+	wasRefundRequired := donation.RefundRequired
+
 	if input.PaymentType == "credit_card" && input.TransactionStatus == "capture" && input.FraudStatus == "accept" {
 		donation.Status = "paid"
 	} else if input.TransactionStatus == "settlement" {
@@ -112,23 +163,65 @@ func (s *service) ProcessPayment(input DonationNotificationInput) error {
 		donation.Status = "cancelled"
 	}
 
-	updatedDonation, err := s.repository.Update(donation)
+	// Cancelled is final, and a cancelled campaign takes no money. A payment
+	// that still comes through is flagged to be refunded instead of counted.
+	if donation.Status == "paid" && previousStatus != "paid" && (previousStatus == "cancelled" || donatedCampaign.Status == campaign.StatusCancelled) {
+		donation.Status = "cancelled"
+		donation.RefundRequired = true
+	}
 
-	if err != nil {
-		return err
+	// A repeated notification changes nothing.
+	if donation.Status == previousStatus && donation.RefundRequired == wasRefundRequired {
+		return nil
 	}
 
-	campaign, err := s.campaignRepository.FindByID(updatedDonation.CampaignID)
+	// Only the notification that moves the donation away from the status read
+	// above acts on it. One that lost the race is rejected so the gateway
+	// sends it again, and it is then applied to the new status.
+	updated, err := s.repository.UpdateStatus(donation, previousStatus)
 
 	if err != nil {
 		return err
 	}
 
-	if updatedDonation.Status == "paid" {
-		campaign.BackerCount = campaign.BackerCount + 1
-		campaign.CurrentAmount = campaign.CurrentAmount + updatedDonation.Amount
+	if !updated {
+		return errors.New("Donation was changed by another notification")
+	}
 
-		_, err := s.campaignRepository.Update(campaign)
+	audit.RecordQuietly(s.auditService, audit.TargetDonation, input.Actor, audit.ActionDonationPayment, donation.ID, map[string]interface{}{
+		"from":               previousStatus,
+		"to":                 donation.Status,
+		"transaction_status": input.TransactionStatus,
+		"payment_type":       input.PaymentType,
+		"refund_required":    donation.RefundRequired,
+	})
+
+	// A cancelled or expired payment gives its reward back to the pool.
+	if donation.Status == "cancelled" && previousStatus != "cancelled" && donation.RewardID != nil {
+		err := s.campaignRepository.ReleaseReward(*donation.RewardID)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// A payment reversed after it was counted, e.g. denied after capture, no
+	// longer counts toward the campaign.
+	if donation.Status == "cancelled" && previousStatus == "paid" {
+		reopened, err := s.campaignRepository.RemoveDonation(donation.CampaignID, donation.Amount)
+
+		if err != nil {
+			return err
+		}
+
+		if reopened {
+			audit.RecordQuietly(s.auditService, audit.TargetCampaign, input.Actor, audit.ActionCampaignStatus, donation.CampaignID,
+				map[string]interface{}{"from": campaign.StatusGoalReached, "to": campaign.StatusActive, "donation_id": donation.ID})
+		}
+	}
+
+	if donation.Status == "paid" && previousStatus != "paid" {
+		err := s.campaignRepository.AddDonation(donation.CampaignID, donation.Amount)
 
 		if err != nil {
 			return err
@@ -138,6 +231,32 @@ func (s *service) ProcessPayment(input DonationNotificationInput) error {
 	return nil
 }
 
+// reserveReward holds one of the campaign's rewards for a new donation.
+func (s *service) reserveReward(campaign campaign.Campaign, rewardID int, amount int) error {
+	for _, reward := range campaign.Rewards {
+		if reward.ID != rewardID {
+			continue
+		}
+
+		if amount < reward.MinimumAmount {
+			return errors.New("Donation amount is below the reward minimum")
+		}
+
+		reserved, err := s.campaignRepository.ReserveReward(reward.ID)
+		if err != nil {
+			return err
+		}
+
+		if !reserved {
+			return errors.New("Reward is out of stock")
+		}
+
+		return nil
+	}
+
+	return errors.New("No reward found with that ID")
+}
+
 // func (s *service) GetAllTransactions() ([]Donation, error) {
 // 	donations, err := s.repository.FindAll()
 // 	if err != nil {
@@ -1,8 +1,14 @@
 package donation
 
 import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/audit/audittest"
+	"crowdfunding-minpro-alterra/modules/campaign"
+	"crowdfunding-minpro-alterra/modules/payment"
+	"crowdfunding-minpro-alterra/modules/user"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -50,9 +56,25 @@ func (m *MockRepository) Update(donation Donation) (Donation, error) {
 	return Donation{}, nil
 }
 
+// UpdateStatus saves through Update if the stored donation still has status
+// from, like the conditional update in the repository.
+func (m *MockRepository) UpdateStatus(donation Donation, from string) (bool, error) {
+	if m.GetByIDFunc != nil {
+		stored, err := m.GetByIDFunc(donation.ID)
+		if err != nil {
+			return false, err
+		}
+		if stored.Status != from || stored.RefundRequired {
+			return false, nil
+		}
+	}
+	_, err := m.Update(donation)
+	return err == nil, err
+}
+
 func TestService_GetDonationsByUserID(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo, nil, nil)
+	service := NewService(repo, nil, nil, nil)
 
 	t.Run("Test GetDonationsByUserID with valid user ID", func(t *testing.T) {
 		mockUserID := 1
@@ -87,3 +109,367 @@ func TestService_GetDonationsByUserID(t *testing.T) {
 	})
 }
 
+// MockCampaignRepository embeds the interface so only the methods the
+// donation service uses have to be implemented.
+type MockCampaignRepository struct {
+	campaign.Repository
+	Campaign campaign.Campaign
+}
+
+// ReserveReward and ReleaseReward work on the rewards of Campaign.
+func (m *MockCampaignRepository) ReserveReward(ID int) (bool, error) {
+	for i, reward := range m.Campaign.Rewards {
+		if reward.ID == ID && reward.IsAvailable() {
+			m.Campaign.Rewards[i].Claimed++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockCampaignRepository) ReleaseReward(ID int) error {
+	for i, reward := range m.Campaign.Rewards {
+		if reward.ID == ID && reward.Claimed > 0 {
+			m.Campaign.Rewards[i].Claimed--
+		}
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) FindByID(ID int) (campaign.Campaign, error) {
+	if ID != m.Campaign.ID {
+		return campaign.Campaign{}, nil
+	}
+	return m.Campaign, nil
+}
+
+func (m *MockCampaignRepository) AddDonation(campaignID int, amount int) error {
+	if campaignID == m.Campaign.ID {
+		m.Campaign.AddDonation(amount)
+	}
+	return nil
+}
+
+func (m *MockCampaignRepository) RemoveDonation(campaignID int, amount int) (bool, error) {
+	if campaignID == m.Campaign.ID {
+		m.Campaign.BackerCount--
+		m.Campaign.CurrentAmount -= amount
+		if m.Campaign.Status == campaign.StatusGoalReached && m.Campaign.CurrentAmount < m.Campaign.GoalAmount {
+			m.Campaign.Status = campaign.StatusActive
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type MockPaymentService struct {
+	Err error
+}
+
+func (m *MockPaymentService) GetPaymentURL(donation payment.Donation, user user.User) (string, error) {
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return "https://pay.example.com/1", nil
+}
+
+
+func TestService_CreateDonation(t *testing.T) {
+	repo := &MockRepository{}
+	auditService := &audittest.Service{}
+	campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusActive}}
+	service := NewService(repo, campaignRepo, &MockPaymentService{}, auditService)
+
+	repo.SaveFunc = func(donation Donation) (Donation, error) {
+		donation.ID = 5
+		return donation, nil
+	}
+	repo.UpdateFunc = func(donation Donation) (Donation, error) {
+		return donation, nil
+	}
+
+	donation, err := service.CreateDonation(CreateDonationInput{
+		Amount:     10000,
+		CampaignID: 2,
+		User:       user.User{ID: 1},
+		Actor:      audit.Actor{ID: 1, IPAddress: "10.0.0.1"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", donation.Status)
+	assert.Equal(t, "https://pay.example.com/1", donation.PaymentURL)
+
+	assert.Len(t, auditService.Records, 1)
+	assert.Equal(t, audit.ActionDonationCreate, auditService.Records[0].Action)
+	assert.Equal(t, 5, auditService.Records[0].TargetID)
+	assert.Equal(t, 10000, auditService.Records[0].Metadata["amount"])
+}
+
+func TestService_CreateDonation_CampaignNotActive(t *testing.T) {
+	repo := &MockRepository{}
+	past := time.Now().Add(-time.Hour)
+
+	for name, notActive := range map[string]campaign.Campaign{
+		"goal reached": {ID: 2, Status: campaign.StatusGoalReached},
+		"draft":        {ID: 2, Status: campaign.StatusDraft},
+		"past end":     {ID: 2, Status: campaign.StatusActive, EndDate: &past},
+	} {
+		t.Run(name, func(t *testing.T) {
+			service := NewService(repo, &MockCampaignRepository{Campaign: notActive}, &MockPaymentService{}, &audittest.Service{})
+
+			_, err := service.CreateDonation(CreateDonationInput{Amount: 10000, CampaignID: 2, User: user.User{ID: 1}})
+
+			assert.EqualError(t, err, "Campaign is not accepting donations")
+		})
+	}
+}
+
+func TestService_ProcessPayment_GoalReached(t *testing.T) {
+	repo := &MockRepository{}
+	campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusActive, GoalAmount: 10000, CurrentAmount: 5000}}
+	service := NewService(repo, campaignRepo, &MockPaymentService{}, &audittest.Service{})
+
+	pending := Donation{ID: 5, CampaignID: 2, Amount: 5000, Status: "pending"}
+
+	repo.GetByIDFunc = func(ID int) (Donation, error) {
+		return pending, nil
+	}
+	repo.UpdateFunc = func(donation Donation) (Donation, error) {
+		pending = donation
+		return donation, nil
+	}
+
+	notification := DonationNotificationInput{OrderID: "5", TransactionStatus: "settlement"}
+
+	err := service.ProcessPayment(notification)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10000, campaignRepo.Campaign.CurrentAmount)
+	assert.Equal(t, 1, campaignRepo.Campaign.BackerCount)
+	assert.Equal(t, campaign.StatusGoalReached, campaignRepo.Campaign.Status)
+
+	// A repeated notification must not count the donation twice.
+	err = service.ProcessPayment(notification)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10000, campaignRepo.Campaign.CurrentAmount)
+}
+
+func TestService_ProcessPayment_RefundsLatePayments(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		donation string
+	}{
+		{"cancelled donation", campaign.StatusActive, "cancelled"},
+		{"cancelled campaign", campaign.StatusCancelled, "pending"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockRepository{}
+			campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: tt.status, GoalAmount: 10000, CurrentAmount: 5000, Rewards: []campaign.Reward{{ID: 7, Quantity: 1, Claimed: 1}}}}
+			auditService := &audittest.Service{}
+			service := NewService(repo, campaignRepo, &MockPaymentService{}, auditService)
+
+			rewardID := 7
+			late := Donation{ID: 5, CampaignID: 2, Amount: 5000, RewardID: &rewardID, Status: tt.donation}
+
+			repo.GetByIDFunc = func(ID int) (Donation, error) {
+				return late, nil
+			}
+			repo.UpdateFunc = func(donation Donation) (Donation, error) {
+				late = donation
+				return donation, nil
+			}
+
+			err := service.ProcessPayment(DonationNotificationInput{OrderID: "5", TransactionStatus: "settlement"})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "cancelled", late.Status)
+			assert.True(t, late.RefundRequired)
+			assert.Equal(t, 5000, campaignRepo.Campaign.CurrentAmount)
+			assert.Equal(t, 0, campaignRepo.Campaign.BackerCount)
+			assert.Equal(t, true, auditService.Records[len(auditService.Records)-1].Metadata["refund_required"])
+
+			// The reward of a donation that was pending is given back once.
+			expectedClaimed := 1
+			if tt.donation == "pending" {
+				expectedClaimed = 0
+			}
+			assert.Equal(t, expectedClaimed, campaignRepo.Campaign.Rewards[0].Claimed)
+		})
+	}
+}
+
+func TestService_CreateDonation_Reward(t *testing.T) {
+	repo := &MockRepository{}
+	campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusActive, Rewards: []campaign.Reward{
+		{ID: 7, CampaignID: 2, Title: "T-shirt", MinimumAmount: 250000, Quantity: 1},
+	}}}
+	service := NewService(repo, campaignRepo, &MockPaymentService{}, &audittest.Service{})
+
+	repo.SaveFunc = func(donation Donation) (Donation, error) {
+		donation.ID = 5
+		return donation, nil
+	}
+	repo.UpdateFunc = func(donation Donation) (Donation, error) {
+		return donation, nil
+	}
+
+	_, err := service.CreateDonation(CreateDonationInput{Amount: 100000, CampaignID: 2, RewardID: 7, User: user.User{ID: 1}})
+	assert.EqualError(t, err, "Donation amount is below the reward minimum")
+
+	_, err = service.CreateDonation(CreateDonationInput{Amount: 250000, CampaignID: 2, RewardID: 8, User: user.User{ID: 1}})
+	assert.EqualError(t, err, "No reward found with that ID")
+
+	donation, err := service.CreateDonation(CreateDonationInput{Amount: 250000, CampaignID: 2, RewardID: 7, User: user.User{ID: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, *donation.RewardID)
+	assert.Equal(t, 1, campaignRepo.Campaign.Rewards[0].Claimed)
+
+	_, err = service.CreateDonation(CreateDonationInput{Amount: 250000, CampaignID: 2, RewardID: 7, User: user.User{ID: 3}})
+	assert.EqualError(t, err, "Reward is out of stock")
+}
+
+func TestService_ProcessPayment_ReleasesReward(t *testing.T) {
+	repo := &MockRepository{}
+	campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusActive, Rewards: []campaign.Reward{
+		{ID: 7, CampaignID: 2, MinimumAmount: 250000, Quantity: 1, Claimed: 1},
+	}}}
+	service := NewService(repo, campaignRepo, &MockPaymentService{}, &audittest.Service{})
+
+	rewardID := 7
+	pending := Donation{ID: 5, CampaignID: 2, Amount: 250000, Status: "pending", RewardID: &rewardID}
+
+	repo.GetByIDFunc = func(ID int) (Donation, error) {
+		return pending, nil
+	}
+	repo.UpdateFunc = func(donation Donation) (Donation, error) {
+		pending = donation
+		return donation, nil
+	}
+
+	notification := DonationNotificationInput{OrderID: "5", TransactionStatus: "expire"}
+
+	err := service.ProcessPayment(notification)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cancelled", pending.Status)
+	assert.Equal(t, 0, campaignRepo.Campaign.Rewards[0].Claimed)
+	assert.True(t, campaignRepo.Campaign.Rewards[0].IsAvailable())
+
+	// The reward is released once, however often the notification arrives.
+	campaignRepo.Campaign.Rewards[0].Claimed = 1
+
+	err = service.ProcessPayment(notification)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, campaignRepo.Campaign.Rewards[0].Claimed)
+}
+
+func TestService_CreateDonation_PaymentURLFails(t *testing.T) {
+	repo := &MockRepository{}
+	campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusActive, Rewards: []campaign.Reward{
+		{ID: 7, CampaignID: 2, MinimumAmount: 250000, Quantity: 1},
+	}}}
+	service := NewService(repo, campaignRepo, &MockPaymentService{Err: errors.New("gateway timeout")}, &audittest.Service{})
+
+	var stored Donation
+
+	repo.SaveFunc = func(donation Donation) (Donation, error) {
+		donation.ID = 5
+		stored = donation
+		return donation, nil
+	}
+	repo.GetByIDFunc = func(ID int) (Donation, error) {
+		return stored, nil
+	}
+	repo.UpdateFunc = func(donation Donation) (Donation, error) {
+		stored = donation
+		return donation, nil
+	}
+
+	_, err := service.CreateDonation(CreateDonationInput{Amount: 250000, CampaignID: 2, RewardID: 7, User: user.User{ID: 1}})
+
+	assert.EqualError(t, err, "gateway timeout")
+	assert.Equal(t, "cancelled", stored.Status)
+	assert.Equal(t, 0, campaignRepo.Campaign.Rewards[0].Claimed)
+}
+
+func TestService_ProcessPayment_DenyAfterPaid(t *testing.T) {
+	repo := &MockRepository{}
+	campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusActive, GoalAmount: 10000, CurrentAmount: 5000}}
+	auditService := &audittest.Service{}
+	service := NewService(repo, campaignRepo, &MockPaymentService{}, auditService)
+
+	pending := Donation{ID: 5, CampaignID: 2, Amount: 5000, Status: "pending"}
+
+	repo.GetByIDFunc = func(ID int) (Donation, error) {
+		return pending, nil
+	}
+	repo.UpdateFunc = func(donation Donation) (Donation, error) {
+		pending = donation
+		return donation, nil
+	}
+
+	err := service.ProcessPayment(DonationNotificationInput{OrderID: "5", PaymentType: "credit_card", TransactionStatus: "capture", FraudStatus: "accept"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10000, campaignRepo.Campaign.CurrentAmount)
+	assert.Equal(t, campaign.StatusGoalReached, campaignRepo.Campaign.Status)
+
+	err = service.ProcessPayment(DonationNotificationInput{OrderID: "5", PaymentType: "credit_card", TransactionStatus: "deny"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cancelled", pending.Status)
+	assert.Equal(t, 5000, campaignRepo.Campaign.CurrentAmount)
+	assert.Equal(t, 0, campaignRepo.Campaign.BackerCount)
+	assert.Equal(t, campaign.StatusActive, campaignRepo.Campaign.Status)
+
+	reopened := auditService.Records[len(auditService.Records)-1]
+	assert.Equal(t, audit.ActionCampaignStatus, reopened.Action)
+	assert.Equal(t, audit.TargetCampaign, reopened.TargetType)
+	assert.Equal(t, 2, reopened.TargetID)
+	assert.Equal(t, campaign.StatusActive, reopened.Metadata["to"])
+}
+
+func TestService_ProcessPayment_ConcurrentNotification(t *testing.T) {
+	repo := &MockRepository{}
+	campaignRepo := &MockCampaignRepository{Campaign: campaign.Campaign{ID: 2, Status: campaign.StatusActive, GoalAmount: 10000}}
+	auditService := &audittest.Service{}
+	service := NewService(repo, campaignRepo, &MockPaymentService{}, auditService)
+
+	stored := Donation{ID: 5, CampaignID: 2, Amount: 5000, Status: "pending"}
+	reads := 0
+
+	// The first read sees the donation still pending, but another delivery of
+	// the same notification has marked it paid before this one writes.
+	repo.GetByIDFunc = func(ID int) (Donation, error) {
+		reads++
+		if reads == 1 {
+			paid := stored
+			stored.Status = "paid"
+			campaignRepo.Campaign.AddDonation(stored.Amount)
+			return paid, nil
+		}
+		return stored, nil
+	}
+	repo.UpdateFunc = func(donation Donation) (Donation, error) {
+		stored = donation
+		return donation, nil
+	}
+
+	err := service.ProcessPayment(DonationNotificationInput{OrderID: "5", TransactionStatus: "settlement"})
+
+	assert.EqualError(t, err, "Donation was changed by another notification")
+	assert.Equal(t, 5000, campaignRepo.Campaign.CurrentAmount)
+	assert.Empty(t, auditService.Records)
+
+	// The gateway retries; by now the donation is paid and nothing changes.
+	err = service.ProcessPayment(DonationNotificationInput{OrderID: "5", TransactionStatus: "settlement"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5000, campaignRepo.Campaign.CurrentAmount)
+	assert.Equal(t, 1, campaignRepo.Campaign.BackerCount)
+}
@@ -0,0 +1,70 @@
+package loginguard
+
+import (
+	"fmt"
+	"time"
+)
+
+// Attempt is the failure counter kept for one email address or client IP.
+type Attempt struct {
+	Key           string     `gorm:"column:attempt_key;primaryKey;type:varchar(191)"`
+	Failures      int        `gorm:"column:failures"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+}
+
+func (Attempt) TableName() string {
+	return "login_attempts"
+}
+
+// FailedLogin is the audit record written for every rejected login.
+type FailedLogin struct {
+	ID        int       `gorm:"column:id;primaryKey;autoIncrement"`
+	Email     string    `gorm:"column:email;index"`
+	IPAddress string    `gorm:"column:ip_address"`
+	UserAgent string    `gorm:"column:user_agent"`
+	Reason    string    `gorm:"column:reason"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+// Policy describes how quickly a key is slowed down and locked.
+type Policy struct {
+	// FreeAttempts is the number of failures allowed before any delay.
+	FreeAttempts int
+	// BaseDelay doubles for every failure past FreeAttempts, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAfter failures lock the key for LockoutDuration.
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// Failures older than ResetAfter are forgotten.
+	ResetAfter time.Duration
+}
+
+var EmailPolicy = Policy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutAfter:    10,
+	LockoutDuration: 15 * time.Minute,
+	ResetAfter:      time.Hour,
+}
+
+// IPPolicy is looser than EmailPolicy because many users can share one
+// address behind a NAT.
+var IPPolicy = Policy{
+	FreeAttempts:    10,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutAfter:    50,
+	LockoutDuration: time.Hour,
+	ResetAfter:      time.Hour,
+}
+
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("Too many failed login attempts. Try again in %d seconds.", int(e.RetryAfter.Seconds()+0.5))
+}
@@ -0,0 +1,34 @@
+package loginguard
+
+import "time"
+
+type FailedLoginFormatter struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func FormatFailedLogin(failedLogin FailedLogin) FailedLoginFormatter {
+	formatter := FailedLoginFormatter{}
+	formatter.ID = failedLogin.ID
+	formatter.Email = failedLogin.Email
+	formatter.IPAddress = failedLogin.IPAddress
+	formatter.UserAgent = failedLogin.UserAgent
+	formatter.Reason = failedLogin.Reason
+	formatter.CreatedAt = failedLogin.CreatedAt
+
+	return formatter
+}
+
+func FormatFailedLogins(failedLogins []FailedLogin) []FailedLoginFormatter {
+	failedLoginsFormatter := []FailedLoginFormatter{}
+
+	for _, failedLogin := range failedLogins {
+		failedLoginsFormatter = append(failedLoginsFormatter, FormatFailedLogin(failedLogin))
+	}
+
+	return failedLoginsFormatter
+}
@@ -0,0 +1,13 @@
+package loginguard
+
+type FailedLoginInput struct {
+	Email     string
+	IPAddress string
+	UserAgent string
+	Reason    string
+}
+
+type GetFailedLoginsInput struct {
+	Email string `form:"email"`
+	Limit int    `form:"limit"`
+}
@@ -0,0 +1,42 @@
+package loginguard
+
+import "gorm.io/gorm"
+
+type Repository interface {
+	SaveFailedLogin(failedLogin FailedLogin) (FailedLogin, error)
+	FindFailedLogins(email string, limit int) ([]FailedLogin, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+func (r *repository) SaveFailedLogin(failedLogin FailedLogin) (FailedLogin, error) {
+	err := r.db.Create(&failedLogin).Error
+	if err != nil {
+		return failedLogin, err
+	}
+
+	return failedLogin, nil
+}
+
+func (r *repository) FindFailedLogins(email string, limit int) ([]FailedLogin, error) {
+	var failedLogins []FailedLogin
+
+	query := r.db.Order("created_at desc").Limit(limit)
+
+	if email != "" {
+		query = query.Where("email = ?", email)
+	}
+
+	err := query.Find(&failedLogins).Error
+	if err != nil {
+		return failedLogins, err
+	}
+
+	return failedLogins, nil
+}
@@ -0,0 +1,160 @@
+package loginguard
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Store keeps the failure counters. The memory store is enough for a single
+// instance; deployments running several instances must share a store so an
+// attacker cannot spread attempts across them.
+type Store interface {
+	Get(key string) (Attempt, error)
+	// Increment counts another failure for key in one step, starting over
+	// when the previous one is older than resetAfter, and returns the count.
+	Increment(key string, now time.Time, resetAfter time.Duration) (int, error)
+	// Lock locks key until at least until; a longer lock is kept.
+	Lock(key string, until time.Time) error
+	Delete(key string) error
+}
+
+type memoryStore struct {
+	mutex    sync.Mutex
+	attempts map[string]Attempt
+	ttl      time.Duration
+	writes   int
+}
+
+// NewMemoryStore keeps counters in process memory. Entries idle for longer
+// than ttl are swept periodically so the map cannot grow without bound.
+func NewMemoryStore(ttl time.Duration) *memoryStore {
+	return &memoryStore{attempts: map[string]Attempt{}, ttl: ttl}
+}
+
+func (s *memoryStore) Get(key string) (Attempt, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return Attempt{Key: key}, nil
+	}
+
+	return attempt, nil
+}
+
+func (s *memoryStore) Increment(key string, now time.Time, resetAfter time.Duration) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt := s.attempts[key]
+
+	if now.Sub(attempt.LastFailureAt) > resetAfter {
+		attempt.Failures = 0
+	}
+
+	attempt.Key = key
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+	s.writes++
+
+	if s.writes%1000 == 0 {
+		s.sweep(now)
+	}
+
+	return attempt.Failures, nil
+}
+
+func (s *memoryStore) Lock(key string, until time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = Attempt{Key: key}
+	}
+
+	if attempt.LockedUntil == nil || attempt.LockedUntil.Before(until) {
+		attempt.LockedUntil = &until
+		s.attempts[key] = attempt
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.attempts, key)
+
+	return nil
+}
+
+func (s *memoryStore) sweep(now time.Time) {
+	for key, attempt := range s.attempts {
+		if now.Sub(attempt.LastFailureAt) > s.ttl && (attempt.LockedUntil == nil || now.After(*attempt.LockedUntil)) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+type databaseStore struct {
+	db *gorm.DB
+}
+
+// NewDatabaseStore keeps counters in the login_attempts table so that every
+// instance connected to the same database sees the same state.
+func NewDatabaseStore(db *gorm.DB) *databaseStore {
+	return &databaseStore{db}
+}
+
+func (s *databaseStore) Get(key string) (Attempt, error) {
+	var attempt Attempt
+
+	err := s.db.Where("attempt_key = ?", key).Find(&attempt).Error
+	if err != nil {
+		return attempt, err
+	}
+
+	attempt.Key = key
+
+	return attempt, nil
+}
+
+// Increment relies on MySQL applying the assignments in order, so failures
+// is compared against the previous last_failure_at.
+func (s *databaseStore) Increment(key string, now time.Time, resetAfter time.Duration) (int, error) {
+	var attempt Attempt
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("IF(last_failure_at < ?, 1, failures + 1)", now.Add(-resetAfter))},
+			{Column: clause.Column{Name: "last_failure_at"}, Value: now},
+		}}).Create(&Attempt{Key: key, Failures: 1, LastFailureAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("attempt_key = ?", key).Find(&attempt).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return attempt.Failures, nil
+}
+
+func (s *databaseStore) Lock(key string, until time.Time) error {
+	return s.db.Model(&Attempt{}).
+		Where("attempt_key = ? AND (locked_until IS NULL OR locked_until < ?)", key, until).
+		Update("locked_until", until).Error
+}
+
+func (s *databaseStore) Delete(key string) error {
+	return s.db.Delete(&Attempt{}, "attempt_key = ?", key).Error
+}
@@ -0,0 +1,157 @@
+package loginguard
+
+import (
+	"strings"
+	"time"
+)
+
+type Service interface {
+	Check(email string, ipAddress string) error
+	RecordFailure(input FailedLoginInput) error
+	RecordSuccess(email string) error
+	Unlock(email string) error
+	GetFailedLogins(input GetFailedLoginsInput) ([]FailedLogin, error)
+}
+
+type service struct {
+	repository Repository
+	store      Store
+	now        func() time.Time
+}
+
+func NewService(repository Repository, store Store) *service {
+	return &service{repository, store, time.Now}
+}
+
+// Check returns a *LockedError when either the email or the client IP is
+// still backing off from earlier failures. It must be called before the
+// password is verified so a locked account gives no hint about the password.
+func (s *service) Check(email string, ipAddress string) error {
+	now := s.now()
+	retryAfter := time.Duration(0)
+
+	for _, key := range keys(email, ipAddress) {
+		attempt, err := s.store.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) && attempt.LockedUntil.Sub(now) > retryAfter {
+			retryAfter = attempt.LockedUntil.Sub(now)
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (s *service) RecordFailure(input FailedLoginInput) error {
+	failedLogin := FailedLogin{}
+	failedLogin.Email = normalizeEmail(input.Email)
+	failedLogin.IPAddress = input.IPAddress
+	failedLogin.UserAgent = input.UserAgent
+	failedLogin.Reason = input.Reason
+
+	_, err := s.repository.SaveFailedLogin(failedLogin)
+	if err != nil {
+		return err
+	}
+
+	err = s.fail(emailKey(input.Email), EmailPolicy)
+	if err != nil {
+		return err
+	}
+
+	if input.IPAddress == "" {
+		return nil
+	}
+
+	return s.fail(ipKey(input.IPAddress), IPPolicy)
+}
+
+// RecordSuccess clears the email counter. The IP counter is kept so that a
+// single valid account cannot be used to reset a password spraying run.
+func (s *service) RecordSuccess(email string) error {
+	return s.store.Delete(emailKey(email))
+}
+
+func (s *service) Unlock(email string) error {
+	return s.store.Delete(emailKey(email))
+}
+
+func (s *service) GetFailedLogins(input GetFailedLoginsInput) ([]FailedLogin, error) {
+	limit := input.Limit
+
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	failedLogins, err := s.repository.FindFailedLogins(normalizeEmail(input.Email), limit)
+	if err != nil {
+		return failedLogins, err
+	}
+
+	return failedLogins, nil
+}
+
+// fail counts the failure for key and locks it once the policy says so.
+// The count is incremented in the store, so concurrent failures are all
+// counted.
+func (s *service) fail(key string, policy Policy) error {
+	now := s.now()
+
+	failures, err := s.store.Increment(key, now, policy.ResetAfter)
+	if err != nil {
+		return err
+	}
+
+	if failures >= policy.LockoutAfter {
+		return s.store.Lock(key, now.Add(policy.LockoutDuration))
+	}
+
+	if failures > policy.FreeAttempts {
+		return s.store.Lock(key, now.Add(backoff(policy, failures-policy.FreeAttempts)))
+	}
+
+	return nil
+}
+
+// backoff returns BaseDelay * 2^(step-1), capped at MaxDelay.
+func backoff(policy Policy, step int) time.Duration {
+	delay := policy.BaseDelay
+
+	for i := 1; i < step; i++ {
+		delay *= 2
+
+		if delay >= policy.MaxDelay {
+			return policy.MaxDelay
+		}
+	}
+
+	return delay
+}
+
+func keys(email string, ipAddress string) []string {
+	keys := []string{emailKey(email)}
+
+	if ipAddress != "" {
+		keys = append(keys, ipKey(ipAddress))
+	}
+
+	return keys
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func emailKey(email string) string {
+	return "email:" + normalizeEmail(email)
+}
+
+func ipKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
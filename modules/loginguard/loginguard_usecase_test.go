@@ -0,0 +1,161 @@
+package loginguard
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRepository struct {
+	FailedLogins []FailedLogin
+}
+
+func (m *MockRepository) SaveFailedLogin(failedLogin FailedLogin) (FailedLogin, error) {
+	m.FailedLogins = append(m.FailedLogins, failedLogin)
+	return failedLogin, nil
+}
+
+func (m *MockRepository) FindFailedLogins(email string, limit int) ([]FailedLogin, error) {
+	return m.FailedLogins, nil
+}
+
+func newTestService(now *time.Time) (*service, *MockRepository) {
+	repo := &MockRepository{}
+	service := NewService(repo, NewMemoryStore(time.Hour))
+	service.now = func() time.Time { return *now }
+
+	return service, repo
+}
+
+func failLogin(service *service, email string, ipAddress string) {
+	service.RecordFailure(FailedLoginInput{Email: email, IPAddress: ipAddress, Reason: "invalid password"})
+}
+
+func TestCheck_FreeAttempts(t *testing.T) {
+	now := time.Now()
+	service, repo := newTestService(&now)
+
+	for i := 0; i < EmailPolicy.FreeAttempts; i++ {
+		failLogin(service, "john@example.com", "10.0.0.1")
+	}
+
+	assert.NoError(t, service.Check("john@example.com", "10.0.0.1"))
+	assert.Len(t, repo.FailedLogins, EmailPolicy.FreeAttempts)
+}
+
+func TestCheck_ExponentialBackoff(t *testing.T) {
+	now := time.Now()
+	service, _ := newTestService(&now)
+
+	for i := 0; i < EmailPolicy.FreeAttempts+1; i++ {
+		failLogin(service, "john@example.com", "10.0.0.1")
+	}
+
+	err := service.Check("JOHN@example.com ", "10.0.0.2")
+
+	var lockedError *LockedError
+	assert.True(t, errors.As(err, &lockedError))
+	assert.Equal(t, EmailPolicy.BaseDelay, lockedError.RetryAfter)
+
+	now = now.Add(EmailPolicy.BaseDelay)
+	assert.NoError(t, service.Check("john@example.com", "10.0.0.2"))
+
+	failLogin(service, "john@example.com", "10.0.0.1")
+
+	err = service.Check("john@example.com", "10.0.0.2")
+	assert.True(t, errors.As(err, &lockedError))
+	assert.Equal(t, 2*EmailPolicy.BaseDelay, lockedError.RetryAfter)
+}
+
+func TestCheck_Lockout(t *testing.T) {
+	now := time.Now()
+	service, _ := newTestService(&now)
+
+	for i := 0; i < EmailPolicy.LockoutAfter; i++ {
+		failLogin(service, "john@example.com", "")
+	}
+
+	err := service.Check("john@example.com", "")
+
+	var lockedError *LockedError
+	assert.True(t, errors.As(err, &lockedError))
+	assert.Equal(t, EmailPolicy.LockoutDuration, lockedError.RetryAfter)
+
+	assert.NoError(t, service.Unlock("john@example.com"))
+	assert.NoError(t, service.Check("john@example.com", ""))
+}
+
+func TestCheck_PerIP(t *testing.T) {
+	now := time.Now()
+	service, _ := newTestService(&now)
+
+	for i := 0; i < IPPolicy.FreeAttempts+1; i++ {
+		failLogin(service, "user"+string(rune('a'+i))+"@example.com", "10.0.0.1")
+	}
+
+	assert.Error(t, service.Check("new@example.com", "10.0.0.1"))
+	assert.NoError(t, service.Check("new@example.com", "10.0.0.2"))
+}
+
+func TestRecordSuccess(t *testing.T) {
+	now := time.Now()
+	service, _ := newTestService(&now)
+
+	for i := 0; i < EmailPolicy.FreeAttempts; i++ {
+		failLogin(service, "john@example.com", "")
+	}
+
+	assert.NoError(t, service.RecordSuccess("john@example.com"))
+
+	failLogin(service, "john@example.com", "")
+
+	assert.NoError(t, service.Check("john@example.com", ""))
+}
+
+func TestRecordFailure_ResetAfter(t *testing.T) {
+	now := time.Now()
+	service, _ := newTestService(&now)
+
+	for i := 0; i < EmailPolicy.FreeAttempts; i++ {
+		failLogin(service, "john@example.com", "")
+	}
+
+	now = now.Add(EmailPolicy.ResetAfter + time.Minute)
+	failLogin(service, "john@example.com", "")
+
+	assert.NoError(t, service.Check("john@example.com", ""))
+}
+
+func TestMemoryStore_ConcurrentIncrement(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.Increment("email:john@example.com", now, time.Hour)
+		}()
+	}
+	wg.Wait()
+
+	attempt, err := store.Get("email:john@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 50, attempt.Failures)
+}
+
+func TestMemoryStore_LockKeepsLongerLock(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	now := time.Now()
+
+	assert.NoError(t, store.Lock("email:john@example.com", now.Add(time.Hour)))
+	assert.NoError(t, store.Lock("email:john@example.com", now.Add(time.Minute)))
+
+	attempt, err := store.Get("email:john@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), *attempt.LockedUntil)
+}
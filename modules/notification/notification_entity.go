@@ -0,0 +1,25 @@
+package notification
+
+import "time"
+
+const (
+	TypeCampaignUpdate    = "campaign_update"
+	TypeCampaignCancelled = "campaign_cancelled"
+)
+
+// Notification is an in-app message for one user. Fan-out writes one row
+// per recipient so each of them can mark it read on their own.
+type Notification struct {
+	ID         int        `gorm:"column:id;primaryKey"`
+	UserID     int        `gorm:"column:user_id;index"`
+	Type       string     `gorm:"column:type;type:varchar(32)"`
+	Title      string     `gorm:"column:title;type:varchar(255)"`
+	Body       string     `gorm:"column:body;type:TEXT"`
+	CampaignID int        `gorm:"column:campaign_id"`
+	ReadAt     *time.Time `gorm:"column:read_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;index"`
+}
+
+func (n Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
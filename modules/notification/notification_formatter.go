@@ -0,0 +1,36 @@
+package notification
+
+import "time"
+
+type NotificationFormatter struct {
+	ID         int        `json:"id"`
+	Type       string     `json:"type"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	CampaignID int        `json:"campaign_id"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func FormatNotification(notification Notification) NotificationFormatter {
+	formatter := NotificationFormatter{}
+	formatter.ID = notification.ID
+	formatter.Type = notification.Type
+	formatter.Title = notification.Title
+	formatter.Body = notification.Body
+	formatter.CampaignID = notification.CampaignID
+	formatter.ReadAt = notification.ReadAt
+	formatter.CreatedAt = notification.CreatedAt
+
+	return formatter
+}
+
+func FormatNotifications(notifications []Notification) []NotificationFormatter {
+	formatters := []NotificationFormatter{}
+
+	for _, notification := range notifications {
+		formatters = append(formatters, FormatNotification(notification))
+	}
+
+	return formatters
+}
@@ -0,0 +1,13 @@
+package notification
+
+type GetNotificationsInput struct {
+	Page   int  `form:"page" binding:"omitempty,min=1"`
+	Limit  int  `form:"limit" binding:"omitempty,min=1,max=100"`
+	Unread bool `form:"unread"`
+	UserID int  `form:"-"`
+}
+
+type MarkReadInput struct {
+	ID     int `uri:"id" binding:"required"`
+	UserID int
+}
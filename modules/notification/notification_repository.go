@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// saveBatchSize bounds the rows inserted per statement when fanning out.
+const saveBatchSize = 500
+
+type Repository interface {
+	SaveAll(notifications []Notification) error
+	Search(input GetNotificationsInput) ([]Notification, int64, error)
+	FindByID(ID int) (Notification, error)
+	MarkRead(ID int, readAt time.Time) error
+	MarkAllRead(userID int, readAt time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+func (r *repository) SaveAll(notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	return r.db.CreateInBatches(&notifications, saveBatchSize).Error
+}
+
+func (r *repository) Search(input GetNotificationsInput) ([]Notification, int64, error) {
+	var notifications []Notification
+	var total int64
+
+	query := r.db.Model(&Notification{}).Where("user_id = ?", input.UserID)
+
+	if input.Unread {
+		query = query.Where("read_at IS NULL")
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return notifications, total, err
+	}
+
+	err = query.Order("created_at desc, id desc").Offset((input.Page - 1) * input.Limit).Limit(input.Limit).Find(&notifications).Error
+	if err != nil {
+		return notifications, total, err
+	}
+
+	return notifications, total, nil
+}
+
+func (r *repository) FindByID(ID int) (Notification, error) {
+	var notification Notification
+
+	err := r.db.Where("id = ?", ID).Find(&notification).Error
+	if err != nil {
+		return notification, err
+	}
+
+	return notification, nil
+}
+
+func (r *repository) MarkRead(ID int, readAt time.Time) error {
+	return r.db.Model(&Notification{}).Where("id = ? AND read_at IS NULL", ID).Update("read_at", readAt).Error
+}
+
+func (r *repository) MarkAllRead(userID int, readAt time.Time) error {
+	return r.db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", readAt).Error
+}
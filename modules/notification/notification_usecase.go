@@ -0,0 +1,95 @@
+package notification
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultNotificationsPageLimit = 20
+
+type Service interface {
+	Notify(userIDs []int, notification Notification) error
+	GetNotifications(input GetNotificationsInput) ([]Notification, int64, GetNotificationsInput, error)
+	MarkRead(input MarkReadInput) (Notification, error)
+	MarkAllRead(userID int) error
+}
+
+type service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *service {
+	return &service{repository}
+}
+
+// Notify sends a copy of notification to every user in userIDs. Duplicate
+// IDs get a single copy.
+func (s *service) Notify(userIDs []int, notification Notification) error {
+	notifications := []Notification{}
+	seen := map[int]bool{}
+
+	for _, userID := range userIDs {
+		if userID == 0 || seen[userID] {
+			continue
+		}
+
+		seen[userID] = true
+
+		message := notification
+		message.ID = 0
+		message.UserID = userID
+		notifications = append(notifications, message)
+	}
+
+	return s.repository.SaveAll(notifications)
+}
+
+// GetNotifications returns one page of the user's notifications, newest
+// first, along with the input after defaults were applied.
+func (s *service) GetNotifications(input GetNotificationsInput) ([]Notification, int64, GetNotificationsInput, error) {
+	if input.Page == 0 {
+		input.Page = 1
+	}
+
+	if input.Limit == 0 {
+		input.Limit = defaultNotificationsPageLimit
+	}
+
+	notifications, total, err := s.repository.Search(input)
+	if err != nil {
+		return notifications, total, input, err
+	}
+
+	return notifications, total, input, nil
+}
+
+func (s *service) MarkRead(input MarkReadInput) (Notification, error) {
+	notification, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return notification, err
+	}
+
+	// Someone else's notification is reported as missing, not forbidden.
+	if notification.ID == 0 || notification.UserID != input.UserID {
+		return Notification{}, errors.New("No notification found with that ID")
+	}
+
+	if notification.IsRead() {
+		return notification, nil
+	}
+
+	readAt := time.Now()
+
+	err = s.repository.MarkRead(notification.ID, readAt)
+	if err != nil {
+		return notification, err
+	}
+
+	notification.ReadAt = &readAt
+
+	return notification, nil
+}
+
+func (s *service) MarkAllRead(userID int) error {
+	return s.repository.MarkAllRead(userID, time.Now())
+}
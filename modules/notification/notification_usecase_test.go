@@ -0,0 +1,84 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRepository struct {
+	Saved       []Notification
+	Stored      map[int]Notification
+	MarkedRead  []int
+	SearchInput GetNotificationsInput
+}
+
+func (m *MockRepository) SaveAll(notifications []Notification) error {
+	m.Saved = append(m.Saved, notifications...)
+	return nil
+}
+
+func (m *MockRepository) Search(input GetNotificationsInput) ([]Notification, int64, error) {
+	m.SearchInput = input
+	return []Notification{}, 0, nil
+}
+
+func (m *MockRepository) FindByID(ID int) (Notification, error) {
+	return m.Stored[ID], nil
+}
+
+func (m *MockRepository) MarkRead(ID int, readAt time.Time) error {
+	m.MarkedRead = append(m.MarkedRead, ID)
+	return nil
+}
+
+func (m *MockRepository) MarkAllRead(userID int, readAt time.Time) error {
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	err := service.Notify([]int{2, 3, 2, 0, 4}, Notification{ID: 9, Type: TypeCampaignUpdate, Title: "News", CampaignID: 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, repo.Saved, 3)
+	assert.Equal(t, 2, repo.Saved[0].UserID)
+	assert.Equal(t, 4, repo.Saved[2].UserID)
+	assert.Equal(t, 0, repo.Saved[1].ID)
+	assert.Equal(t, "News", repo.Saved[1].Title)
+}
+
+func TestGetNotifications(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo)
+
+	_, _, input, err := service.GetNotifications(GetNotificationsInput{UserID: 2, Unread: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, input.Page)
+	assert.Equal(t, defaultNotificationsPageLimit, input.Limit)
+	assert.Equal(t, 2, repo.SearchInput.UserID)
+}
+
+func TestMarkRead(t *testing.T) {
+	readAt := time.Now().Add(-time.Hour)
+	repo := &MockRepository{Stored: map[int]Notification{
+		1: {ID: 1, UserID: 2},
+		2: {ID: 2, UserID: 2, ReadAt: &readAt},
+	}}
+	service := NewService(repo)
+
+	_, err := service.MarkRead(MarkReadInput{ID: 1, UserID: 3})
+	assert.EqualError(t, err, "No notification found with that ID")
+
+	readNotification, err := service.MarkRead(MarkReadInput{ID: 1, UserID: 2})
+	assert.NoError(t, err)
+	assert.True(t, readNotification.IsRead())
+
+	_, err = service.MarkRead(MarkReadInput{ID: 2, UserID: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, repo.MarkedRead)
+}
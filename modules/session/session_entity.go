@@ -0,0 +1,37 @@
+package session
+
+import "time"
+
+// Session is one login on one device. Its ID is the FamilyID of the refresh
+// tokens issued for it and the "sid" claim of its access tokens, so revoking
+// the session invalidates both.
+type Session struct {
+	ID         string     `gorm:"column:id;type:varchar(64);primaryKey"`
+	UserID     int        `gorm:"column:user_id;index"`
+	UserAgent  string     `gorm:"column:user_agent"`
+	IPAddress  string     `gorm:"column:ip_address;type:varchar(45)"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at"`
+}
+
+func (s Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is a long-lived, single-use credential exchanged for a new
+// access token. Every token issued from one login shares a FamilyID so that
+// the whole chain can be revoked at once.
+type RefreshToken struct {
+	ID        int        `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    int        `gorm:"column:user_id;index"`
+	FamilyID  string     `gorm:"column:family_id;type:varchar(64);index"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at"`
+}
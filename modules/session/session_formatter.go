@@ -0,0 +1,50 @@
+package session
+
+import "time"
+
+type TokenFormatter struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+}
+
+func FormatToken(accessToken string, refreshToken string) TokenFormatter {
+	formatter := TokenFormatter{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+	}
+
+	return formatter
+}
+
+type SessionFormatter struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+func FormatSession(session Session, currentSessionID string) SessionFormatter {
+	formatter := SessionFormatter{}
+	formatter.ID = session.ID
+	formatter.UserAgent = session.UserAgent
+	formatter.IPAddress = session.IPAddress
+	formatter.CreatedAt = session.CreatedAt
+	formatter.LastSeenAt = session.LastSeenAt
+	formatter.Current = session.ID == currentSessionID
+
+	return formatter
+}
+
+func FormatSessions(sessions []Session, currentSessionID string) []SessionFormatter {
+	formatters := []SessionFormatter{}
+
+	for _, session := range sessions {
+		formatters = append(formatters, FormatSession(session, currentSessionID))
+	}
+
+	return formatters
+}
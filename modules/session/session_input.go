@@ -0,0 +1,16 @@
+package session
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type CreateSessionInput struct {
+	UserID    int
+	UserAgent string
+	IPAddress string
+}
+
+type RevokeSessionInput struct {
+	ID     string `uri:"id" binding:"required"`
+	UserID int
+}
@@ -0,0 +1,167 @@
+package session
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Save(refreshToken RefreshToken) (RefreshToken, error)
+	FindByTokenHash(tokenHash string) (RefreshToken, error)
+	Update(refreshToken RefreshToken) (RefreshToken, error)
+	MarkAsUsed(ID int) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeByUserID(userID int) error
+	SaveSession(session Session) (Session, error)
+	FindSessionByID(ID string) (Session, error)
+	FindActiveSessionsByUserID(userID int) ([]Session, error)
+	ExtendSession(ID string, expiresAt time.Time, lastSeenAt time.Time) (bool, error)
+	TouchSession(ID string, lastSeenAt time.Time) error
+	RevokeSessions(userID int, IDs []string, exceptID string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+func (r *repository) Save(refreshToken RefreshToken) (RefreshToken, error) {
+	err := r.db.Create(&refreshToken).Error
+	if err != nil {
+		return refreshToken, err
+	}
+
+	return refreshToken, nil
+}
+
+func (r *repository) FindByTokenHash(tokenHash string) (RefreshToken, error) {
+	var refreshToken RefreshToken
+
+	err := r.db.Where("token_hash = ?", tokenHash).Find(&refreshToken).Error
+	if err != nil {
+		return refreshToken, err
+	}
+
+	return refreshToken, nil
+}
+
+func (r *repository) Update(refreshToken RefreshToken) (RefreshToken, error) {
+	err := r.db.Save(&refreshToken).Error
+	if err != nil {
+		return refreshToken, err
+	}
+
+	return refreshToken, nil
+}
+
+// MarkAsUsed flags the token as consumed only if nobody consumed it first, so
+// two concurrent refreshes with the same token cannot both succeed.
+func (r *repository) MarkAsUsed(ID int) (bool, error) {
+	result := r.db.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", ID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes every refresh token of the family along with the
+// session it belongs to.
+func (r *repository) RevokeFamily(familyID string) error {
+	now := time.Now()
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", familyID).Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Session{}).Where("id = ? AND revoked_at IS NULL", familyID).Update("revoked_at", now).Error
+	})
+}
+
+func (r *repository) RevokeByUserID(userID int) error {
+	return r.RevokeSessions(userID, nil, "")
+}
+
+func (r *repository) SaveSession(session Session) (Session, error) {
+	err := r.db.Create(&session).Error
+	if err != nil {
+		return session, err
+	}
+
+	return session, nil
+}
+
+func (r *repository) FindSessionByID(ID string) (Session, error) {
+	var session Session
+
+	err := r.db.Where("id = ?", ID).Find(&session).Error
+	if err != nil {
+		return session, err
+	}
+
+	return session, nil
+}
+
+func (r *repository) FindActiveSessionsByUserID(userID int) ([]Session, error) {
+	var sessions []Session
+
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).Order("last_seen_at desc").Find(&sessions).Error
+	if err != nil {
+		return sessions, err
+	}
+
+	return sessions, nil
+}
+
+// ExtendSession moves the expiry of a session that is still active. It only
+// writes those two columns, so a revocation that happened since the session
+// was read is kept, and reports false in that case.
+func (r *repository) ExtendSession(ID string, expiresAt time.Time, lastSeenAt time.Time) (bool, error) {
+	result := r.db.Model(&Session{}).Where("id = ? AND revoked_at IS NULL", ID).UpdateColumns(map[string]interface{}{
+		"expires_at":   expiresAt,
+		"last_seen_at": lastSeenAt,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) TouchSession(ID string, lastSeenAt time.Time) error {
+	return r.db.Model(&Session{}).Where("id = ?", ID).UpdateColumn("last_seen_at", lastSeenAt).Error
+}
+
+// RevokeSessions revokes the user's sessions and their refresh tokens. IDs
+// limits it to the given sessions (nil means all of them) and exceptID keeps
+// one session alive.
+func (r *repository) RevokeSessions(userID int, IDs []string, exceptID string) error {
+	now := time.Now()
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		sessions := tx.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+		refreshTokens := tx.Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+
+		if IDs != nil {
+			sessions = sessions.Where("id IN ?", IDs)
+			refreshTokens = refreshTokens.Where("family_id IN ?", IDs)
+		}
+
+		if exceptID != "" {
+			sessions = sessions.Where("id <> ?", exceptID)
+			refreshTokens = refreshTokens.Where("family_id <> ?", exceptID)
+		}
+
+		if err := sessions.Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		return refreshTokens.Update("revoked_at", now).Error
+	})
+}
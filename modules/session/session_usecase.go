@@ -0,0 +1,223 @@
+package session
+
+import (
+	"crowdfunding-minpro-alterra/utils/helper"
+	"errors"
+	"time"
+)
+
+// lastSeenInterval limits last_seen_at writes to one per session per
+// interval instead of one per request.
+const lastSeenInterval = time.Minute
+
+type Service interface {
+	CreateSession(input CreateSessionInput) (Session, string, error)
+	RotateRefreshToken(input RefreshTokenInput) (RefreshToken, string, error)
+	RevokeRefreshToken(input RefreshTokenInput) error
+	RevokeUserSessions(userID int) error
+	ValidateSession(ID string, userID int) (Session, error)
+	GetUserSessions(userID int) ([]Session, error)
+	RevokeSession(input RevokeSessionInput) error
+	RevokeOtherSessions(userID int, currentSessionID string) error
+}
+
+type service struct {
+	repository      Repository
+	refreshTokenTTL time.Duration
+}
+
+func NewService(repository Repository, refreshTokenTTL time.Duration) *service {
+	return &service{repository, refreshTokenTTL}
+}
+
+// CreateSession records a new login and returns it with the first refresh
+// token of the session.
+func (s *service) CreateSession(input CreateSessionInput) (Session, string, error) {
+	sessionID, err := helper.GenerateRandomToken(24)
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	now := time.Now()
+
+	session := Session{}
+	session.ID = sessionID
+	session.UserID = input.UserID
+	session.UserAgent = input.UserAgent
+	session.IPAddress = input.IPAddress
+	session.ExpiresAt = now.Add(s.refreshTokenTTL)
+	session.LastSeenAt = now
+
+	newSession, err := s.repository.SaveSession(session)
+	if err != nil {
+		return newSession, "", err
+	}
+
+	_, token, err := s.issue(input.UserID, newSession.ID)
+	if err != nil {
+		return newSession, "", err
+	}
+
+	return newSession, token, nil
+}
+
+func (s *service) RotateRefreshToken(input RefreshTokenInput) (RefreshToken, string, error) {
+	refreshToken, err := s.repository.FindByTokenHash(helper.HashToken(input.RefreshToken))
+	if err != nil {
+		return refreshToken, "", err
+	}
+
+	if refreshToken.ID == 0 {
+		return refreshToken, "", errors.New("Invalid refresh token")
+	}
+
+	if refreshToken.RevokedAt != nil || refreshToken.UsedAt != nil {
+		return refreshToken, "", s.revokeReusedFamily(refreshToken)
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return refreshToken, "", errors.New("Refresh token has expired")
+	}
+
+	marked, err := s.repository.MarkAsUsed(refreshToken.ID)
+	if err != nil {
+		return refreshToken, "", err
+	}
+
+	if !marked {
+		return refreshToken, "", s.revokeReusedFamily(refreshToken)
+	}
+
+	session, err := s.repository.FindSessionByID(refreshToken.FamilyID)
+	if err != nil {
+		return refreshToken, "", err
+	}
+
+	if session.ID == "" || session.RevokedAt != nil {
+		return refreshToken, "", errors.New("Session has been revoked")
+	}
+
+	newRefreshToken, token, err := s.issue(refreshToken.UserID, refreshToken.FamilyID)
+	if err != nil {
+		return newRefreshToken, "", err
+	}
+
+	extended, err := s.repository.ExtendSession(session.ID, newRefreshToken.ExpiresAt, time.Now())
+	if err != nil {
+		return newRefreshToken, "", err
+	}
+
+	// The session was revoked after it was read; the token just issued must
+	// not outlive it.
+	if !extended {
+		err = s.repository.RevokeFamily(refreshToken.FamilyID)
+		if err != nil {
+			return newRefreshToken, "", err
+		}
+
+		return newRefreshToken, "", errors.New("Session has been revoked")
+	}
+
+	return newRefreshToken, token, nil
+}
+
+func (s *service) RevokeRefreshToken(input RefreshTokenInput) error {
+	refreshToken, err := s.repository.FindByTokenHash(helper.HashToken(input.RefreshToken))
+	if err != nil {
+		return err
+	}
+
+	if refreshToken.ID == 0 {
+		return errors.New("Invalid refresh token")
+	}
+
+	return s.repository.RevokeFamily(refreshToken.FamilyID)
+}
+
+func (s *service) RevokeUserSessions(userID int) error {
+	return s.repository.RevokeByUserID(userID)
+}
+
+// ValidateSession checks the "sid" of an access token: the session must
+// belong to userID and be neither revoked nor expired.
+func (s *service) ValidateSession(ID string, userID int) (Session, error) {
+	session, err := s.repository.FindSessionByID(ID)
+	if err != nil {
+		return session, err
+	}
+
+	now := time.Now()
+
+	if session.ID == "" || session.UserID != userID || !session.IsActive(now) {
+		return Session{}, errors.New("Session has been revoked")
+	}
+
+	if now.Sub(session.LastSeenAt) >= lastSeenInterval {
+		err = s.repository.TouchSession(session.ID, now)
+		if err != nil {
+			return session, err
+		}
+
+		session.LastSeenAt = now
+	}
+
+	return session, nil
+}
+
+func (s *service) GetUserSessions(userID int) ([]Session, error) {
+	sessions, err := s.repository.FindActiveSessionsByUserID(userID)
+	if err != nil {
+		return sessions, err
+	}
+
+	return sessions, nil
+}
+
+func (s *service) RevokeSession(input RevokeSessionInput) error {
+	session, err := s.repository.FindSessionByID(input.ID)
+	if err != nil {
+		return err
+	}
+
+	if session.ID == "" || session.UserID != input.UserID {
+		return errors.New("No session found with that ID")
+	}
+
+	return s.repository.RevokeSessions(input.UserID, []string{session.ID}, "")
+}
+
+func (s *service) RevokeOtherSessions(userID int, currentSessionID string) error {
+	return s.repository.RevokeSessions(userID, nil, currentSessionID)
+}
+
+func (s *service) issue(userID int, familyID string) (RefreshToken, string, error) {
+	token, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return RefreshToken{}, "", err
+	}
+
+	refreshToken := RefreshToken{}
+	refreshToken.UserID = userID
+	refreshToken.FamilyID = familyID
+	refreshToken.TokenHash = helper.HashToken(token)
+	refreshToken.ExpiresAt = time.Now().Add(s.refreshTokenTTL)
+
+	newRefreshToken, err := s.repository.Save(refreshToken)
+	if err != nil {
+		return newRefreshToken, "", err
+	}
+
+	return newRefreshToken, token, nil
+}
+
+// revokeReusedFamily handles a refresh token that is presented a second
+// time. Only a thief or a replaying client can do that, so every token of
+// the family is revoked and the legitimate user has to log in again.
+func (s *service) revokeReusedFamily(refreshToken RefreshToken) error {
+	err := s.repository.RevokeFamily(refreshToken.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	return errors.New("Refresh token reuse detected")
+}
@@ -0,0 +1,343 @@
+package session
+
+import (
+	"crowdfunding-minpro-alterra/utils/helper"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRepository struct {
+	FindByTokenHashFunc func(tokenHash string) (RefreshToken, error)
+	MarkAsUsedFunc      func(ID int) (bool, error)
+	ExtendSessionFunc   func(ID string, expiresAt time.Time, lastSeenAt time.Time) (bool, error)
+	SavedTokens         []RefreshToken
+	RevokedFamilies     []string
+	RevokedUsers        []int
+	Sessions            []Session
+	TouchedSessions     []string
+}
+
+func (m *MockRepository) Save(refreshToken RefreshToken) (RefreshToken, error) {
+	refreshToken.ID = len(m.SavedTokens) + 1
+	m.SavedTokens = append(m.SavedTokens, refreshToken)
+	return refreshToken, nil
+}
+
+func (m *MockRepository) FindByTokenHash(tokenHash string) (RefreshToken, error) {
+	if m.FindByTokenHashFunc != nil {
+		return m.FindByTokenHashFunc(tokenHash)
+	}
+	return RefreshToken{}, nil
+}
+
+func (m *MockRepository) Update(refreshToken RefreshToken) (RefreshToken, error) {
+	return refreshToken, nil
+}
+
+func (m *MockRepository) MarkAsUsed(ID int) (bool, error) {
+	if m.MarkAsUsedFunc != nil {
+		return m.MarkAsUsedFunc(ID)
+	}
+	return true, nil
+}
+
+func (m *MockRepository) RevokeFamily(familyID string) error {
+	m.RevokedFamilies = append(m.RevokedFamilies, familyID)
+	return nil
+}
+
+func (m *MockRepository) RevokeByUserID(userID int) error {
+	m.RevokedUsers = append(m.RevokedUsers, userID)
+	return nil
+}
+
+func (m *MockRepository) SaveSession(session Session) (Session, error) {
+	m.Sessions = append(m.Sessions, session)
+	return session, nil
+}
+
+func (m *MockRepository) FindSessionByID(ID string) (Session, error) {
+	for _, session := range m.Sessions {
+		if session.ID == ID {
+			return session, nil
+		}
+	}
+	return Session{}, nil
+}
+
+func (m *MockRepository) FindActiveSessionsByUserID(userID int) ([]Session, error) {
+	var sessions []Session
+	for _, session := range m.Sessions {
+		if session.UserID == userID && session.IsActive(time.Now()) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *MockRepository) ExtendSession(ID string, expiresAt time.Time, lastSeenAt time.Time) (bool, error) {
+	if m.ExtendSessionFunc != nil {
+		return m.ExtendSessionFunc(ID, expiresAt, lastSeenAt)
+	}
+	for i := range m.Sessions {
+		if m.Sessions[i].ID == ID && m.Sessions[i].RevokedAt == nil {
+			m.Sessions[i].ExpiresAt = expiresAt
+			m.Sessions[i].LastSeenAt = lastSeenAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockRepository) TouchSession(ID string, lastSeenAt time.Time) error {
+	m.TouchedSessions = append(m.TouchedSessions, ID)
+	return nil
+}
+
+func (m *MockRepository) RevokeSessions(userID int, IDs []string, exceptID string) error {
+	now := time.Now()
+	for i, session := range m.Sessions {
+		if session.UserID != userID || session.ID == exceptID {
+			continue
+		}
+		if IDs != nil && !contains(IDs, session.ID) {
+			continue
+		}
+		m.Sessions[i].RevokedAt = &now
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCreateSession(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, time.Hour)
+
+	session, token, err := service.CreateSession(CreateSessionInput{UserID: 1, UserAgent: "Mozilla/5.0", IPAddress: "10.0.0.1"})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, session.ID)
+	assert.Equal(t, "Mozilla/5.0", session.UserAgent)
+	assert.Equal(t, "10.0.0.1", session.IPAddress)
+	assert.Len(t, repo.SavedTokens, 1)
+	assert.Equal(t, helper.HashToken(token), repo.SavedTokens[0].TokenHash)
+	assert.Equal(t, session.ID, repo.SavedTokens[0].FamilyID)
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	t.Run("Test RotateRefreshToken success", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		existingToken := RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+		repo.Sessions = []Session{{ID: "family", UserID: 1, ExpiresAt: existingToken.ExpiresAt}}
+
+		repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+			if tokenHash == helper.HashToken("old-token") {
+				return existingToken, nil
+			}
+			return RefreshToken{}, nil
+		}
+
+		newToken, plainToken, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, "old-token", plainToken)
+		assert.Equal(t, 1, newToken.UserID)
+		assert.Equal(t, "family", newToken.FamilyID)
+		assert.Empty(t, repo.RevokedFamilies)
+		assert.Equal(t, newToken.ExpiresAt, repo.Sessions[0].ExpiresAt)
+	})
+
+	t.Run("Test RotateRefreshToken with revoked session", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		revokedAt := time.Now()
+		repo.Sessions = []Session{{ID: "family", UserID: 1, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}}
+		repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+			return RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		}
+
+		_, _, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+		assert.EqualError(t, err, "Session has been revoked")
+		assert.Empty(t, repo.SavedTokens)
+	})
+
+	t.Run("Test RotateRefreshToken with session revoked during rotation", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		repo.Sessions = []Session{{ID: "family", UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}}
+		repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+			return RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		}
+		repo.ExtendSessionFunc = func(ID string, expiresAt time.Time, lastSeenAt time.Time) (bool, error) {
+			return false, nil
+		}
+
+		_, _, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+		assert.EqualError(t, err, "Session has been revoked")
+		assert.Equal(t, []string{"family"}, repo.RevokedFamilies)
+	})
+
+	t.Run("Test RotateRefreshToken with unknown token", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		_, _, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "unknown"})
+
+		assert.EqualError(t, err, "Invalid refresh token")
+	})
+
+	t.Run("Test RotateRefreshToken with expired token", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+			return RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(-time.Minute)}, nil
+		}
+
+		_, _, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+		assert.EqualError(t, err, "Refresh token has expired")
+	})
+
+	t.Run("Test RotateRefreshToken reuse revokes the family", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		usedAt := time.Now().Add(-time.Minute)
+		repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+			return RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}, nil
+		}
+
+		_, _, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+		assert.EqualError(t, err, "Refresh token reuse detected")
+		assert.Equal(t, []string{"family"}, repo.RevokedFamilies)
+		assert.Empty(t, repo.SavedTokens)
+	})
+
+	t.Run("Test RotateRefreshToken concurrent use revokes the family", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+			return RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		}
+		repo.MarkAsUsedFunc = func(ID int) (bool, error) {
+			return false, nil
+		}
+
+		_, _, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+		assert.EqualError(t, err, "Refresh token reuse detected")
+		assert.Equal(t, []string{"family"}, repo.RevokedFamilies)
+	})
+
+	t.Run("Test RotateRefreshToken with repository error", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := NewService(repo, time.Hour)
+
+		repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+			return RefreshToken{}, errors.New("repository error")
+		}
+
+		_, _, err := service.RotateRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+		assert.EqualError(t, err, "repository error")
+	})
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, time.Hour)
+
+	repo.FindByTokenHashFunc = func(tokenHash string) (RefreshToken, error) {
+		return RefreshToken{ID: 7, UserID: 1, FamilyID: "family"}, nil
+	}
+
+	err := service.RevokeRefreshToken(RefreshTokenInput{RefreshToken: "old-token"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"family"}, repo.RevokedFamilies)
+}
+
+func TestValidateSession(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, time.Hour)
+
+	session, _, _ := service.CreateSession(CreateSessionInput{UserID: 1})
+
+	_, err := service.ValidateSession(session.ID, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, repo.TouchedSessions)
+
+	repo.Sessions[0].LastSeenAt = time.Now().Add(-time.Hour)
+
+	_, err = service.ValidateSession(session.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{session.ID}, repo.TouchedSessions)
+
+	_, err = service.ValidateSession(session.ID, 2)
+	assert.EqualError(t, err, "Session has been revoked")
+
+	_, err = service.ValidateSession("unknown", 1)
+	assert.EqualError(t, err, "Session has been revoked")
+}
+
+func TestRevokeSession(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, time.Hour)
+
+	phone, _, _ := service.CreateSession(CreateSessionInput{UserID: 1, UserAgent: "Phone"})
+	laptop, _, _ := service.CreateSession(CreateSessionInput{UserID: 1, UserAgent: "Laptop"})
+
+	err := service.RevokeSession(RevokeSessionInput{ID: phone.ID, UserID: 2})
+	assert.EqualError(t, err, "No session found with that ID")
+
+	err = service.RevokeSession(RevokeSessionInput{ID: phone.ID, UserID: 1})
+	assert.NoError(t, err)
+
+	_, err = service.ValidateSession(phone.ID, 1)
+	assert.EqualError(t, err, "Session has been revoked")
+
+	sessions, _ := service.GetUserSessions(1)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, laptop.ID, sessions[0].ID)
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, time.Hour)
+
+	current, _, _ := service.CreateSession(CreateSessionInput{UserID: 1})
+	service.CreateSession(CreateSessionInput{UserID: 1})
+	service.CreateSession(CreateSessionInput{UserID: 1})
+	other, _, _ := service.CreateSession(CreateSessionInput{UserID: 2})
+
+	err := service.RevokeOtherSessions(1, current.ID)
+	assert.NoError(t, err)
+
+	sessions, _ := service.GetUserSessions(1)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, current.ID, sessions[0].ID)
+
+	_, err = service.ValidateSession(other.ID, 2)
+	assert.NoError(t, err)
+}
@@ -2,6 +2,8 @@ package user
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type User struct {
@@ -12,7 +14,101 @@ type User struct {
     AvatarFileName string    `gorm:"column:avatar_file_name"`
     Role           string    `gorm:"column:role"`
     Token          string    `gorm:"column:token"`
+    VerifiedAt     *time.Time `gorm:"column:verified_at"`
+    TOTPSecret     string     `gorm:"column:totp_secret"`
+    TOTPEnabledAt  *time.Time `gorm:"column:totp_enabled_at"`
+    TOTPLastStep   int64      `gorm:"column:totp_last_step"`
+    SuspendedAt    *time.Time `gorm:"column:suspended_at"`
+    SuspensionReason string   `gorm:"column:suspension_reason"`
     CreatedAt      time.Time `gorm:"column:created_at"`
     UpdatedAt      time.Time `gorm:"column:updated_at"`
-    DeletedAt      *time.Time `gorm:"column:deleted_at"` 
-}
\ No newline at end of file
+    DeletedAt      gorm.DeletedAt `gorm:"column:deleted_at;index"`
+    ErasedAt       *time.Time `gorm:"column:erased_at"`
+}
+
+func (u User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+const (
+	StatusActive     = "active"
+	StatusUnverified = "unverified"
+	StatusSuspended  = "suspended"
+	StatusDeleted    = "deleted"
+)
+
+func (u User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// Status reports the most restrictive state of the account.
+func (u User) Status() string {
+	switch {
+	case u.IsDeleted():
+		return StatusDeleted
+	case u.IsSuspended():
+		return StatusSuspended
+	case !u.IsVerified():
+		return StatusUnverified
+	default:
+		return StatusActive
+	}
+}
+
+func (u User) IsDeleted() bool {
+	return u.DeletedAt.Valid
+}
+
+func (u User) IsErased() bool {
+	return u.ErasedAt != nil
+}
+
+func (u User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// TwoFactorRequired reports whether the role forces two-factor
+// authentication before its permissions can be used.
+func (u User) TwoFactorRequired() bool {
+	return u.Role == RoleAdmin
+}
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// UserToken is a single-use, expiring secret sent to the user out of band.
+// Only the SHA-256 hash of the secret is stored.
+type UserToken struct {
+	ID        int        `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    int        `gorm:"column:user_id;index"`
+	Purpose   string     `gorm:"column:purpose;type:varchar(32)"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+// RecoveryCode is a single-use fallback for a lost authenticator device.
+type RecoveryCode struct {
+	ID        int        `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    int        `gorm:"column:user_id;index"`
+	CodeHash  string     `gorm:"column:code_hash;type:varchar(64)"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// UserIdentity links a user to an account at an external OpenID Connect
+// provider.
+type UserIdentity struct {
+	ID        int       `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    int       `gorm:"column:user_id;index"`
+	Provider  string    `gorm:"column:provider;type:varchar(64);uniqueIndex:idx_provider_subject"`
+	Subject   string    `gorm:"column:subject;type:varchar(191);uniqueIndex:idx_provider_subject"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
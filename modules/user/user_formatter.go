@@ -1,30 +1,41 @@
 package user
 
+import "time"
+
 type UserFormatter struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Token    string `json:"token"`
-	ImageURL string `json:"image_url"`
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	IsVerified   bool   `json:"is_verified"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ImageURL     string `json:"image_url"`
 }
 
-func FormatUser(user User, token string) UserFormatter {
+func FormatUser(user User, token string, refreshToken string) UserFormatter {
 	formatter := UserFormatter{
-		ID:       user.ID,
-		Name:     user.Name,
-		Email:    user.Email,
-		Token:    token,
-		ImageURL: user.AvatarFileName,
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		Role:         user.Role,
+		IsVerified:   user.IsVerified(),
+		Token:        token,
+		RefreshToken: refreshToken,
+		ImageURL:     user.AvatarFileName,
 	}
 
 	return formatter
 }
 
 type GetUserFormatter struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	ImageURL string `json:"image_url"`
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	Email            string `json:"email"`
+	Role             string `json:"role"`
+	IsVerified       bool   `json:"is_verified"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	ImageURL         string `json:"image_url"`
 }
 
 func GetFormatUser(user User) GetUserFormatter {
@@ -32,6 +43,9 @@ func GetFormatUser(user User) GetUserFormatter {
 	userFormatter.ID = user.ID
 	userFormatter.Name = user.Name
 	userFormatter.Email = user.Email
+	userFormatter.Role = user.Role
+	userFormatter.IsVerified = user.IsVerified()
+	userFormatter.TwoFactorEnabled = user.TwoFactorEnabled()
 	userFormatter.ImageURL = user.AvatarFileName
 
 	return userFormatter
@@ -46,4 +60,119 @@ func GetFormatUsers(users []User) []GetUserFormatter {
 	}
 
 	return usersFormatter
-}
\ No newline at end of file
+}
+
+type AdminUserFormatter struct {
+	ID               int        `json:"id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	Status           string     `json:"status"`
+	IsVerified       bool       `json:"is_verified"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	SuspendedAt      *time.Time `json:"suspended_at"`
+	SuspensionReason string     `json:"suspension_reason"`
+	ImageURL         string     `json:"image_url"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func FormatAdminUser(user User) AdminUserFormatter {
+	formatter := AdminUserFormatter{}
+	formatter.ID = user.ID
+	formatter.Name = user.Name
+	formatter.Email = user.Email
+	formatter.Role = user.Role
+	formatter.Status = user.Status()
+	formatter.IsVerified = user.IsVerified()
+	formatter.TwoFactorEnabled = user.TwoFactorEnabled()
+	formatter.SuspendedAt = user.SuspendedAt
+	formatter.SuspensionReason = user.SuspensionReason
+	formatter.ImageURL = user.AvatarFileName
+	formatter.CreatedAt = user.CreatedAt
+
+	return formatter
+}
+
+func FormatAdminUsers(users []User) []AdminUserFormatter {
+	formatters := []AdminUserFormatter{}
+
+	for _, user := range users {
+		formatters = append(formatters, FormatAdminUser(user))
+	}
+
+	return formatters
+}
+
+type UserExportFormatter struct {
+	ID               int        `json:"id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	ImageURL         string     `json:"image_url"`
+	VerifiedAt       *time.Time `json:"verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func FormatUserExport(user User) UserExportFormatter {
+	formatter := UserExportFormatter{}
+	formatter.ID = user.ID
+	formatter.Name = user.Name
+	formatter.Email = user.Email
+	formatter.Role = user.Role
+	formatter.ImageURL = user.AvatarFileName
+	formatter.VerifiedAt = user.VerifiedAt
+	formatter.TwoFactorEnabled = user.TwoFactorEnabled()
+	formatter.CreatedAt = user.CreatedAt
+	formatter.UpdatedAt = user.UpdatedAt
+
+	return formatter
+}
+
+type RoleFormatter struct {
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+}
+
+func FormatRoles(roles []string) []RoleFormatter {
+	rolesFormatter := []RoleFormatter{}
+
+	for _, role := range roles {
+		roleFormatter := RoleFormatter{}
+		roleFormatter.Name = role
+		roleFormatter.Permissions = PermissionsOf(role)
+
+		rolesFormatter = append(rolesFormatter, roleFormatter)
+	}
+
+	return rolesFormatter
+}
+
+type TwoFactorChallengeFormatter struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+func FormatTwoFactorChallenge(challengeToken string) TwoFactorChallengeFormatter {
+	formatter := TwoFactorChallengeFormatter{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+	}
+
+	return formatter
+}
+
+type TwoFactorEnrollmentFormatter struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+func FormatTwoFactorEnrollment(secret string, provisioningURI string) TwoFactorEnrollmentFormatter {
+	formatter := TwoFactorEnrollmentFormatter{
+		Secret:          secret,
+		ProvisioningURI: provisioningURI,
+	}
+
+	return formatter
+}
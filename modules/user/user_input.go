@@ -1,14 +1,21 @@
 package user
 
+import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"time"
+)
+
 type RegisterUserInput struct {
-	Name     string `json:"name" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Name     string      `json:"name" binding:"required"`
+	Email    string      `json:"email" binding:"required,email"`
+	Password string      `json:"password" binding:"required"`
+	Actor    audit.Actor `json:"-" form:"-"`
 }
 
 type LoginInput struct {
-	Email    string `json:"email" form:"email" binding:"required,email"`
-	Password string `json:"password" form:"password" binding:"required"`
+	Email    string      `json:"email" form:"email" binding:"required,email"`
+	Password string      `json:"password" form:"password" binding:"required"`
+	Actor    audit.Actor `json:"-" form:"-"`
 }
 
 type CheckEmailInput struct {
@@ -24,7 +31,83 @@ type FormCreateUserInput struct {
 
 type FormUpdateUserInput struct {
 	ID    int
-	Name  string `form:"name" binding:"required"`
-	Email string `form:"email" binding:"required,email"`
+	Name  string `form:"name" json:"name" binding:"required"`
+	Email string `form:"email" json:"email" binding:"required,email"`
+	// CurrentPassword is only needed to change the email address.
+	CurrentPassword string `form:"current_password" json:"current_password"`
 	Error error
-}
\ No newline at end of file
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type AssignRoleInput struct {
+	ID    int
+	Role  string      `json:"role" binding:"required"`
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string      `json:"token" binding:"required"`
+	Password string      `json:"password" binding:"required,min=8"`
+	Actor    audit.Actor `json:"-" form:"-"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ConfirmTwoFactorInput struct {
+	ID    int
+	Code  string      `json:"code" binding:"required"`
+	Actor audit.Actor `json:"-" form:"-"`
+}
+
+type DisableTwoFactorInput struct {
+	ID       int
+	Password string      `json:"password" binding:"required"`
+	Code     string      `json:"code" binding:"required"`
+	Actor    audit.Actor `json:"-" form:"-"`
+}
+
+type TwoFactorLoginInput struct {
+	ID             int
+	ChallengeToken string      `json:"challenge_token" binding:"required"`
+	Code           string      `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode   string      `json:"recovery_code"`
+	Actor          audit.Actor `json:"-" form:"-"`
+}
+
+type OIDCLoginInput struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Actor         audit.Actor `json:"-" form:"-"`
+}
+
+type ChangePasswordInput struct {
+	ID              int
+	CurrentPassword string      `json:"current_password" binding:"required"`
+	NewPassword     string      `json:"new_password" binding:"required,min=8"`
+	Actor           audit.Actor `json:"-" form:"-"`
+}
+
+type GetUsersInput struct {
+	Page        int       `form:"page" binding:"omitempty,min=1"`
+	Limit       int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	Query       string    `form:"q"`
+	Role        string    `form:"role"`
+	Status      string    `form:"status" binding:"omitempty,oneof=active unverified suspended deleted"`
+	CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02"`
+	CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02"`
+}
+
+type SuspendUserInput struct {
+	ID     int
+	Reason string      `json:"reason" binding:"max=255"`
+	Actor  audit.Actor `json:"-" form:"-"`
+}
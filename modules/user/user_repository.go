@@ -1,6 +1,10 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Repository interface {
 	Save(user User) (User, error)
@@ -8,7 +12,21 @@ type Repository interface {
 	FindByID(ID int) (User, error)
 	Update(user User) (User, error)
 	FindAll() ([]User, error)
+	CountActiveAdmins() (int64, error)
+	Search(input GetUsersInput) ([]User, int64, error)
 	Delete(ID int) error
+	FindByIDWithDeleted(ID int) (User, error)
+	Restore(ID int) error
+	Erase(user User) error
+	SaveToken(token UserToken) (UserToken, error)
+	FindTokenByHash(tokenHash string, purpose string) (UserToken, error)
+	FindLatestToken(userID int, purpose string) (UserToken, error)
+	MarkTokensAsUsed(userID int, purpose string) error
+	UseToken(tokenID int) (bool, error)
+	ReplaceRecoveryCodes(userID int, codes []RecoveryCode) error
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	FindIdentity(provider string, subject string) (UserIdentity, error)
+	SaveIdentity(identity UserIdentity) (UserIdentity, error)
 }
 
 type repository struct {
@@ -71,6 +89,67 @@ func (r *repository) FindAll() ([]User, error) {
 	return users, nil
 }
 
+// CountActiveAdmins counts the admins who are not suspended or deleted.
+func (r *repository) CountActiveAdmins() (int64, error) {
+	var count int64
+
+	err := r.db.Model(&User{}).Where("role = ? AND suspended_at IS NULL", RoleAdmin).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// Search expects input.Page and input.Limit to be set. Deleted users are only
+// returned when filtering by StatusDeleted.
+func (r *repository) Search(input GetUsersInput) ([]User, int64, error) {
+	var users []User
+	var total int64
+
+	query := r.db.Model(&User{})
+
+	switch input.Status {
+	case StatusDeleted:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	case StatusSuspended:
+		query = query.Where("suspended_at IS NOT NULL")
+	case StatusUnverified:
+		query = query.Where("suspended_at IS NULL AND verified_at IS NULL")
+	case StatusActive:
+		query = query.Where("suspended_at IS NULL AND verified_at IS NOT NULL")
+	}
+
+	if input.Query != "" {
+		like := "%" + input.Query + "%"
+		query = query.Where("name LIKE ? OR email LIKE ?", like, like)
+	}
+
+	if input.Role != "" {
+		query = query.Where("role = ?", input.Role)
+	}
+
+	if !input.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", input.CreatedFrom)
+	}
+
+	if !input.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", input.CreatedTo.AddDate(0, 0, 1))
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return users, total, err
+	}
+
+	err = query.Order("id desc").Offset((input.Page - 1) * input.Limit).Limit(input.Limit).Find(&users).Error
+	if err != nil {
+		return users, total, err
+	}
+
+	return users, total, nil
+}
+
 func (r *repository) Delete(ID int) error {
 	err := r.db.Delete(&User{}, ID).Error
 	if err != nil {
@@ -78,3 +157,133 @@ func (r *repository) Delete(ID int) error {
 	}
 	return nil
 }
+
+func (r *repository) FindByIDWithDeleted(ID int) (User, error) {
+	var user User
+
+	err := r.db.Unscoped().Where("id = ?", ID).Find(&user).Error
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+func (r *repository) Restore(ID int) error {
+	return r.db.Unscoped().Model(&User{}).Where("id = ?", ID).Update("deleted_at", nil).Error
+}
+
+// Erase stores the anonymized user and removes every credential and linked
+// identity. Campaigns and donations keep pointing at the anonymized row.
+func (r *repository) Erase(user User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&UserToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&UserIdentity{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Save(&user).Error
+	})
+}
+
+func (r *repository) SaveToken(token UserToken) (UserToken, error) {
+	err := r.db.Create(&token).Error
+	if err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (r *repository) FindTokenByHash(tokenHash string, purpose string) (UserToken, error) {
+	var token UserToken
+
+	err := r.db.Where("token_hash = ? AND purpose = ?", tokenHash, purpose).Find(&token).Error
+	if err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (r *repository) FindLatestToken(userID int, purpose string) (UserToken, error) {
+	var token UserToken
+
+	err := r.db.Where("user_id = ? AND purpose = ?", userID, purpose).Order("created_at desc").Limit(1).Find(&token).Error
+	if err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (r *repository) MarkTokensAsUsed(userID int, purpose string) error {
+	err := r.db.Model(&UserToken{}).Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).Update("used_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UseToken marks the token used unless that already happened, and reports
+// whether this call was the one that spent it.
+func (r *repository) UseToken(tokenID int) (bool, error) {
+	result := r.db.Model(&UserToken{}).Where("id = ? AND used_at IS NULL", tokenID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) ReplaceRecoveryCodes(userID int, codes []RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(codes) == 0 {
+			return nil
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *repository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result := r.db.Model(&RecoveryCode{}).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) FindIdentity(provider string, subject string) (UserIdentity, error) {
+	var identity UserIdentity
+
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).Find(&identity).Error
+	if err != nil {
+		return identity, err
+	}
+
+	return identity, nil
+}
+
+func (r *repository) SaveIdentity(identity UserIdentity) (UserIdentity, error) {
+	err := r.db.Create(&identity).Error
+	if err != nil {
+		return identity, err
+	}
+
+	return identity, nil
+}
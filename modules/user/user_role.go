@@ -0,0 +1,117 @@
+package user
+
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleOrganizer = "organizer"
+	RoleDonor     = "donor"
+
+	// RoleLegacyUser is the role stored for accounts registered before roles
+	// existed. It keeps the permissions those accounts always had.
+	RoleLegacyUser = "user"
+
+	// DefaultRole is given to new accounts. Organizers may start campaigns
+	// as well as donate, like every account could before roles existed.
+	DefaultRole = RoleOrganizer
+)
+
+type Permission string
+
+const (
+	PermissionCampaignCreate   Permission = "campaigns:create"
+	PermissionDonationCreate   Permission = "donations:create"
+	PermissionUserRead         Permission = "users:read"
+	PermissionUserDelete       Permission = "users:delete"
+	PermissionUserUnlock       Permission = "users:unlock"
+	PermissionUserSuspend      Permission = "users:suspend"
+	PermissionRoleAssign       Permission = "roles:assign"
+	PermissionAuditRead        Permission = "audit:read"
+	PermissionCampaignModerate Permission = "campaigns:moderate"
+	PermissionCategoryManage   Permission = "categories:manage"
+	PermissionCommentModerate  Permission = "comments:moderate"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermissionCampaignCreate,
+		PermissionDonationCreate,
+		PermissionUserRead,
+		PermissionUserDelete,
+		PermissionUserUnlock,
+		PermissionUserSuspend,
+		PermissionRoleAssign,
+		PermissionAuditRead,
+		PermissionCampaignModerate,
+		PermissionCategoryManage,
+		PermissionCommentModerate,
+	},
+	RoleModerator: {
+		PermissionDonationCreate,
+		PermissionUserRead,
+		PermissionUserUnlock,
+		PermissionUserSuspend,
+		PermissionCampaignModerate,
+		PermissionCommentModerate,
+	},
+	RoleOrganizer: {
+		PermissionCampaignCreate,
+		PermissionDonationCreate,
+	},
+	RoleDonor: {
+		PermissionDonationCreate,
+	},
+	RoleLegacyUser: {
+		PermissionCampaignCreate,
+		PermissionDonationCreate,
+	},
+}
+
+// Roles lists the roles that can be assigned to a user, highest privilege first.
+func Roles() []string {
+	return []string{RoleAdmin, RoleModerator, RoleOrganizer, RoleDonor}
+}
+
+// rank orders roles by privilege, 0 being the highest. Legacy accounts rank
+// with organizers, whose permissions they share; unknown roles rank lowest.
+func rank(role string) int {
+	if role == RoleLegacyUser {
+		role = RoleOrganizer
+	}
+
+	for i, r := range Roles() {
+		if r == role {
+			return i
+		}
+	}
+
+	return len(Roles())
+}
+
+// Outranks reports whether u's role is strictly higher than other's.
+func (u User) Outranks(other User) bool {
+	return rank(u.Role) < rank(other.Role)
+}
+
+func IsValidRole(role string) bool {
+	for _, r := range Roles() {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func PermissionsOf(role string) []Permission {
+	return rolePermissions[role]
+}
+
+func (u User) HasPermission(permission Permission) bool {
+	for _, p := range rolePermissions[u.Role] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
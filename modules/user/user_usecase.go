@@ -1,28 +1,78 @@
 package user
 
 import (
+	"crowdfunding-minpro-alterra/config"
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"crowdfunding-minpro-alterra/utils/mailer"
+	"crowdfunding-minpro-alterra/utils/password"
+	"crowdfunding-minpro-alterra/utils/totp"
+	"crypto/rand"
+	"encoding/base32"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultUsersPageLimit = 20
+
+	passwordResetTokenTTL     = time.Hour
+	emailVerificationTokenTTL = 24 * time.Hour
+
+	// emailVerificationResendInterval is the minimum time between two
+	// verification emails for the same account.
+	emailVerificationResendInterval = time.Minute
+
+	recoveryCodeCount = 10
 )
 
 type Service interface {
 	RegisterUser(input RegisterUserInput) (User, error)
 	Login(input LoginInput) (User, error)
 	IsEmailAvailable(input CheckEmailInput) (bool, error)
-	SaveAvatar(ID int, fileLocation string) (User, error)
+	SaveAvatar(ID int, fileLocation string, actor audit.Actor) (User, error)
 	GetUserByID(ID int) (User, error)
 	GetAllUsers() ([]User, error)
+	SearchUsers(input GetUsersInput) ([]User, int64, GetUsersInput, error)
+	SuspendUser(input SuspendUserInput) (User, error)
+	UnsuspendUser(ID int, actor audit.Actor) (User, error)
 	UpdateUser(input FormUpdateUserInput) (User, error)
-	DeleteUser(ID int) error
+	DeleteUser(ID int, actor audit.Actor) error
+	RestoreUser(ID int, actor audit.Actor) (User, error)
+	EraseUser(ID int, actor audit.Actor) error
+	AssignRole(input AssignRoleInput) (User, error)
+	BootstrapAdmin(email string) (User, error)
+	ForgotPassword(input ForgotPasswordInput) error
+	ResetPassword(input ResetPasswordInput) (User, error)
+	VerifyEmail(input VerifyEmailInput) (User, error)
+	ResendVerificationEmail(ID int) error
+	EnrollTwoFactor(ID int) (User, string, error)
+	ConfirmTwoFactor(input ConfirmTwoFactorInput) ([]string, error)
+	DisableTwoFactor(input DisableTwoFactorInput) (User, error)
+	VerifyTwoFactor(input TwoFactorLoginInput) (User, error)
+	LoginWithOIDC(input OIDCLoginInput) (User, bool, error)
+	ChangePassword(input ChangePasswordInput) (User, error)
+	RemoveAvatar(ID int, actor audit.Actor) (User, error)
 }
 
+// ErrUserSuspended is returned once a suspended user proved their identity,
+// so handlers can tell it apart from a failed login.
+var ErrUserSuspended = errors.New("Your account has been suspended")
+
 type service struct {
-	repository Repository
+	repository     Repository
+	mailer         mailer.Mailer
+	passwordHasher password.Hasher
+	passwordPolicy *password.Policy
+	auditService   audit.Service
 }
 
-func NewService(repository Repository) *service {
-	return &service{repository}
+func NewService(repository Repository, mailer mailer.Mailer, passwordHasher password.Hasher, passwordPolicy *password.Policy, auditService audit.Service) *service {
+	return &service{repository, mailer, passwordHasher, passwordPolicy, auditService}
 }
 
 func (s *service) RegisterUser(input RegisterUserInput) (User, error) {
@@ -30,19 +80,31 @@ func (s *service) RegisterUser(input RegisterUserInput) (User, error) {
 	user.Name = input.Name
 	user.Email = input.Email
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.MinCost)
+	err := s.passwordPolicy.Validate(input.Password, input.Email)
+	if err != nil {
+		return user, err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(input.Password)
 	if err != nil {
 		return user, err
 	}
 
-	user.PasswordHash = string(passwordHash)
-	user.Role = "user"
+	user.PasswordHash = passwordHash
+	user.Role = DefaultRole
 
 	newUser, err := s.repository.Save(user)
 	if err != nil {
 		return newUser, err
 	}
 
+	input.Actor.ID = newUser.ID
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionUserRegister, newUser.ID, nil)
+
+	// A failed delivery must not fail the registration; the user can ask
+	// for a new link through ResendVerificationEmail.
+	_ = s.sendVerificationEmail(newUser)
+
 	return newUser, nil
 }
 
@@ -56,14 +118,40 @@ func (s *service) Login(input LoginInput) (User, error) {
 	}
 
 	if user.ID == 0 {
+		audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionLoginFailed, 0, map[string]interface{}{"email": email, "reason": "unknown_email"})
 		return user, errors.New("No user found on that email")
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	err = s.passwordHasher.Compare(user.PasswordHash, password)
 	if err != nil {
+		audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionLoginFailed, user.ID, map[string]interface{}{"email": email, "reason": "wrong_password"})
 		return user, err
 	}
 
+	input.Actor.ID = user.ID
+
+	if user.IsSuspended() {
+		audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionLoginFailed, user.ID, map[string]interface{}{"reason": "suspended"})
+		return user, ErrUserSuspended
+	}
+
+	// The plain password is only available here, so this is where hashes
+	// made with outdated parameters are upgraded. A failed upgrade is
+	// retried on the next login rather than failing this one.
+	if s.passwordHasher.NeedsRehash(user.PasswordHash) {
+		passwordHash, err := s.passwordHasher.Hash(password)
+		if err == nil {
+			user.PasswordHash = passwordHash
+
+			rehashedUser, err := s.repository.Update(user)
+			if err == nil {
+				user = rehashedUser
+			}
+		}
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionLoginSuccess, user.ID, map[string]interface{}{"two_factor_pending": user.TwoFactorEnabled()})
+
 	return user, nil
 }
 
@@ -82,7 +170,7 @@ func (s *service) IsEmailAvailable(input CheckEmailInput) (bool, error) {
 	return false, nil
 }
 
-func (s *service) SaveAvatar(ID int, fileLocation string) (User, error) {
+func (s *service) SaveAvatar(ID int, fileLocation string, actor audit.Actor) (User, error) {
 	user, err := s.repository.FindByID(ID)
 	if err != nil {
 		return user, err
@@ -95,6 +183,8 @@ func (s *service) SaveAvatar(ID int, fileLocation string) (User, error) {
 		return updatedUser, err
 	}
 
+	audit.RecordQuietly(s.auditService, audit.TargetUser, actor, audit.ActionAvatarUpdate, updatedUser.ID, nil)
+
 	return updatedUser, nil
 }
 
@@ -120,12 +210,130 @@ func (s *service) GetAllUsers() ([]User, error) {
 	return users, nil
 }
 
+// SearchUsers returns one page of users and the total number of matches,
+// along with the input after defaults were applied.
+func (s *service) SearchUsers(input GetUsersInput) ([]User, int64, GetUsersInput, error) {
+	if input.Page == 0 {
+		input.Page = 1
+	}
+
+	if input.Limit == 0 {
+		input.Limit = defaultUsersPageLimit
+	}
+
+	if input.Role != "" && !IsValidRole(input.Role) && input.Role != RoleLegacyUser {
+		return []User{}, 0, input, errors.New("Invalid role")
+	}
+
+	users, total, err := s.repository.Search(input)
+	if err != nil {
+		return users, total, input, err
+	}
+
+	return users, total, input, nil
+}
+
+func (s *service) SuspendUser(input SuspendUserInput) (User, error) {
+	if input.ID == input.Actor.ID {
+		return User{}, errors.New("You cannot suspend your own account")
+	}
+
+	user, err := s.GetUserByID(input.ID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.IsSuspended() {
+		return user, errors.New("User is already suspended")
+	}
+
+	// Moderators cannot suspend other moderators or admins, nor admins
+	// each other.
+	actor, err := s.GetUserByID(input.Actor.ID)
+	if err != nil {
+		return user, err
+	}
+
+	if !actor.Outranks(user) {
+		return user, errors.New("You can only suspend users with a lower role")
+	}
+
+	now := time.Now()
+	user.SuspendedAt = &now
+	user.SuspensionReason = input.Reason
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionUserSuspend, updatedUser.ID, map[string]interface{}{"reason": input.Reason})
+
+	return updatedUser, nil
+}
+
+func (s *service) UnsuspendUser(ID int, actor audit.Actor) (User, error) {
+	user, err := s.GetUserByID(ID)
+	if err != nil {
+		return user, err
+	}
+
+	if !user.IsSuspended() {
+		return user, errors.New("User is not suspended")
+	}
+
+	user.SuspendedAt = nil
+	user.SuspensionReason = ""
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, actor, audit.ActionUserUnsuspend, updatedUser.ID, nil)
+
+	return updatedUser, nil
+}
+
 func (s *service) UpdateUser(input FormUpdateUserInput) (User, error) {
 	user, err := s.repository.FindByID(input.ID)
 	if err != nil {
 		return user, err
 	}
 
+	previousEmail := user.Email
+	emailChanged := input.Email != previousEmail
+
+	if emailChanged {
+		owner, err := s.repository.FindByEmail(input.Email)
+		if err != nil {
+			return user, err
+		}
+
+		if owner.ID != 0 && owner.ID != user.ID {
+			return user, errors.New("Email has been registered")
+		}
+
+		// Verified emails link OIDC logins to accounts, so whoever holds the
+		// session must also prove they know the password to move it.
+		if user.PasswordHash == "" {
+			return user, errors.New("Set a password before changing your email")
+		}
+
+		err = s.passwordHasher.Compare(user.PasswordHash, input.CurrentPassword)
+		if err != nil {
+			return user, errors.New("Current password is incorrect")
+		}
+
+		// Links sent to the old address must not verify the new one.
+		err = s.repository.MarkTokensAsUsed(user.ID, TokenPurposeEmailVerification)
+		if err != nil {
+			return user, err
+		}
+
+		user.VerifiedAt = nil
+	}
+
 	user.Name = input.Name
 	user.Email = input.Email
 
@@ -134,10 +342,76 @@ func (s *service) UpdateUser(input FormUpdateUserInput) (User, error) {
 		return updatedUser, err
 	}
 
+	// As in RegisterUser, delivery failures are not fatal: the new address
+	// can be verified later through ResendVerificationEmail.
+	if emailChanged {
+		_ = s.sendVerificationEmail(updatedUser)
+		_ = s.sendEmailChangedNotice(updatedUser, previousEmail)
+	}
+
+	metadata := map[string]interface{}{"email_changed": emailChanged}
+	if emailChanged {
+		metadata["previous_email"] = previousEmail
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionProfileUpdate, updatedUser.ID, metadata)
+
 	return updatedUser, nil
 }
 
-func (s *service) DeleteUser(ID int) error {
+func (s *service) ChangePassword(input ChangePasswordInput) (User, error) {
+	user, err := s.GetUserByID(input.ID)
+	if err != nil {
+		return user, err
+	}
+
+	err = s.passwordHasher.Compare(user.PasswordHash, input.CurrentPassword)
+	if err != nil {
+		audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionPasswordChange, user.ID, map[string]interface{}{"success": false})
+		return user, errors.New("Current password is incorrect")
+	}
+
+	err = s.passwordPolicy.Validate(input.NewPassword, user.Email)
+	if err != nil {
+		return user, err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(input.NewPassword)
+	if err != nil {
+		return user, err
+	}
+
+	user.PasswordHash = passwordHash
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionPasswordChange, updatedUser.ID, map[string]interface{}{"success": true})
+
+	return updatedUser, nil
+}
+
+func (s *service) RemoveAvatar(ID int, actor audit.Actor) (User, error) {
+	user, err := s.GetUserByID(ID)
+	if err != nil {
+		return user, err
+	}
+
+	user.AvatarFileName = ""
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, actor, audit.ActionAvatarRemove, updatedUser.ID, nil)
+
+	return updatedUser, nil
+}
+
+func (s *service) DeleteUser(ID int, actor audit.Actor) error {
 	user, err := s.repository.FindByID(ID)
 	if err != nil {
 			return err
@@ -147,10 +421,736 @@ func (s *service) DeleteUser(ID int) error {
 			return errors.New("No user found with that ID")
 	}
 
+	err = s.checkCanRemove(user, actor, "delete")
+	if err != nil {
+			return err
+	}
+
 	err = s.repository.Delete(ID)
 	if err != nil {
 			return err
 	}
 
+	audit.RecordQuietly(s.auditService, audit.TargetUser, actor, audit.ActionUserDelete, ID, nil)
+
+	return nil
+}
+
+// checkCanRemove applies the rules of SuspendUser and AssignRole to
+// deleting and erasing: nobody removes their own account or one with an
+// equal or higher role, and the last admin is kept.
+func (s *service) checkCanRemove(user User, actor audit.Actor, verb string) error {
+	if user.ID == actor.ID {
+		return fmt.Errorf("You cannot %s your own account", verb)
+	}
+
+	actorUser, err := s.GetUserByID(actor.ID)
+	if err != nil {
+		return err
+	}
+
+	if !actorUser.Outranks(user) {
+		return fmt.Errorf("You can only %s users with a lower role", verb)
+	}
+
+	if user.Role == RoleAdmin && !user.IsDeleted() {
+		admins, err := s.repository.CountActiveAdmins()
+		if err != nil {
+			return err
+		}
+
+		if admins <= 1 {
+			return fmt.Errorf("You cannot %s the last admin", verb)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) RestoreUser(ID int, actor audit.Actor) (User, error) {
+	user, err := s.repository.FindByIDWithDeleted(ID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.ID == 0 {
+		return user, errors.New("No user found with that ID")
+	}
+
+	if user.IsErased() {
+		return user, errors.New("Erased users cannot be restored")
+	}
+
+	if !user.IsDeleted() {
+		return user, errors.New("User is not deleted")
+	}
+
+	// Another account may have registered the address while this one was
+	// deleted; restoring it would leave two active users with one email.
+	owner, err := s.repository.FindByEmail(user.Email)
+	if err != nil {
+		return user, err
+	}
+
+	if owner.ID != 0 && owner.ID != user.ID {
+		return user, errors.New("Email has been registered")
+	}
+
+	err = s.repository.Restore(ID)
+	if err != nil {
+		return user, err
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, actor, audit.ActionUserRestore, ID, nil)
+
+	return user, nil
+}
+
+// EraseUser permanently anonymizes a user. The row itself is kept, soft
+// deleted, so donations and campaigns remain available for accounting.
+func (s *service) EraseUser(ID int, actor audit.Actor) error {
+	user, err := s.repository.FindByIDWithDeleted(ID)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == 0 {
+		return errors.New("No user found with that ID")
+	}
+
+	if user.IsErased() {
+		return errors.New("User has already been erased")
+	}
+
+	err = s.checkCanRemove(user, actor, "erase")
+	if err != nil {
+		return err
+	}
+
+	// Scrubbed first: if erasing fails the request can be repeated, while
+	// an erased user's old email could no longer be looked up.
+	err = s.auditService.ScrubUser(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	user.Name = "Deleted user"
+	user.Email = fmt.Sprintf("erased-user-%d@invalid", user.ID)
+	user.PasswordHash = ""
+	user.AvatarFileName = ""
+	user.Token = ""
+	user.VerifiedAt = nil
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	user.ErasedAt = &now
+
+	if !user.IsDeleted() {
+		user.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	}
+
+	err = s.repository.Erase(user)
+	if err != nil {
+		return err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, actor, audit.ActionUserErase, ID, nil)
+
 	return nil
-}
\ No newline at end of file
+}
+
+func (s *service) AssignRole(input AssignRoleInput) (User, error) {
+	if !IsValidRole(input.Role) {
+		return User{}, errors.New("Invalid role")
+	}
+
+	user, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.ID == 0 {
+		return user, errors.New("No user found with that ID")
+	}
+
+	previousRole := user.Role
+
+	// Someone has to be left to assign roles.
+	if previousRole == RoleAdmin && input.Role != RoleAdmin {
+		admins, err := s.repository.CountActiveAdmins()
+		if err != nil {
+			return user, err
+		}
+
+		if admins <= 1 {
+			return user, errors.New("The last admin cannot be given another role")
+		}
+	}
+
+	user.Role = input.Role
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionRoleAssign, updatedUser.ID, map[string]interface{}{"from": previousRole, "to": updatedUser.Role})
+
+	return updatedUser, nil
+}
+
+// BootstrapAdmin makes the account registered with email an admin while
+// there is no admin yet, so a new installation can be administered. Once an
+// admin exists it does nothing and roles are assigned through the API.
+func (s *service) BootstrapAdmin(email string) (User, error) {
+	admins, err := s.repository.CountActiveAdmins()
+	if err != nil {
+		return User{}, err
+	}
+
+	if admins > 0 {
+		return User{}, nil
+	}
+
+	user, err := s.repository.FindByEmail(email)
+	if err != nil {
+		return user, err
+	}
+
+	if user.ID == 0 {
+		return user, errors.New("No user found with that email")
+	}
+
+	previousRole := user.Role
+	user.Role = RoleAdmin
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, audit.Actor{}, audit.ActionRoleAssign, updatedUser.ID, map[string]interface{}{"from": previousRole, "to": updatedUser.Role, "bootstrap": true})
+
+	return updatedUser, nil
+}
+
+// ForgotPassword mails a reset link when the email belongs to an account.
+// Unknown emails are not reported so the endpoint cannot be used to probe
+// which addresses are registered.
+func (s *service) ForgotPassword(input ForgotPasswordInput) error {
+	user, err := s.repository.FindByEmail(input.Email)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == 0 {
+		return nil
+	}
+
+	err = s.repository.MarkTokensAsUsed(user.ID, TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.createToken(user.ID, TokenPurposePasswordReset, passwordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.GetEnv("APP_URL", "http://localhost:8080"), token)
+
+	message := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in one hour and can only be used once.\n\n%s\n\nIf you did not ask for a password reset you can ignore this email.\n", user.Name, link),
+	}
+
+	return s.mailer.Send(message)
+}
+
+func (s *service) ResetPassword(input ResetPasswordInput) (User, error) {
+	token, err := s.repository.FindTokenByHash(helper.HashToken(input.Token), TokenPurposePasswordReset)
+	if err != nil {
+		return User{}, err
+	}
+
+	if token.ID == 0 || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return User{}, errors.New("Invalid or expired token")
+	}
+
+	user, err := s.repository.FindByID(token.UserID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.ID == 0 {
+		return user, errors.New("No user found with that ID")
+	}
+
+	// Validated before the token is spent so a rejected password can be
+	// retried with the same link.
+	err = s.passwordPolicy.Validate(input.Password, user.Email)
+	if err != nil {
+		return user, err
+	}
+
+	// Spending the token is conditional so that two requests racing with
+	// the same link cannot both set a password.
+	used, err := s.repository.UseToken(token.ID)
+	if err != nil {
+		return user, err
+	}
+
+	if !used {
+		return user, errors.New("Invalid or expired token")
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(input.Password)
+	if err != nil {
+		return user, err
+	}
+
+	user.PasswordHash = passwordHash
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	input.Actor.ID = updatedUser.ID
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionPasswordReset, updatedUser.ID, nil)
+
+	return updatedUser, nil
+}
+
+func (s *service) VerifyEmail(input VerifyEmailInput) (User, error) {
+	token, err := s.repository.FindTokenByHash(helper.HashToken(input.Token), TokenPurposeEmailVerification)
+	if err != nil {
+		return User{}, err
+	}
+
+	if token.ID == 0 || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return User{}, errors.New("Invalid or expired token")
+	}
+
+	user, err := s.repository.FindByID(token.UserID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.ID == 0 {
+		return user, errors.New("No user found with that ID")
+	}
+
+	err = s.repository.MarkTokensAsUsed(user.ID, TokenPurposeEmailVerification)
+	if err != nil {
+		return user, err
+	}
+
+	if user.IsVerified() {
+		return user, nil
+	}
+
+	verifiedAt := time.Now()
+	user.VerifiedAt = &verifiedAt
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+func (s *service) ResendVerificationEmail(ID int) error {
+	user, err := s.GetUserByID(ID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsVerified() {
+		return errors.New("Email is already verified")
+	}
+
+	latestToken, err := s.repository.FindLatestToken(user.ID, TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	if latestToken.ID != 0 && time.Since(latestToken.CreatedAt) < emailVerificationResendInterval {
+		return errors.New("Please wait before requesting another verification email")
+	}
+
+	err = s.repository.MarkTokensAsUsed(user.ID, TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	return s.sendVerificationEmail(user)
+}
+
+func (s *service) sendVerificationEmail(user User) error {
+	token, err := s.createToken(user.ID, TokenPurposeEmailVerification, emailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", config.GetEnv("APP_URL", "http://localhost:8080"), token)
+
+	message := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in 24 hours.\n\n%s\n", user.Name, link),
+	}
+
+	return s.mailer.Send(message)
+}
+
+// EnrollTwoFactor stores a new TOTP secret for the user and returns the
+// provisioning URI. The secret is not enforced until ConfirmTwoFactor proves
+// the authenticator app was set up correctly.
+func (s *service) EnrollTwoFactor(ID int) (User, string, error) {
+	user, err := s.GetUserByID(ID)
+	if err != nil {
+		return user, "", err
+	}
+
+	if user.TwoFactorEnabled() {
+		return user, "", errors.New("Two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return user, "", err
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, "", err
+	}
+
+	provisioningURI := totp.ProvisioningURI(config.GetEnv("APP_NAME", "Crowdfunding"), updatedUser.Email, secret)
+
+	return updatedUser, provisioningURI, nil
+}
+
+func (s *service) ConfirmTwoFactor(input ConfirmTwoFactorInput) ([]string, error) {
+	user, err := s.GetUserByID(input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled() {
+		return nil, errors.New("Two-factor authentication is already enabled")
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, errors.New("Two-factor authentication has not been set up")
+	}
+
+	step, valid := totp.Validate(user.TOTPSecret, input.Code, time.Now())
+	if !valid {
+		return nil, errors.New("Invalid two-factor code")
+	}
+
+	recoveryCodes, err := s.regenerateRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabledAt := time.Now()
+	user.TOTPEnabledAt = &enabledAt
+	user.TOTPLastStep = step
+
+	_, err = s.repository.Update(user)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionTwoFactorEnable, user.ID, nil)
+
+	return recoveryCodes, nil
+}
+
+func (s *service) DisableTwoFactor(input DisableTwoFactorInput) (User, error) {
+	user, err := s.GetUserByID(input.ID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.TwoFactorRequired() {
+		return user, errors.New("Two-factor authentication is required for your role")
+	}
+
+	if !user.TwoFactorEnabled() {
+		return user, errors.New("Two-factor authentication is not enabled")
+	}
+
+	err = s.passwordHasher.Compare(user.PasswordHash, input.Password)
+	if err != nil {
+		return user, err
+	}
+
+	_, valid := totp.Validate(user.TOTPSecret, input.Code, time.Now())
+	if !valid {
+		return user, errors.New("Invalid two-factor code")
+	}
+
+	err = s.repository.ReplaceRecoveryCodes(user.ID, nil)
+	if err != nil {
+		return user, err
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionTwoFactorDisable, updatedUser.ID, nil)
+
+	return updatedUser, nil
+}
+
+// VerifyTwoFactor completes a login with either a TOTP code or one of the
+// recovery codes. A TOTP code can only be used once, even within its
+// validity window.
+func (s *service) VerifyTwoFactor(input TwoFactorLoginInput) (User, error) {
+	user, err := s.GetUserByID(input.ID)
+	if err != nil {
+		return user, err
+	}
+
+	if !user.TwoFactorEnabled() {
+		return user, errors.New("Two-factor authentication is not enabled")
+	}
+
+	if user.IsSuspended() {
+		return user, ErrUserSuspended
+	}
+
+	input.Actor.ID = user.ID
+
+	if input.RecoveryCode != "" {
+		used, err := s.repository.UseRecoveryCode(user.ID, helper.HashToken(normalizeRecoveryCode(input.RecoveryCode)))
+		if err != nil {
+			return user, err
+		}
+
+		if !used {
+			audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionTwoFactorFailed, user.ID, map[string]interface{}{"method": "recovery_code"})
+			return user, errors.New("Invalid recovery code")
+		}
+
+		audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionTwoFactorSuccess, user.ID, map[string]interface{}{"method": "recovery_code"})
+
+		return user, nil
+	}
+
+	step, valid := totp.Validate(user.TOTPSecret, input.Code, time.Now())
+	if !valid || step <= user.TOTPLastStep {
+		audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionTwoFactorFailed, user.ID, map[string]interface{}{"method": "totp"})
+		return user, errors.New("Invalid two-factor code")
+	}
+
+	user.TOTPLastStep = step
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionTwoFactorSuccess, updatedUser.ID, map[string]interface{}{"method": "totp"})
+
+	return updatedUser, nil
+}
+
+// LoginWithOIDC returns the user linked to the provider account, linking an
+// existing user or registering a new one by email the first time. Only
+// emails the provider has verified are trusted, otherwise anyone could take
+// over an account by registering its address at the provider.
+//
+// reclaimed reports that an unverified account registered with the email
+// was taken over and its credentials cleared; the caller must revoke its
+// sessions and API keys.
+func (s *service) LoginWithOIDC(input OIDCLoginInput) (User, bool, error) {
+	user, reclaimed, err := s.findOrCreateOIDCUser(input)
+	if err != nil {
+		return user, reclaimed, err
+	}
+
+	input.Actor.ID = user.ID
+
+	if user.IsSuspended() {
+		audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionLoginFailed, user.ID, map[string]interface{}{"provider": input.Provider, "reason": "suspended"})
+		return user, reclaimed, ErrUserSuspended
+	}
+
+	audit.RecordQuietly(s.auditService, audit.TargetUser, input.Actor, audit.ActionOIDCLogin, user.ID, map[string]interface{}{"provider": input.Provider, "reclaimed": reclaimed})
+
+	return user, reclaimed, nil
+}
+
+func (s *service) findOrCreateOIDCUser(input OIDCLoginInput) (User, bool, error) {
+	identity, err := s.repository.FindIdentity(input.Provider, input.Subject)
+	if err != nil {
+		return User{}, false, err
+	}
+
+	if identity.ID != 0 {
+		user, err := s.GetUserByID(identity.UserID)
+		return user, false, err
+	}
+
+	if input.Email == "" || !input.EmailVerified {
+		return User{}, false, errors.New("Email address is not verified by the provider")
+	}
+
+	user, err := s.repository.FindByEmail(input.Email)
+	if err != nil {
+		return user, false, err
+	}
+
+	verifiedAt := time.Now()
+	reclaimed := false
+
+	if user.ID == 0 {
+		user.Name = input.Name
+		user.Email = input.Email
+		user.Role = DefaultRole
+		user.VerifiedAt = &verifiedAt
+
+		if user.Name == "" {
+			user.Name = strings.Split(input.Email, "@")[0]
+		}
+
+		user, err = s.repository.Save(user)
+		if err != nil {
+			return user, false, err
+		}
+	} else if !user.IsVerified() {
+		// Nobody proved they own the address, so whoever registered it may
+		// not be the person now signing in with it. Linking must not leave
+		// them a way in.
+		user, err = s.reclaimUnverifiedUser(user, verifiedAt)
+		if err != nil {
+			return user, false, err
+		}
+
+		reclaimed = true
+	}
+
+	identity = UserIdentity{}
+	identity.UserID = user.ID
+	identity.Provider = input.Provider
+	identity.Subject = input.Subject
+
+	_, err = s.repository.SaveIdentity(identity)
+	if err != nil {
+		return user, reclaimed, err
+	}
+
+	return user, reclaimed, nil
+}
+
+// reclaimUnverifiedUser verifies user and clears every credential that was
+// set up before the email was proven: the password, two-factor secret,
+// recovery codes and outstanding email tokens.
+func (s *service) reclaimUnverifiedUser(user User, verifiedAt time.Time) (User, error) {
+	for _, purpose := range []string{TokenPurposePasswordReset, TokenPurposeEmailVerification} {
+		err := s.repository.MarkTokensAsUsed(user.ID, purpose)
+		if err != nil {
+			return user, err
+		}
+	}
+
+	err := s.repository.ReplaceRecoveryCodes(user.ID, nil)
+	if err != nil {
+		return user, err
+	}
+
+	user.VerifiedAt = &verifiedAt
+	user.PasswordHash = ""
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+
+	return s.repository.Update(user)
+}
+
+func (s *service) regenerateRecoveryCodes(userID int) ([]string, error) {
+	plainCodes := []string{}
+	codes := []RecoveryCode{}
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buffer := make([]byte, 8)
+
+		_, err := rand.Read(buffer)
+		if err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buffer))[:10]
+		plainCodes = append(plainCodes, code[:5]+"-"+code[5:])
+
+		codes = append(codes, RecoveryCode{UserID: userID, CodeHash: helper.HashToken(code)})
+	}
+
+	err := s.repository.ReplaceRecoveryCodes(userID, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	return plainCodes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	return strings.ReplaceAll(code, "-", "")
+}
+
+func (s *service) sendEmailChangedNotice(user User, previousEmail string) error {
+	if previousEmail == "" {
+		return nil
+	}
+
+	message := mailer.Message{
+		To:      previousEmail,
+		Subject: "Your email address was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s. If you did not make this change, reset your password and contact us immediately.\n", user.Name, user.Email),
+	}
+
+	return s.mailer.Send(message)
+}
+
+
+func (s *service) createToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	plainToken, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := UserToken{}
+	token.UserID = userID
+	token.Purpose = purpose
+	token.TokenHash = helper.HashToken(plainToken)
+	token.ExpiresAt = time.Now().Add(ttl)
+
+	_, err = s.repository.SaveToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	return plainToken, nil
+}
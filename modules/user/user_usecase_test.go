@@ -1,21 +1,57 @@
 package user
 
 import (
+	"crowdfunding-minpro-alterra/modules/audit"
+	"crowdfunding-minpro-alterra/modules/audit/audittest"
+	"crowdfunding-minpro-alterra/utils/helper"
+	"crowdfunding-minpro-alterra/utils/mailer"
+	"crowdfunding-minpro-alterra/utils/password"
+	"crowdfunding-minpro-alterra/utils/totp"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// Tests hash with the cheapest bcrypt cost and a policy without a breached
+// list; both are configured from the environment in production.
+var testPasswordHasher, _ = password.NewBcryptHasher(bcrypt.MinCost)
+var testPasswordPolicy = password.NewPolicy(8, 72, nil)
+
 type MockRepository struct {
-	FindByEmailFunc func(email string) (User, error)
-	FindByIDFunc    func(ID int) (User, error)
-	SaveFunc        func(user User) (User, error)
-	FindAllFunc     func() ([]User, error)
-	UpdateFunc      func(user User) (User, error)
+	FindByEmailFunc         func(email string) (User, error)
+	FindByIDFunc            func(ID int) (User, error)
+	FindByIDWithDeletedFunc func(ID int) (User, error)
+	SaveFunc                func(user User) (User, error)
+	FindAllFunc             func() ([]User, error)
+	CountActiveAdminsFunc   func() (int64, error)
+	SearchFunc              func(input GetUsersInput) ([]User, int64, error)
+	UpdateFunc              func(user User) (User, error)
+	FindTokenByHashFunc     func(tokenHash string, purpose string) (UserToken, error)
+	FindLatestTokenFunc     func(userID int, purpose string) (UserToken, error)
+	SavedTokens             []UserToken
+	RecoveryCodes           []RecoveryCode
+	Identities              []UserIdentity
+	RestoredIDs             []int
+	ErasedUsers             []User
+	UsedTokenPurposes       []string
+	UsedTokenIDs            []int
+}
+
+type MockMailer struct {
+	Messages []mailer.Message
 }
 
+func (m *MockMailer) Send(message mailer.Message) error {
+	m.Messages = append(m.Messages, message)
+	return nil
+}
+
+
 func (m *MockRepository) Save(user User) (User, error) {
 	if m.SaveFunc != nil {
 			return m.SaveFunc(user)
@@ -27,10 +63,13 @@ func (m *MockRepository) FindByEmail(email string) (User, error) {
 	if m.FindByEmailFunc != nil {
 			return m.FindByEmailFunc(email)
 	}
-	return User{}, errors.New("FindByEmailFunc is not implemented")
+	return User{}, nil
 }
 
 func (m *MockRepository) FindByID(ID int) (User, error) {
+	if m.FindByIDFunc != nil {
+		return m.FindByIDFunc(ID)
+	}
 	if ID == 1 {
 			return User{ID: 1, Name: "John", Email: "existing@example.com", PasswordHash: "hashed_password", Role: "user"}, nil
 	}
@@ -51,14 +90,110 @@ func (m *MockRepository) FindAll() ([]User, error) {
 	return []User{}, nil
 }
 
+func (m *MockRepository) CountActiveAdmins() (int64, error) {
+	if m.CountActiveAdminsFunc != nil {
+		return m.CountActiveAdminsFunc()
+	}
+	return 0, nil
+}
+
+func (m *MockRepository) Search(input GetUsersInput) ([]User, int64, error) {
+	if m.SearchFunc != nil {
+		return m.SearchFunc(input)
+	}
+	return []User{}, 0, nil
+}
+
 func (m *MockRepository) Delete(ID int) error {
 	return nil
 }
 
+func (m *MockRepository) FindByIDWithDeleted(ID int) (User, error) {
+	if m.FindByIDWithDeletedFunc != nil {
+		return m.FindByIDWithDeletedFunc(ID)
+	}
+	return m.FindByID(ID)
+}
+
+func (m *MockRepository) Restore(ID int) error {
+	m.RestoredIDs = append(m.RestoredIDs, ID)
+	return nil
+}
+
+func (m *MockRepository) Erase(user User) error {
+	m.ErasedUsers = append(m.ErasedUsers, user)
+	return nil
+}
+
+func (m *MockRepository) SaveToken(token UserToken) (UserToken, error) {
+	m.SavedTokens = append(m.SavedTokens, token)
+	return token, nil
+}
+
+func (m *MockRepository) FindTokenByHash(tokenHash string, purpose string) (UserToken, error) {
+	if m.FindTokenByHashFunc != nil {
+		return m.FindTokenByHashFunc(tokenHash, purpose)
+	}
+	return UserToken{}, nil
+}
+
+func (m *MockRepository) FindLatestToken(userID int, purpose string) (UserToken, error) {
+	if m.FindLatestTokenFunc != nil {
+		return m.FindLatestTokenFunc(userID, purpose)
+	}
+	return UserToken{}, nil
+}
+
+func (m *MockRepository) MarkTokensAsUsed(userID int, purpose string) error {
+	m.UsedTokenPurposes = append(m.UsedTokenPurposes, purpose)
+	return nil
+}
+
+func (m *MockRepository) UseToken(tokenID int) (bool, error) {
+	for _, usedID := range m.UsedTokenIDs {
+		if usedID == tokenID {
+			return false, nil
+		}
+	}
+	m.UsedTokenIDs = append(m.UsedTokenIDs, tokenID)
+	return true, nil
+}
+
+func (m *MockRepository) FindIdentity(provider string, subject string) (UserIdentity, error) {
+	for _, identity := range m.Identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return UserIdentity{}, nil
+}
+
+func (m *MockRepository) SaveIdentity(identity UserIdentity) (UserIdentity, error) {
+	identity.ID = len(m.Identities) + 1
+	m.Identities = append(m.Identities, identity)
+	return identity, nil
+}
+
+func (m *MockRepository) ReplaceRecoveryCodes(userID int, codes []RecoveryCode) error {
+	m.RecoveryCodes = codes
+	return nil
+}
+
+func (m *MockRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	for i, code := range m.RecoveryCodes {
+		if code.CodeHash == codeHash && code.UsedAt == nil {
+			usedAt := time.Now()
+			m.RecoveryCodes[i].UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // TestRegisterUser
 func TestRegisterUser(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	input := RegisterUserInput{Name: "John", Email: "john@example.com", Password: "password"}
 	user, err := service.RegisterUser(input)
@@ -69,7 +204,7 @@ func TestRegisterUser(t *testing.T) {
 
 func TestRegisterUser_RepositoryError(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock user input
 	input := RegisterUserInput{
@@ -95,7 +230,7 @@ func TestRegisterUser_RepositoryError(t *testing.T) {
 // TestLogin
 func TestLogin_Success(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock user data
 	email := "existing@example.com"
@@ -139,7 +274,7 @@ func TestLogin_Success(t *testing.T) {
 
 func TestLogin_IncorrectPassword(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock user data
 	email := "existing@example.com"
@@ -182,7 +317,7 @@ func TestLogin_IncorrectPassword(t *testing.T) {
 
 func TestLogin_UserNotFound(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock repository's FindByEmail method to return no user found error
 	repo.FindByEmailFunc = func(email string) (User, error) {
@@ -207,7 +342,7 @@ func TestLogin_UserNotFound(t *testing.T) {
 // Get User By ID
 func TestGetUserByID(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	user, err := service.GetUserByID(1)
 
@@ -217,7 +352,7 @@ func TestGetUserByID(t *testing.T) {
 
 func TestGetUserByID_UserNotFoundError(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Test getting non-existing user by ID
 	user, err := service.GetUserByID(2)
@@ -229,9 +364,9 @@ func TestGetUserByID_UserNotFoundError(t *testing.T) {
 // Update User
 func TestUpdateUser(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
-	input := FormUpdateUserInput{ID: 1, Name: "Updated John", Email: "updated@example.com"}
+	input := FormUpdateUserInput{ID: 1, Name: "Updated John", Email: "existing@example.com"}
 	user, err := service.UpdateUser(input)
 
 	assert.NoError(t, err)
@@ -240,11 +375,11 @@ func TestUpdateUser(t *testing.T) {
 
 func TestUpdateUser_InvalidID(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	initialUser, _ := service.GetUserByID(0)
 
-	input := FormUpdateUserInput{ID: 0, Name: "Updated John", Email: "updated@example.com"}
+	input := FormUpdateUserInput{ID: 0, Name: "Updated John"}
 	_, err := service.UpdateUser(input)
 
 	assert.NoError(t, err, "unexpected error when updating user with invalid ID")
@@ -255,10 +390,10 @@ func TestUpdateUser_InvalidID(t *testing.T) {
 
 func TestUpdateUser_RepositoryError(t *testing.T) {
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock user input
-	input := FormUpdateUserInput{ID: 1, Name: "Updated John", Email: "updated@example.com"}
+	input := FormUpdateUserInput{ID: 1, Name: "Updated John", Email: "john@example.com"}
 
 	// Mock repository's FindByID method to return a user
 	repo.FindByIDFunc = func(ID int) (User, error) {
@@ -287,7 +422,7 @@ func TestIsEmailAvailable_EmailAvailable(t *testing.T) {
 			// Return nil error to simulate email not found
 			return User{}, nil
 	}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	input := CheckEmailInput{Email: "new@example.com"}
 	available, err := service.IsEmailAvailable(input)
@@ -303,7 +438,7 @@ func TestIsEmailAvailable_EmailNotAvailable(t *testing.T) {
 			// Return a user to simulate email found
 			return User{ID: 1}, nil
 	}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	input := CheckEmailInput{Email: "existing@example.com"}
 	available, err := service.IsEmailAvailable(input)
@@ -317,7 +452,7 @@ func TestIsEmailAvailable_FindByEmailError(t *testing.T) {
 	repo.FindByEmailFunc = func(email string) (User, error) {
 			return User{}, errors.New("find by email error")
 	}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	input := CheckEmailInput{Email: "new@example.com"}
 	available, err := service.IsEmailAvailable(input)
@@ -336,7 +471,7 @@ func TestGetAllUsers(t *testing.T) {
 
 	// Create a mock repository with a FindAll function that returns mock users
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock repository's FindAll method to return mock users
 	repo.FindAllFunc = func() ([]User, error) {
@@ -356,7 +491,7 @@ func TestGetAllUsers(t *testing.T) {
 func TestGetAllUsers_Error(t *testing.T) {
 	// Create a mock repository with a FindAll function that returns an error
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock repository's FindAll method to return an error
 	repo.FindAllFunc = func() ([]User, error) {
@@ -375,7 +510,7 @@ func TestGetAllUsers_Error(t *testing.T) {
 func TestSaveAvatar(t *testing.T) {
 	// Create a mock repository
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock user data
 	mockUser := User{
@@ -405,7 +540,7 @@ func TestSaveAvatar(t *testing.T) {
 	}
 
 	// Perform SaveAvatar operation
-	userWithAvatar, err := service.SaveAvatar(mockUser.ID, fileLocation)
+	userWithAvatar, err := service.SaveAvatar(mockUser.ID, fileLocation, audit.Actor{ID: mockUser.ID})
 
 	// Assert no error occurred
 	assert.NoError(t, err)
@@ -417,7 +552,7 @@ func TestSaveAvatar(t *testing.T) {
 func TestSaveAvatar_Error(t *testing.T) {
 	// Create a mock repository
 	repo := &MockRepository{}
-	service := NewService(repo)
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
 	// Mock user data
 	mockUser := User{
@@ -446,13 +581,851 @@ func TestSaveAvatar_Error(t *testing.T) {
 	}
 
 	// Perform SaveAvatar operation
-	_, err := service.SaveAvatar(mockUser.ID, fileLocation)
+	_, err := service.SaveAvatar(mockUser.ID, fileLocation, audit.Actor{ID: mockUser.ID})
 
 	// Assert an error occurred
 	assert.Error(t, err)
 	assert.EqualError(t, err, "error updating user")
 }
 
+// Roles
+func TestRegisterUser_DefaultRole(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	input := RegisterUserInput{Name: "John", Email: "john@example.com", Password: "password"}
+	user, err := service.RegisterUser(input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRole, user.Role)
+	assert.True(t, user.HasPermission(PermissionCampaignCreate))
+}
+
+func TestAssignRole(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	user, err := service.AssignRole(AssignRoleInput{ID: 1, Role: RoleOrganizer})
+
+	assert.NoError(t, err)
+	assert.Equal(t, RoleOrganizer, user.Role)
+}
+
+func TestAssignRole_InvalidRole(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	_, err := service.AssignRole(AssignRoleInput{ID: 1, Role: "superuser"})
+
+	assert.Error(t, err)
+	assert.EqualError(t, err, "Invalid role")
+}
+
+func TestAssignRole_UserNotFound(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	_, err := service.AssignRole(AssignRoleInput{ID: 2, Role: RoleAdmin})
+
+	assert.Error(t, err)
+	assert.EqualError(t, err, "No user found with that ID")
+}
+
+func TestAssignRole_LastAdmin(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 1, Role: RoleAdmin})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
 
+	admins := int64(1)
+	repo.CountActiveAdminsFunc = func() (int64, error) {
+		return admins, nil
+	}
+
+	_, err := service.AssignRole(AssignRoleInput{ID: 1, Role: RoleModerator})
+	assert.EqualError(t, err, "The last admin cannot be given another role")
+
+	admins = 2
+	user, err := service.AssignRole(AssignRoleInput{ID: 1, Role: RoleModerator})
+	assert.NoError(t, err)
+	assert.Equal(t, RoleModerator, user.Role)
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 1, Email: "owner@example.com", Role: DefaultRole})
+	auditService := &audittest.Service{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, auditService)
+
+	admins := int64(0)
+	repo.CountActiveAdminsFunc = func() (int64, error) {
+		return admins, nil
+	}
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		if email == "owner@example.com" {
+			return repo.FindByIDFunc(1)
+		}
+		return User{}, nil
+	}
+
+	_, err := service.BootstrapAdmin("nobody@example.com")
+	assert.EqualError(t, err, "No user found with that email")
+
+	admin, err := service.BootstrapAdmin("owner@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, admin.Role)
+	assert.Equal(t, audit.ActionRoleAssign, auditService.Records[0].Action)
+
+	// With an admin in place nothing changes.
+	admins = 1
+	user, err := service.BootstrapAdmin("owner@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, user.ID)
+	assert.Len(t, auditService.Records, 1)
+}
+
+func TestOutranks(t *testing.T) {
+	admin := User{Role: RoleAdmin}
+	moderator := User{Role: RoleModerator}
+	legacy := User{Role: RoleLegacyUser}
+
+	assert.True(t, admin.Outranks(moderator))
+	assert.False(t, moderator.Outranks(moderator))
+	assert.False(t, moderator.Outranks(admin))
+	assert.True(t, moderator.Outranks(legacy))
+	assert.False(t, legacy.Outranks(User{Role: RoleOrganizer}))
+	assert.True(t, legacy.Outranks(User{Role: RoleDonor}))
+}
+
+func TestHasPermission(t *testing.T) {
+	admin := User{Role: RoleAdmin}
+	donor := User{Role: RoleDonor}
+	organizer := User{Role: RoleOrganizer}
 
+	assert.True(t, admin.HasPermission(PermissionUserDelete))
+	assert.False(t, donor.HasPermission(PermissionUserRead))
+	assert.False(t, donor.HasPermission(PermissionCampaignCreate))
+	assert.True(t, organizer.HasPermission(PermissionCampaignCreate))
+	assert.False(t, User{}.HasPermission(PermissionDonationCreate))
+}
+
+// Password Reset
+func TestForgotPassword(t *testing.T) {
+	repo := &MockRepository{}
+	mail := &MockMailer{}
+	service := NewService(repo, mail, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return User{ID: 1, Name: "John", Email: email}, nil
+	}
 
+	err := service.ForgotPassword(ForgotPasswordInput{Email: "john@example.com"})
+
+	assert.NoError(t, err)
+	assert.Len(t, repo.SavedTokens, 1)
+	assert.Equal(t, TokenPurposePasswordReset, repo.SavedTokens[0].Purpose)
+	assert.Len(t, mail.Messages, 1)
+	assert.Equal(t, "john@example.com", mail.Messages[0].To)
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	repo := &MockRepository{}
+	mail := &MockMailer{}
+	service := NewService(repo, mail, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return User{}, nil
+	}
+
+	err := service.ForgotPassword(ForgotPasswordInput{Email: "nobody@example.com"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, repo.SavedTokens)
+	assert.Empty(t, mail.Messages)
+}
+
+func TestResetPassword(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindTokenByHashFunc = func(tokenHash string, purpose string) (UserToken, error) {
+		if tokenHash == helper.HashToken("reset-token") {
+			return UserToken{ID: 1, UserID: 1, Purpose: purpose, ExpiresAt: time.Now().Add(time.Hour)}, nil
+		}
+		return UserToken{}, nil
+	}
+
+	user, err := service.ResetPassword(ResetPasswordInput{Token: "reset-token", Password: "new-password"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-password")))
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	usedAt := time.Now()
+
+	tokens := map[string]UserToken{
+		"unknown": {},
+		"expired": {ID: 1, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)},
+		"used":    {ID: 2, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt},
+	}
+
+	for name, token := range tokens {
+		t.Run(name, func(t *testing.T) {
+			repo := &MockRepository{}
+			service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+			repo.FindTokenByHashFunc = func(tokenHash string, purpose string) (UserToken, error) {
+				return token, nil
+			}
+
+			_, err := service.ResetPassword(ResetPasswordInput{Token: name, Password: "new-password"})
+
+			assert.EqualError(t, err, "Invalid or expired token")
+		})
+	}
+}
+
+func TestResetPassword_TokenSpentConcurrently(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	// Both requests read the token before either marked it used.
+	repo.FindTokenByHashFunc = func(tokenHash string, purpose string) (UserToken, error) {
+		return UserToken{ID: 1, UserID: 1, Purpose: purpose, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	_, err := service.ResetPassword(ResetPasswordInput{Token: "reset-token", Password: "new-password"})
+	assert.NoError(t, err)
+
+	_, err = service.ResetPassword(ResetPasswordInput{Token: "reset-token", Password: "other-password"})
+	assert.EqualError(t, err, "Invalid or expired token")
+}
+
+// Email Verification
+func TestRegisterUser_SendsVerificationEmail(t *testing.T) {
+	repo := &MockRepository{}
+	mail := &MockMailer{}
+	service := NewService(repo, mail, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	user, err := service.RegisterUser(RegisterUserInput{Name: "John", Email: "john@example.com", Password: "password"})
+
+	assert.NoError(t, err)
+	assert.False(t, user.IsVerified())
+	assert.Len(t, repo.SavedTokens, 1)
+	assert.Equal(t, TokenPurposeEmailVerification, repo.SavedTokens[0].Purpose)
+	assert.Len(t, mail.Messages, 1)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindTokenByHashFunc = func(tokenHash string, purpose string) (UserToken, error) {
+		if tokenHash == helper.HashToken("verify-token") && purpose == TokenPurposeEmailVerification {
+			return UserToken{ID: 1, UserID: 1, Purpose: purpose, ExpiresAt: time.Now().Add(time.Hour)}, nil
+		}
+		return UserToken{}, nil
+	}
+
+	user, err := service.VerifyEmail(VerifyEmailInput{Token: "verify-token"})
+
+	assert.NoError(t, err)
+	assert.True(t, user.IsVerified())
+
+	_, err = service.VerifyEmail(VerifyEmailInput{Token: "other-token"})
+
+	assert.EqualError(t, err, "Invalid or expired token")
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	t.Run("Test ResendVerificationEmail success", func(t *testing.T) {
+		repo := &MockRepository{}
+		mail := &MockMailer{}
+		service := NewService(repo, mail, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+		repo.FindLatestTokenFunc = func(userID int, purpose string) (UserToken, error) {
+			return UserToken{ID: 1, UserID: userID, CreatedAt: time.Now().Add(-time.Hour)}, nil
+		}
+
+		err := service.ResendVerificationEmail(1)
+
+		assert.NoError(t, err)
+		assert.Len(t, mail.Messages, 1)
+	})
+
+	t.Run("Test ResendVerificationEmail is throttled", func(t *testing.T) {
+		repo := &MockRepository{}
+		mail := &MockMailer{}
+		service := NewService(repo, mail, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+		repo.FindLatestTokenFunc = func(userID int, purpose string) (UserToken, error) {
+			return UserToken{ID: 1, UserID: userID, CreatedAt: time.Now().Add(-10 * time.Second)}, nil
+		}
+
+		err := service.ResendVerificationEmail(1)
+
+		assert.EqualError(t, err, "Please wait before requesting another verification email")
+		assert.Empty(t, mail.Messages)
+	})
+}
+
+// Two-Factor Authentication
+func newStatefulRepository(user User) *MockRepository {
+	repo := &MockRepository{}
+
+	repo.FindByIDFunc = func(ID int) (User, error) {
+		if ID == user.ID {
+			return user, nil
+		}
+		return User{}, nil
+	}
+	repo.UpdateFunc = func(updatedUser User) (User, error) {
+		user = updatedUser
+		return user, nil
+	}
+
+	return repo
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	repo := newStatefulRepository(User{ID: 1, Email: "john@example.com", PasswordHash: string(hashedPassword), Role: RoleOrganizer})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	enrolledUser, provisioningURI, err := service.EnrollTwoFactor(1)
+
+	assert.NoError(t, err)
+	assert.False(t, enrolledUser.TwoFactorEnabled())
+	assert.Contains(t, provisioningURI, "otpauth://totp/")
+	assert.Contains(t, provisioningURI, enrolledUser.TOTPSecret)
+
+	_, err = service.ConfirmTwoFactor(ConfirmTwoFactorInput{ID: 1, Code: "000000"})
+	assert.EqualError(t, err, "Invalid two-factor code")
+
+	code, _ := totp.GenerateCode(enrolledUser.TOTPSecret, time.Now())
+	recoveryCodes, err := service.ConfirmTwoFactor(ConfirmTwoFactorInput{ID: 1, Code: code})
+
+	assert.NoError(t, err)
+	assert.Len(t, recoveryCodes, recoveryCodeCount)
+
+	confirmedUser, _ := service.GetUserByID(1)
+	assert.True(t, confirmedUser.TwoFactorEnabled())
+
+	_, err = service.DisableTwoFactor(DisableTwoFactorInput{ID: 1, Password: "password", Code: code})
+	assert.NoError(t, err)
+
+	disabledUser, _ := service.GetUserByID(1)
+	assert.False(t, disabledUser.TwoFactorEnabled())
+	assert.Empty(t, disabledUser.TOTPSecret)
+}
+
+func TestVerifyTwoFactor(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	enabledAt := time.Now()
+	repo := newStatefulRepository(User{ID: 1, TOTPSecret: secret, TOTPEnabledAt: &enabledAt})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	code, _ := totp.GenerateCode(secret, time.Now())
+
+	_, err := service.VerifyTwoFactor(TwoFactorLoginInput{ID: 1, Code: code})
+	assert.NoError(t, err)
+
+	_, err = service.VerifyTwoFactor(TwoFactorLoginInput{ID: 1, Code: code})
+	assert.EqualError(t, err, "Invalid two-factor code", "expected a code to be usable only once")
+
+	repo.RecoveryCodes = []RecoveryCode{{UserID: 1, CodeHash: helper.HashToken("abcdefghij")}}
+
+	_, err = service.VerifyTwoFactor(TwoFactorLoginInput{ID: 1, RecoveryCode: "ABCDE-FGHIJ"})
+	assert.NoError(t, err)
+
+	_, err = service.VerifyTwoFactor(TwoFactorLoginInput{ID: 1, RecoveryCode: "abcde-fghij"})
+	assert.EqualError(t, err, "Invalid recovery code")
+}
+
+func TestDisableTwoFactor_RequiredForAdmin(t *testing.T) {
+	enabledAt := time.Now()
+	repo := newStatefulRepository(User{ID: 1, Role: RoleAdmin, TOTPSecret: "SECRET", TOTPEnabledAt: &enabledAt})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	_, err := service.DisableTwoFactor(DisableTwoFactorInput{ID: 1, Password: "password", Code: "123456"})
+
+	assert.EqualError(t, err, "Two-factor authentication is required for your role")
+}
+
+// OpenID Connect
+func TestLoginWithOIDC_CreatesUser(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return User{}, nil
+	}
+	repo.SaveFunc = func(user User) (User, error) {
+		user.ID = 5
+		return user, nil
+	}
+
+	user, _, err := service.LoginWithOIDC(OIDCLoginInput{Provider: "google", Subject: "sub-1", Email: "jane@example.com", EmailVerified: true, Name: "Jane"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, user.ID)
+	assert.Equal(t, DefaultRole, user.Role)
+	assert.True(t, user.IsVerified())
+	assert.Equal(t, []UserIdentity{{ID: 1, UserID: 5, Provider: "google", Subject: "sub-1"}}, repo.Identities)
+}
+
+func TestLoginWithOIDC_LinksExistingUser(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	verifiedAt := time.Now()
+	repo := newStatefulRepository(User{ID: 1, Name: "John", Email: "john@example.com", PasswordHash: string(hashedPassword), Role: RoleOrganizer, VerifiedAt: &verifiedAt})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return repo.FindByID(1)
+	}
+
+	user, reclaimed, err := service.LoginWithOIDC(OIDCLoginInput{Provider: "google", Subject: "sub-1", Email: "john@example.com", EmailVerified: true})
+
+	assert.NoError(t, err)
+	assert.False(t, reclaimed)
+	assert.Equal(t, 1, user.ID)
+	assert.Equal(t, RoleOrganizer, user.Role)
+	assert.Len(t, repo.Identities, 1)
+
+	_, err = service.Login(LoginInput{Email: "john@example.com", Password: "password"})
+	assert.NoError(t, err)
+
+	linkedUser, _, err := service.LoginWithOIDC(OIDCLoginInput{Provider: "google", Subject: "sub-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, linkedUser.ID)
+	assert.Len(t, repo.Identities, 1)
+}
+
+func TestLoginWithOIDC_ReclaimsUnverifiedUser(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("attacker-password"), bcrypt.MinCost)
+	enabledAt := time.Now()
+	repo := newStatefulRepository(User{ID: 1, Name: "John", Email: "john@example.com", PasswordHash: string(hashedPassword), Role: RoleOrganizer, TOTPSecret: "secret", TOTPEnabledAt: &enabledAt})
+	repo.RecoveryCodes = []RecoveryCode{{UserID: 1, CodeHash: "hash"}}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return repo.FindByID(1)
+	}
+
+	user, reclaimed, err := service.LoginWithOIDC(OIDCLoginInput{Provider: "google", Subject: "sub-1", Email: "john@example.com", EmailVerified: true})
+
+	assert.NoError(t, err)
+	assert.True(t, reclaimed)
+	assert.Equal(t, 1, user.ID)
+	assert.True(t, user.IsVerified())
+	assert.False(t, user.TwoFactorEnabled())
+	assert.Empty(t, repo.RecoveryCodes)
+	assert.ElementsMatch(t, []string{TokenPurposePasswordReset, TokenPurposeEmailVerification}, repo.UsedTokenPurposes)
+	assert.Len(t, repo.Identities, 1)
+
+	_, err = service.Login(LoginInput{Email: "john@example.com", Password: "attacker-password"})
+	assert.Error(t, err)
+}
+
+func TestLoginWithOIDC_UnverifiedEmail(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	_, _, err := service.LoginWithOIDC(OIDCLoginInput{Provider: "google", Subject: "sub-1", Email: "john@example.com", EmailVerified: false})
+
+	assert.EqualError(t, err, "Email address is not verified by the provider")
+	assert.Empty(t, repo.Identities)
+}
+
+func TestUpdateUser_EmailChangeRequiresVerification(t *testing.T) {
+	verifiedAt := time.Now()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	repo := newStatefulRepository(User{ID: 1, Name: "John", Email: "john@example.com", PasswordHash: string(hashedPassword), Role: RoleOrganizer, VerifiedAt: &verifiedAt})
+	mailer := &MockMailer{}
+	service := NewService(repo, mailer, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	_, err := service.UpdateUser(FormUpdateUserInput{ID: 1, Name: "John", Email: "new@example.com"})
+	assert.EqualError(t, err, "Current password is incorrect")
+	assert.Empty(t, mailer.Messages)
+
+	updatedUser, err := service.UpdateUser(FormUpdateUserInput{ID: 1, Name: "John", Email: "new@example.com", CurrentPassword: "password"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", updatedUser.Email)
+	assert.False(t, updatedUser.IsVerified())
+	assert.Equal(t, []string{TokenPurposeEmailVerification}, repo.UsedTokenPurposes)
+	assert.Len(t, mailer.Messages, 2)
+	assert.Equal(t, "new@example.com", mailer.Messages[0].To)
+	assert.Equal(t, "john@example.com", mailer.Messages[1].To)
+}
+
+func TestUpdateUser_EmailTaken(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 1, Name: "John", Email: "john@example.com"})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return User{ID: 2, Email: email}, nil
+	}
+
+	_, err := service.UpdateUser(FormUpdateUserInput{ID: 1, Name: "John", Email: "jane@example.com"})
+
+	assert.EqualError(t, err, "Email has been registered")
+}
+
+func TestUpdateUser_EmailChangeWithoutPassword(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 1, Name: "John", Email: "john@example.com"})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	_, err := service.UpdateUser(FormUpdateUserInput{ID: 1, Name: "John", Email: "new@example.com", CurrentPassword: ""})
+
+	assert.EqualError(t, err, "Set a password before changing your email")
+}
+
+func TestChangePassword(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	repo := newStatefulRepository(User{ID: 1, Email: "john@example.com", PasswordHash: string(hashedPassword)})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	_, err := service.ChangePassword(ChangePasswordInput{ID: 1, CurrentPassword: "wrong", NewPassword: "newpassword"})
+	assert.EqualError(t, err, "Current password is incorrect")
+
+	updatedUser, err := service.ChangePassword(ChangePasswordInput{ID: 1, CurrentPassword: "password", NewPassword: "newpassword"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updatedUser.PasswordHash), []byte("newpassword")))
+}
+
+func TestRemoveAvatar(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 1, AvatarFileName: "https://example.com/avatars/john.png"})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	updatedUser, err := service.RemoveAvatar(1, audit.Actor{ID: 1})
+
+	assert.NoError(t, err)
+	assert.Empty(t, updatedUser.AvatarFileName)
+}
+
+func TestDeleteUser_Rules(t *testing.T) {
+	users := map[int]User{
+		1: {ID: 1, Role: RoleAdmin},
+		2: {ID: 2, Role: RoleAdmin},
+		3: {ID: 3, Role: RoleModerator},
+		4: {ID: 4, Role: RoleModerator},
+	}
+
+	repo := &MockRepository{}
+	repo.FindByIDFunc = func(ID int) (User, error) {
+		return users[ID], nil
+	}
+	repo.FindByIDWithDeletedFunc = repo.FindByIDFunc
+	repo.CountActiveAdminsFunc = func() (int64, error) {
+		return 1, nil
+	}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	err := service.DeleteUser(1, audit.Actor{ID: 1})
+	assert.EqualError(t, err, "You cannot delete your own account")
+
+	err = service.DeleteUser(2, audit.Actor{ID: 1})
+	assert.EqualError(t, err, "You can only delete users with a lower role")
+
+	err = service.DeleteUser(4, audit.Actor{ID: 3})
+	assert.EqualError(t, err, "You can only delete users with a lower role")
+
+	err = service.EraseUser(1, audit.Actor{ID: 1})
+	assert.EqualError(t, err, "You cannot erase your own account")
+
+	err = service.EraseUser(2, audit.Actor{ID: 1})
+	assert.EqualError(t, err, "You can only erase users with a lower role")
+
+	assert.Empty(t, repo.ErasedUsers)
+}
+
+func TestRestoreUser(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByIDWithDeletedFunc = func(ID int) (User, error) {
+		return User{ID: ID, Email: "john@example.com", DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}, nil
+	}
+
+	user, err := service.RestoreUser(1, audit.Actor{ID: 2})
+
+	assert.NoError(t, err)
+	assert.False(t, user.IsDeleted())
+	assert.Equal(t, []int{1}, repo.RestoredIDs)
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return User{ID: 2, Email: email}, nil
+	}
+
+	_, err = service.RestoreUser(1, audit.Actor{ID: 2})
+	assert.EqualError(t, err, "Email has been registered")
+}
+
+func TestRestoreUser_Erased(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	erasedAt := time.Now()
+	repo.FindByIDWithDeletedFunc = func(ID int) (User, error) {
+		return User{ID: ID, DeletedAt: gorm.DeletedAt{Time: erasedAt, Valid: true}, ErasedAt: &erasedAt}, nil
+	}
+
+	_, err := service.RestoreUser(1, audit.Actor{ID: 2})
+
+	assert.EqualError(t, err, "Erased users cannot be restored")
+	assert.Empty(t, repo.RestoredIDs)
+}
+
+func TestEraseUser(t *testing.T) {
+	repo := &MockRepository{}
+	auditService := &audittest.Service{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, auditService)
+
+	repo.FindByIDFunc = func(ID int) (User, error) {
+		return User{ID: ID, Role: RoleAdmin}, nil
+	}
+
+	verifiedAt := time.Now()
+	repo.FindByIDWithDeletedFunc = func(ID int) (User, error) {
+		return User{ID: ID, Name: "John", Email: "john@example.com", PasswordHash: "hash", AvatarFileName: "avatar.png", TOTPSecret: "secret", VerifiedAt: &verifiedAt}, nil
+	}
+
+	err := service.EraseUser(7, audit.Actor{ID: 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, repo.ErasedUsers, 1)
+
+	erased := repo.ErasedUsers[0]
+	assert.Equal(t, 7, erased.ID)
+	assert.Equal(t, "erased-user-7@invalid", erased.Email)
+	assert.NotContains(t, erased.Name, "John")
+	assert.Empty(t, erased.PasswordHash)
+	assert.Empty(t, erased.AvatarFileName)
+	assert.Empty(t, erased.TOTPSecret)
+	assert.False(t, erased.IsVerified())
+	assert.True(t, erased.IsDeleted())
+	assert.True(t, erased.IsErased())
+	assert.Equal(t, []int{7}, auditService.ScrubbedUsers)
+}
+
+func TestSearchUsers_Defaults(t *testing.T) {
+	repo := &MockRepository{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	var searched GetUsersInput
+	repo.SearchFunc = func(input GetUsersInput) ([]User, int64, error) {
+		searched = input
+		return []User{{ID: 1}}, 41, nil
+	}
+
+	users, total, input, err := service.SearchUsers(GetUsersInput{Query: "john"})
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, int64(41), total)
+	assert.Equal(t, 1, input.Page)
+	assert.Equal(t, defaultUsersPageLimit, input.Limit)
+	assert.Equal(t, "john", searched.Query)
+
+	_, _, _, err = service.SearchUsers(GetUsersInput{Role: "root"})
+	assert.EqualError(t, err, "Invalid role")
+}
+
+func TestSuspendUser(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	repo := newStatefulRepository(User{ID: 2, Email: "john@example.com", PasswordHash: string(hashedPassword), Role: RoleModerator})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, &audittest.Service{})
+
+	staff := map[int]User{1: {ID: 1, Role: RoleAdmin}, 3: {ID: 3, Role: RoleModerator}}
+	findUser := repo.FindByIDFunc
+	repo.FindByIDFunc = func(ID int) (User, error) {
+		if member, ok := staff[ID]; ok {
+			return member, nil
+		}
+		return findUser(ID)
+	}
+
+	_, err := service.SuspendUser(SuspendUserInput{ID: 2, Actor: audit.Actor{ID: 2}})
+	assert.EqualError(t, err, "You cannot suspend your own account")
+
+	_, err = service.SuspendUser(SuspendUserInput{ID: 2, Actor: audit.Actor{ID: 3}})
+	assert.EqualError(t, err, "You can only suspend users with a lower role")
+
+	suspendedUser, err := service.SuspendUser(SuspendUserInput{ID: 2, Actor: audit.Actor{ID: 1}, Reason: "Spam"})
+
+	assert.NoError(t, err)
+	assert.True(t, suspendedUser.IsSuspended())
+	assert.Equal(t, StatusSuspended, suspendedUser.Status())
+	assert.Equal(t, "Spam", suspendedUser.SuspensionReason)
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return suspendedUser, nil
+	}
+
+	_, err = service.Login(LoginInput{Email: "john@example.com", Password: "password"})
+	assert.ErrorIs(t, err, ErrUserSuspended)
+
+	_, err = service.Login(LoginInput{Email: "john@example.com", Password: "wrong"})
+	assert.NotErrorIs(t, err, ErrUserSuspended)
+
+	unsuspendedUser, err := service.UnsuspendUser(2, audit.Actor{ID: 1})
+
+	assert.NoError(t, err)
+	assert.False(t, unsuspendedUser.IsSuspended())
+	assert.Empty(t, unsuspendedUser.SuspensionReason)
+}
+
+func TestRegisterUser_PasswordPolicy(t *testing.T) {
+	repo := &MockRepository{}
+	policy := password.NewPolicy(8, 72, []string{"Password123"})
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, policy, &audittest.Service{})
+
+	repo.SaveFunc = func(user User) (User, error) {
+		return user, nil
+	}
+
+	_, err := service.RegisterUser(RegisterUserInput{Name: "John", Email: "john@example.com", Password: "short"})
+	assert.EqualError(t, err, "Password must be at least 8 characters")
+
+	_, err = service.RegisterUser(RegisterUserInput{Name: "John", Email: "john@example.com", Password: "John@Example.com"})
+	assert.EqualError(t, err, "Password must not be your email address")
+
+	_, err = service.RegisterUser(RegisterUserInput{Name: "John", Email: "john@example.com", Password: "password123"})
+	assert.EqualError(t, err, "Password has appeared in a data breach, choose another one")
+
+	_, err = service.RegisterUser(RegisterUserInput{Name: "John", Email: "john@example.com", Password: "correct horse battery"})
+	assert.NoError(t, err)
+}
+
+func TestLogin_RehashesOutdatedHash(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	repo := newStatefulRepository(User{ID: 1, Email: "john@example.com", PasswordHash: string(hashedPassword)})
+
+	hasher, _ := password.NewArgon2idHasher(password.Argon2idParams{Memory: 1024, Time: 1, Threads: 1})
+	service := NewService(repo, &MockMailer{}, hasher, testPasswordPolicy, &audittest.Service{})
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return repo.FindByID(1)
+	}
+
+	user, err := service.Login(LoginInput{Email: "john@example.com", Password: "password"})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(user.PasswordHash, "$argon2id$"))
+	assert.False(t, hasher.NeedsRehash(user.PasswordHash))
+
+	user, err = service.Login(LoginInput{Email: "john@example.com", Password: "password"})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(user.PasswordHash, "$argon2id$"))
+}
+
+func TestLogin_RecordsAuditEvents(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	repo := newStatefulRepository(User{ID: 3, Email: "john@example.com", PasswordHash: string(hashedPassword)})
+	auditService := &audittest.Service{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, auditService)
+
+	repo.FindByEmailFunc = func(email string) (User, error) {
+		return repo.FindByID(3)
+	}
+
+	actor := audit.Actor{IPAddress: "10.0.0.1", UserAgent: "curl"}
+
+	_, err := service.Login(LoginInput{Email: "john@example.com", Password: "wrong", Actor: actor})
+	assert.Error(t, err)
+
+	_, err = service.Login(LoginInput{Email: "john@example.com", Password: "password", Actor: actor})
+	assert.NoError(t, err)
+
+	assert.Len(t, auditService.Records, 2)
+
+	assert.Equal(t, audit.ActionLoginFailed, auditService.Records[0].Action)
+	assert.Equal(t, 0, auditService.Records[0].Actor.ID)
+	assert.Equal(t, 3, auditService.Records[0].TargetID)
+	assert.Equal(t, "wrong_password", auditService.Records[0].Metadata["reason"])
+
+	assert.Equal(t, audit.ActionLoginSuccess, auditService.Records[1].Action)
+	assert.Equal(t, 3, auditService.Records[1].Actor.ID)
+	assert.Equal(t, "10.0.0.1", auditService.Records[1].Actor.IPAddress)
+}
+
+func TestLogin_UnknownEmailRecordsAuditEvent(t *testing.T) {
+	repo := &MockRepository{}
+	auditService := &audittest.Service{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, auditService)
+
+	_, err := service.Login(LoginInput{Email: "nobody@example.com", Password: "password"})
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{audit.ActionLoginFailed}, auditService.Actions())
+	assert.Equal(t, 0, auditService.Records[0].TargetID)
+	assert.Equal(t, "unknown_email", auditService.Records[0].Metadata["reason"])
+	assert.Equal(t, "nobody@example.com", auditService.Records[0].Metadata["email"])
+}
+
+func TestSuspendUser_RecordsAuditEvent(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 2, Email: "john@example.com", Role: RoleOrganizer})
+	auditService := &audittest.Service{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, auditService)
+
+	findUser := repo.FindByIDFunc
+	repo.FindByIDFunc = func(ID int) (User, error) {
+		if ID == 1 {
+			return User{ID: 1, Role: RoleAdmin}, nil
+		}
+		return findUser(ID)
+	}
+
+	_, err := service.SuspendUser(SuspendUserInput{ID: 2, Actor: audit.Actor{ID: 1}, Reason: "Spam"})
+	assert.NoError(t, err)
+
+	_, err = service.UnsuspendUser(2, audit.Actor{ID: 1})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{audit.ActionUserSuspend, audit.ActionUserUnsuspend}, auditService.Actions())
+	assert.Equal(t, 1, auditService.Records[0].Actor.ID)
+	assert.Equal(t, 2, auditService.Records[0].TargetID)
+	assert.Equal(t, "Spam", auditService.Records[0].Metadata["reason"])
+}
+
+func TestAssignRole_RecordsAuditEvent(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 2, Email: "john@example.com", Role: RoleOrganizer})
+	auditService := &audittest.Service{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, auditService)
+
+	_, err := service.AssignRole(AssignRoleInput{ID: 2, Role: RoleModerator, Actor: audit.Actor{ID: 1}})
+	assert.NoError(t, err)
+
+	_, err = service.AssignRole(AssignRoleInput{ID: 2, Role: "owner", Actor: audit.Actor{ID: 1}})
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{audit.ActionRoleAssign}, auditService.Actions())
+	assert.Equal(t, 1, auditService.Records[0].Actor.ID)
+	assert.Equal(t, RoleOrganizer, auditService.Records[0].Metadata["from"])
+	assert.Equal(t, RoleModerator, auditService.Records[0].Metadata["to"])
+}
+
+func TestDeleteUser_RecordsAuditEvent(t *testing.T) {
+	repo := newStatefulRepository(User{ID: 4, Email: "john@example.com", Role: RoleOrganizer})
+	auditService := &audittest.Service{}
+	service := NewService(repo, &MockMailer{}, testPasswordHasher, testPasswordPolicy, auditService)
+
+	findUser := repo.FindByIDFunc
+	repo.FindByIDFunc = func(ID int) (User, error) {
+		if ID == 1 {
+			return User{ID: 1, Role: RoleAdmin}, nil
+		}
+		return findUser(ID)
+	}
+
+	err := service.DeleteUser(4, audit.Actor{ID: 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, auditService.Records, 1)
+	assert.Equal(t, audit.ActionUserDelete, auditService.Records[0].Action)
+	assert.Equal(t, 1, auditService.Records[0].Actor.ID)
+	assert.Equal(t, audit.TargetUser, auditService.Records[0].TargetType)
+	assert.Equal(t, 4, auditService.Records[0].TargetID)
+}
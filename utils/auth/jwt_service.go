@@ -1,37 +1,108 @@
 package auth
 
 import (
+	"crowdfunding-minpro-alterra/config"
 	"errors"
-	"os"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+const (
+	TokenTypeAccess             = "access"
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+
+	challengeTokenTTL = 5 * time.Minute
+)
+
 type Service interface {
-	GenerateToken(UserID int) (string, error)
+	GenerateToken(UserID int, sessionID string) (string, error)
 	ValidateToken(token string) (*jwt.Token, error)
+	GenerateChallengeToken(UserID int) (string, error)
+	ValidateChallengeToken(token string) (int, error)
+	JWKS() JSONWebKeySet
 }
 
 type jwtService struct {
-	secretKey []byte
+	keys           *KeySet
+	accessTokenTTL time.Duration
 }
 
-func NewService() *jwtService {
-	jwtSecret := os.Getenv("JWT_SECRET")
+// NewService loads the signing keys from JWT_KEYS_DIR. It fails when no key
+// can sign right now, so the server never starts issuing unverifiable tokens.
+func NewService() (*jwtService, error) {
+	keys, err := LoadKeySet(config.GetEnv("JWT_KEYS_DIR", "keys"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewServiceWithKeys(keys, config.GetDuration("JWT_ACCESS_TOKEN_TTL", 15*time.Minute))
+}
 
-	return &jwtService{
-		secretKey: []byte(jwtSecret),
+func NewServiceWithKeys(keys *KeySet, accessTokenTTL time.Duration) (*jwtService, error) {
+	if _, ok := keys.SigningKey(time.Now()); !ok {
+		return nil, errors.New("No active JWT signing key")
 	}
+
+	return &jwtService{keys, accessTokenTTL}, nil
 }
 
-func (s *jwtService) GenerateToken(UserID int) (string, error) {
+func (s *jwtService) JWKS() JSONWebKeySet {
+	return s.keys.JWKS(time.Now())
+}
+
+// GenerateToken issues an access token for the session, carried in the
+// "sid" claim so revoking the session also rejects its access tokens.
+func (s *jwtService) GenerateToken(UserID int, sessionID string) (string, error) {
+	return s.sign(UserID, TokenTypeAccess, s.accessTokenTTL, jwt.MapClaims{"sid": sessionID})
+}
+
+// GenerateChallengeToken issues the short-lived token returned by /sessions
+// when the password was correct but a second factor is still required. It
+// is rejected everywhere an access token is expected.
+func (s *jwtService) GenerateChallengeToken(UserID int) (string, error) {
+	return s.sign(UserID, TokenTypeTwoFactorChallenge, challengeTokenTTL, nil)
+}
+
+func (s *jwtService) ValidateChallengeToken(encodedToken string) (int, error) {
+	token, err := s.ValidateToken(encodedToken)
+	if err != nil {
+		return 0, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok || claims["typ"] != TokenTypeTwoFactorChallenge {
+		return 0, errors.New("Invalid token")
+	}
+
+	return int(userID), nil
+}
+
+func (s *jwtService) sign(UserID int, tokenType string, ttl time.Duration, extraClaims jwt.MapClaims) (string, error) {
+	now := time.Now()
+
+	key, ok := s.keys.SigningKey(now)
+	if !ok {
+		return "", errors.New("No active JWT signing key")
+	}
+
 	claims := jwt.MapClaims{
 		"user_id": UserID,
+		"typ":     tokenType,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	for name, value := range extraClaims {
+		claims[name] = value
+	}
 
-	signedToken, err := token.SignedString(s.secretKey)
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+
+	signedToken, err := token.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", err
 	}
@@ -41,16 +112,34 @@ func (s *jwtService) GenerateToken(UserID int) (string, error) {
 
 func (s *jwtService) ValidateToken(encodedToken string) (*jwt.Token, error) {
 	token, err := jwt.Parse(encodedToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := s.keys.VerificationKey(kid, time.Now())
+		if !ok || token.Method.Alg() != key.Algorithm {
 			return nil, errors.New("Invalid token")
 		}
 
-		return s.secretKey, nil
+		return key.PrivateKey.Public(), nil
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	// Tokens issued before expiry was introduced carry no "exp" claim, which
+	// jwt-go treats as valid forever.
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("Invalid token")
+	}
+
 	return token, nil
 }
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	if algorithm == AlgorithmEdDSA {
+		return SigningMethodEdDSA
+	}
+
+	return jwt.SigningMethodRS256
+}
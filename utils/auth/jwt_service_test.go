@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestGenerateAndValidateToken(t *testing.T) {
+	for _, key := range []SigningKey{
+		{ID: "rsa", Algorithm: AlgorithmRS256, PrivateKey: newRSAKey(t)},
+		{ID: "ed", Algorithm: AlgorithmEdDSA, PrivateKey: newEd25519Key(t)},
+	} {
+		keys, _ := NewKeySet(key)
+		service, err := NewServiceWithKeys(keys, time.Minute)
+		assert.NoError(t, err)
+
+		encodedToken, err := service.GenerateToken(7, "session")
+		assert.NoError(t, err)
+
+		token, err := service.ValidateToken(encodedToken)
+		assert.NoError(t, err)
+		assert.Equal(t, key.ID, token.Header["kid"])
+		assert.Equal(t, key.Algorithm, token.Header["alg"])
+		assert.Equal(t, float64(7), token.Claims.(jwt.MapClaims)["user_id"])
+		assert.Equal(t, "session", token.Claims.(jwt.MapClaims)["sid"])
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Now()
+	oldKey := SigningKey{ID: "old", Algorithm: AlgorithmRS256, PrivateKey: newRSAKey(t)}
+	newKey := SigningKey{ID: "new", Algorithm: AlgorithmEdDSA, PrivateKey: newEd25519Key(t), ActiveFrom: now.Add(time.Hour)}
+
+	keys, _ := NewKeySet(oldKey, newKey)
+	service, _ := NewServiceWithKeys(keys, time.Minute)
+
+	encodedToken, _ := service.GenerateToken(1, "session")
+	token, err := service.ValidateToken(encodedToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", token.Header["kid"])
+
+	// The scheduled key is announced before it signs anything.
+	jwks := service.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+
+	signingKey, _ := keys.SigningKey(now.Add(2 * time.Hour))
+	assert.Equal(t, "new", signingKey.ID)
+}
+
+func TestValidateToken_RetiredKey(t *testing.T) {
+	key := SigningKey{ID: "retired", Algorithm: AlgorithmEdDSA, PrivateKey: newEd25519Key(t)}
+	keys, _ := NewKeySet(key)
+	service, _ := NewServiceWithKeys(keys, time.Minute)
+
+	encodedToken, _ := service.GenerateToken(1, "session")
+
+	keys.keys[0].RetireAt = time.Now().Add(-time.Second)
+
+	_, err := service.ValidateToken(encodedToken)
+	assert.Error(t, err)
+	assert.Empty(t, service.JWKS().Keys)
+}
+
+func TestValidateToken_RejectsSharedSecret(t *testing.T) {
+	keys, _ := NewKeySet(SigningKey{ID: "ed", Algorithm: AlgorithmEdDSA, PrivateKey: newEd25519Key(t)})
+	service, _ := NewServiceWithKeys(keys, time.Minute)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1, "typ": TokenTypeAccess, "exp": time.Now().Add(time.Minute).Unix()})
+	token.Header["kid"] = "ed"
+	encodedToken, _ := token.SignedString([]byte("secret"))
+
+	_, err := service.ValidateToken(encodedToken)
+	assert.Error(t, err)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadKeySet(dir)
+	assert.EqualError(t, err, "No JWT signing keys found in "+dir)
+
+	rsaBytes := x509.MarshalPKCS1PrivateKey(newRSAKey(t))
+	edBytes, _ := x509.MarshalPKCS8PrivateKey(newEd25519Key(t))
+
+	os.WriteFile(filepath.Join(dir, "2026-01.pem"), pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: rsaBytes}), 0600)
+	os.WriteFile(filepath.Join(dir, "2026-02.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edBytes}), 0600)
+
+	keys, err := LoadKeySet(dir)
+	assert.NoError(t, err)
+
+	signingKey, _ := keys.SigningKey(time.Now())
+	assert.Equal(t, "2026-02", signingKey.ID)
+	assert.Equal(t, AlgorithmEdDSA, signingKey.Algorithm)
+
+	manifest := `[{"kid": "2026-01"}, {"kid": "2026-02", "active_from": "2999-01-01T00:00:00Z"}]`
+	os.WriteFile(filepath.Join(dir, "keys.json"), []byte(manifest), 0600)
+
+	keys, err = LoadKeySet(dir)
+	assert.NoError(t, err)
+
+	signingKey, _ = keys.SigningKey(time.Now())
+	assert.Equal(t, "2026-01", signingKey.ID)
+	assert.Len(t, keys.JWKS(time.Now()).Keys, 2)
+}
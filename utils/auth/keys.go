@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	minRSAKeyBits = 2048
+	keyManifest   = "keys.json"
+)
+
+// SigningKey is one private key of the key set. A key signs new tokens from
+// ActiveFrom on, and is published for verification until RetireAt (or
+// forever when RetireAt is zero), so it can be announced in the JWKS before
+// it starts signing and kept there until the last token it signed expired.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	ActiveFrom time.Time
+	RetireAt   time.Time
+}
+
+func (k SigningKey) isPublished(now time.Time) bool {
+	return k.RetireAt.IsZero() || now.Before(k.RetireAt)
+}
+
+func (k SigningKey) canSign(now time.Time) bool {
+	return k.isPublished(now) && !now.Before(k.ActiveFrom)
+}
+
+type KeySet struct {
+	keys []SigningKey
+}
+
+func NewKeySet(keys ...SigningKey) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("No JWT signing keys configured")
+	}
+
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("JWT signing key without kid")
+		}
+
+		if seen[key.ID] {
+			return nil, fmt.Errorf("Duplicate JWT signing key %q", key.ID)
+		}
+		seen[key.ID] = true
+	}
+
+	sorted := append([]SigningKey(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].ActiveFrom.Equal(sorted[j].ActiveFrom) {
+			return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return &KeySet{keys: sorted}, nil
+}
+
+// SigningKey returns the most recently activated key that may sign at now.
+func (s *KeySet) SigningKey(now time.Time) (SigningKey, bool) {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if s.keys[i].canSign(now) {
+			return s.keys[i], true
+		}
+	}
+
+	return SigningKey{}, false
+}
+
+func (s *KeySet) VerificationKey(kid string, now time.Time) (SigningKey, bool) {
+	for _, key := range s.keys {
+		if key.ID == kid && key.isPublished(now) {
+			return key, true
+		}
+	}
+
+	return SigningKey{}, false
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS lists the public half of every published key, including keys that
+// are scheduled but not signing yet.
+func (s *KeySet) JWKS(now time.Time) JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+
+	for _, key := range s.keys {
+		if !key.isPublished(now) {
+			continue
+		}
+
+		jwk := JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+
+		switch publicKey := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+type keyManifestEntry struct {
+	Kid        string `json:"kid"`
+	File       string `json:"file"`
+	ActiveFrom string `json:"active_from"`
+	RetireAt   string `json:"retire_at"`
+}
+
+// LoadKeySet reads PEM encoded RSA or Ed25519 private keys from dir. When
+// dir contains a keys.json manifest, it lists the keys and their rotation
+// schedule; otherwise every *.pem file is loaded with its file name as kid
+// and the highest kid signs.
+func LoadKeySet(dir string) (*KeySet, error) {
+	entries, err := readKeyManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []SigningKey
+
+	for _, entry := range entries {
+		key, err := loadSigningKey(filepath.Join(dir, entry.File))
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", entry.Kid, err)
+		}
+
+		key.ID = entry.Kid
+
+		if entry.ActiveFrom != "" {
+			key.ActiveFrom, err = time.Parse(time.RFC3339, entry.ActiveFrom)
+			if err != nil {
+				return nil, fmt.Errorf("JWT key %q: invalid active_from: %w", entry.Kid, err)
+			}
+		}
+
+		if entry.RetireAt != "" {
+			key.RetireAt, err = time.Parse(time.RFC3339, entry.RetireAt)
+			if err != nil {
+				return nil, fmt.Errorf("JWT key %q: invalid retire_at: %w", entry.Kid, err)
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("No JWT signing keys found in %s", dir)
+	}
+
+	return NewKeySet(keys...)
+}
+
+func readKeyManifest(dir string) ([]keyManifestEntry, error) {
+	content, err := os.ReadFile(filepath.Join(dir, keyManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []keyManifestEntry
+		for _, file := range files {
+			name := filepath.Base(file)
+			entries = append(entries, keyManifestEntry{Kid: strings.TrimSuffix(name, ".pem"), File: name})
+		}
+
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []keyManifestEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("Invalid %s: %w", keyManifest, err)
+	}
+
+	for i := range entries {
+		if entries[i].File == "" {
+			entries[i].File = entries[i].Kid + ".pem"
+		}
+	}
+
+	return entries, nil
+}
+
+func loadSigningKey(path string) (SigningKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return SigningKey{}, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	return newSigningKey(parsed)
+}
+
+func newSigningKey(privateKey interface{}) (SigningKey, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return SigningKey{}, fmt.Errorf("RSA keys must be at least %d bits", minRSAKeyBits)
+		}
+		return SigningKey{Algorithm: AlgorithmRS256, PrivateKey: key}, nil
+	case ed25519.PrivateKey:
+		return SigningKey{Algorithm: AlgorithmEdDSA, PrivateKey: key}, nil
+	default:
+		return SigningKey{}, errors.New("only RSA and Ed25519 keys are supported")
+	}
+}
@@ -8,9 +8,26 @@ type Response struct {
 }
 
 type Meta struct {
-	Message string `json:"message"`
-	Code    int    `json:"code"`
-	Status  string `json:"status"`
+	Message    string      `json:"message"`
+	Code       int         `json:"code"`
+	Status     string      `json:"status"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+func NewPagination(page int, limit int, total int64) Pagination {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+
+	return Pagination{Page: page, Limit: limit, Total: total, TotalPages: totalPages}
 }
 
 func APIResponse(message string, code int, status string, data interface{}) Response {
@@ -28,6 +45,13 @@ func APIResponse(message string, code int, status string, data interface{}) Resp
 	return jsonResponse
 }
 
+func APIResponseWithPagination(message string, code int, status string, data interface{}, pagination Pagination) Response {
+	jsonResponse := APIResponse(message, code, status, data)
+	jsonResponse.Meta.Pagination = &pagination
+
+	return jsonResponse
+}
+
 func FormatValidationError(err error) []string {
 	var errors []string
 
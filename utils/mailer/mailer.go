@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"crowdfunding-minpro-alterra/config"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(message Message) error
+}
+
+// NewFromEnv builds the mailer selected by MAIL_DRIVER. "smtp" delivers
+// through SMTP_HOST; anything else writes messages to MAIL_OUTBOX_DIR so
+// local development never sends real email.
+func NewFromEnv() Mailer {
+	if config.GetEnv("MAIL_DRIVER", "outbox") == "smtp" {
+		return NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			config.GetEnv("SMTP_PORT", "587"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			config.GetEnv("MAIL_FROM", "no-reply@crowdfunding.local"),
+		)
+	}
+
+	return NewOutboxMailer(config.GetEnv("MAIL_OUTBOX_DIR", "outbox"))
+}
+
+type smtpMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host string, port string, username string, password string, from string) *smtpMailer {
+	return &smtpMailer{host, port, username, password, from}
+}
+
+func (m *smtpMailer) Send(message Message) error {
+	var auth smtp.Auth
+
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	return smtp.SendMail(m.host+":"+m.port, auth, m.from, []string{message.To}, compose(m.from, message))
+}
+
+type outboxMailer struct {
+	directory string
+	mutex     sync.Mutex
+}
+
+func NewOutboxMailer(directory string) *outboxMailer {
+	return &outboxMailer{directory: directory}
+}
+
+var unsafeFileName = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+func (m *outboxMailer) Send(message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	err := os.MkdirAll(m.directory, 0o755)
+	if err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileName.ReplaceAllString(message.To, "_"))
+
+	return os.WriteFile(filepath.Join(m.directory, fileName), compose("outbox@localhost", message), 0o644)
+}
+
+func compose(from string, message Message) []byte {
+	var builder strings.Builder
+
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(message.Body)
+
+	return []byte(builder.String())
+}
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompose(t *testing.T) {
+	message := Message{To: "john@example.com", Subject: "Hello", Body: "Hi John"}
+
+	composed := string(compose("no-reply@example.com", message))
+
+	assert.True(t, strings.HasPrefix(composed, "From: no-reply@example.com\r\nTo: john@example.com\r\nSubject: Hello\r\n"))
+	assert.Contains(t, composed, "Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	assert.True(t, strings.HasSuffix(composed, "\r\n\r\nHi John"))
+}
+
+func TestOutboxMailer_Send(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "outbox")
+	mailer := NewOutboxMailer(directory)
+
+	err := mailer.Send(Message{To: "john/../doe@example.com", Subject: "Hello", Body: "Hi John"})
+	assert.NoError(t, err)
+
+	entries, err := os.ReadDir(directory)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), "-john_.._doe@example.com.eml"))
+
+	content, err := os.ReadFile(filepath.Join(directory, entries[0].Name()))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Run("outbox by default", func(t *testing.T) {
+		t.Setenv("MAIL_DRIVER", "")
+
+		_, ok := NewFromEnv().(*outboxMailer)
+		assert.True(t, ok)
+	})
+
+	t.Run("smtp", func(t *testing.T) {
+		t.Setenv("MAIL_DRIVER", "smtp")
+		t.Setenv("SMTP_HOST", "smtp.example.com")
+		t.Setenv("SMTP_PORT", "")
+
+		mailer, ok := NewFromEnv().(*smtpMailer)
+		assert.True(t, ok)
+		assert.Equal(t, "smtp.example.com", mailer.host)
+		assert.Equal(t, "587", mailer.port)
+	})
+}
@@ -0,0 +1,410 @@
+package oidc
+
+import (
+	"crowdfunding-minpro-alterra/config"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type ProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// Claims are the ID token claims used to find or create the local user.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider talks to one OpenID Connect issuer. Discovery and signing keys
+// are fetched lazily and cached, so any spec-compliant issuer works,
+// including a local mock issuer in development.
+type Provider struct {
+	config     ProviderConfig
+	httpClient *http.Client
+
+	mutex     sync.Mutex
+	discovery *Discovery
+	keys      map[string]interface{}
+}
+
+func NewProvider(config ProviderConfig) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ProvidersFromEnv reads OIDC_PROVIDERS (a comma separated list of names)
+// and, for each name, OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL and optionally
+// OIDC_<NAME>_SCOPES.
+func ProvidersFromEnv() map[string]*Provider {
+	providers := map[string]*Provider{}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		providerConfig := ProviderConfig{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  config.GetEnv(prefix+"REDIRECT_URL", config.GetEnv("APP_URL", "http://localhost:8080")+"/api/v1/auth/"+name+"/callback"),
+		}
+
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			providerConfig.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+
+		providers[name] = NewProvider(providerConfig)
+	}
+
+	return providers
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the URL the browser is redirected to. codeChallenge is
+// the S256 PKCE challenge derived from the verifier kept in the StateStore.
+func (p *Provider) AuthCodeURL(state string, nonce string, codeChallenge string) (string, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+func (p *Provider) Exchange(code string, codeVerifier string) (TokenResponse, error) {
+	var tokenResponse TokenResponse
+
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return tokenResponse, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	response, err := p.httpClient.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return tokenResponse, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return tokenResponse, fmt.Errorf("token endpoint returned %s", response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&tokenResponse)
+	if err != nil {
+		return tokenResponse, err
+	}
+
+	if tokenResponse.IDToken == "" {
+		return tokenResponse, errors.New("token response has no id_token")
+	}
+
+	return tokenResponse, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// rawIDToken and returns its identity claims.
+func (p *Provider) VerifyIDToken(rawIDToken string, nonce string) (Claims, error) {
+	var claims Claims
+
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return claims, err
+	}
+
+	token, err := jwt.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := p.getKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+		case *ecdsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+		}
+
+		return key, nil
+	})
+	if err != nil {
+		return claims, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || !mapClaims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return claims, errors.New("invalid id_token")
+	}
+
+	if mapClaims["iss"] != discovery.Issuer {
+		return claims, errors.New("id_token issuer mismatch")
+	}
+
+	if !hasAudience(mapClaims["aud"], p.config.ClientID) {
+		return claims, errors.New("id_token audience mismatch")
+	}
+
+	if mapClaims["nonce"] != nonce {
+		return claims, errors.New("id_token nonce mismatch")
+	}
+
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.Name, _ = mapClaims["name"].(string)
+
+	switch verified := mapClaims["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+
+	if claims.Subject == "" {
+		return claims, errors.New("id_token has no subject")
+	}
+
+	return claims, nil
+}
+
+func hasAudience(audience interface{}, clientID string) bool {
+	switch aud := audience.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, value := range aud {
+			if value == clientID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *Provider) getDiscovery() (*Discovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery Discovery
+
+	err := p.getJSON(strings.TrimSuffix(p.config.IssuerURL, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.config.IssuerURL, "/") {
+		return nil, errors.New("discovery document issuer mismatch")
+	}
+
+	p.discovery = &discovery
+
+	return p.discovery, nil
+}
+
+// getKey returns the signing key identified by kid, refreshing the JWKS once
+// when the key is unknown so that provider key rotation is picked up.
+func (p *Provider) getKey(kid string) (interface{}, error) {
+	p.mutex.Lock()
+	key, ok := p.keys[kid]
+	p.mutex.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	err = p.getJSON(discovery.JWKSURI, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+
+	for _, jwk := range jwks.Keys {
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = publicKey
+	}
+
+	p.mutex.Lock()
+	p.keys = keys
+	p.mutex.Unlock()
+
+	key, ok = keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key, nil
+}
+
+func (p *Provider) getJSON(url string, target interface{}) error {
+	response, err := p.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, response.Status)
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, errors.New("unsupported curve")
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, errors.New("unsupported key type")
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(bytes), nil
+}
@@ -0,0 +1,133 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockIssuer is a minimal OpenID Connect issuer that signs whatever claims
+// the test puts in idTokenClaims.
+type mockIssuer struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	idTokenClaims jwt.MapClaims
+	lastForm      url.Values
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	issuer := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                issuer.server.URL,
+			AuthorizationEndpoint: issuer.server.URL + "/authorize",
+			TokenEndpoint:         issuer.server.URL + "/token",
+			JWKSURI:               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		issuer.lastForm = r.PostForm
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.idTokenClaims)
+		token.Header["kid"] = "test-key"
+		idToken, _ := token.SignedString(key)
+
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "access", IDToken: idToken, TokenType: "Bearer"})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+
+	return issuer
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+
+	provider := NewProvider(ProviderConfig{
+		Name:        "mock",
+		IssuerURL:   issuer.server.URL,
+		ClientID:    "client-id",
+		RedirectURL: "http://localhost:8080/api/v1/auth/mock/callback",
+	})
+
+	verifier, challenge, err := NewPKCE()
+	assert.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL("state", "nonce", challenge)
+	assert.NoError(t, err)
+
+	parsedURL, _ := url.Parse(authURL)
+	assert.Equal(t, "/authorize", parsedURL.Path)
+	assert.Equal(t, challenge, parsedURL.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", parsedURL.Query().Get("code_challenge_method"))
+
+	issuer.idTokenClaims = jwt.MapClaims{
+		"iss":            issuer.server.URL,
+		"aud":            "client-id",
+		"sub":            "subject-1",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"name":           "Jane",
+		"nonce":          "nonce",
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+
+	tokenResponse, err := provider.Exchange("code", verifier)
+	assert.NoError(t, err)
+	assert.Equal(t, verifier, issuer.lastForm.Get("code_verifier"))
+
+	claims, err := provider.VerifyIDToken(tokenResponse.IDToken, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, Claims{Subject: "subject-1", Email: "jane@example.com", EmailVerified: true, Name: "Jane"}, claims)
+
+	_, err = provider.VerifyIDToken(tokenResponse.IDToken, "other-nonce")
+	assert.EqualError(t, err, "id_token nonce mismatch")
+}
+
+func TestProvider_RejectsForeignAudience(t *testing.T) {
+	issuer := newMockIssuer(t)
+	defer issuer.server.Close()
+
+	provider := NewProvider(ProviderConfig{Name: "mock", IssuerURL: issuer.server.URL, ClientID: "client-id"})
+
+	issuer.idTokenClaims = jwt.MapClaims{
+		"iss":   issuer.server.URL,
+		"aud":   []string{"another-client"},
+		"sub":   "subject-1",
+		"nonce": "nonce",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+
+	tokenResponse, err := provider.Exchange("code", "verifier")
+	assert.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(tokenResponse.IDToken, "nonce")
+	assert.EqualError(t, err, "id_token audience mismatch")
+}
@@ -0,0 +1,83 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// AuthState is what has to survive the round trip to the provider.
+type AuthState struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type StateStore interface {
+	Save(state string, authState AuthState) error
+	// Consume returns and forgets the state, so a callback can only be
+	// completed once.
+	Consume(state string) (AuthState, bool)
+}
+
+type memoryStateStore struct {
+	mutex  sync.Mutex
+	states map[string]AuthState
+}
+
+func NewMemoryStateStore() *memoryStateStore {
+	return &memoryStateStore{states: map[string]AuthState{}}
+}
+
+func (s *memoryStateStore) Save(state string, authState AuthState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	for key, value := range s.states {
+		if now.After(value.ExpiresAt) {
+			delete(s.states, key)
+		}
+	}
+
+	s.states[state] = authState
+
+	return nil
+}
+
+func (s *memoryStateStore) Consume(state string) (AuthState, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	authState, ok := s.states[state]
+	if !ok {
+		return authState, false
+	}
+
+	delete(s.states, state)
+
+	if time.Now().After(authState.ExpiresAt) {
+		return authState, false
+	}
+
+	return authState, true
+}
+
+// NewPKCE returns a random code verifier and its S256 code challenge.
+func NewPKCE() (string, string, error) {
+	buffer := make([]byte, 32)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier := base64.RawURLEncoding.EncodeToString(buffer)
+	sum := sha256.Sum256([]byte(verifier))
+
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,198 @@
+package password
+
+import (
+	"crowdfunding-minpro-alterra/config"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+
+	argon2idPrefix = "$argon2id$"
+	argon2SaltSize = 16
+	argon2KeySize  = 32
+)
+
+// ErrMismatch is returned by Compare for a wrong password, whichever
+// algorithm produced the stored hash.
+var ErrMismatch = bcrypt.ErrMismatchedHashAndPassword
+
+// Hasher hashes new passwords with its configured algorithm and parameters.
+// Compare accepts hashes from any supported algorithm, so existing users can
+// still log in after the configuration changed; NeedsRehash reports whether
+// their hash should then be replaced.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Compare(hash string, password string) error
+	NeedsRehash(hash string) bool
+}
+
+type Argon2idParams struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// NewHasherFromEnv reads PASSWORD_HASH_ALGORITHM (bcrypt or argon2id) and
+// its parameters.
+func NewHasherFromEnv() (Hasher, error) {
+	algorithm := config.GetEnv("PASSWORD_HASH_ALGORITHM", AlgorithmBcrypt)
+
+	switch algorithm {
+	case AlgorithmBcrypt:
+		return NewBcryptHasher(config.GetInt("PASSWORD_BCRYPT_COST", 12))
+	case AlgorithmArgon2id:
+		return NewArgon2idHasher(Argon2idParams{
+			Memory:  uint32(config.GetInt("PASSWORD_ARGON2_MEMORY", 64*1024)),
+			Time:    uint32(config.GetInt("PASSWORD_ARGON2_TIME", 3)),
+			Threads: uint8(config.GetInt("PASSWORD_ARGON2_THREADS", 2)),
+		})
+	default:
+		return nil, fmt.Errorf("Unsupported password hash algorithm %q", algorithm)
+	}
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) (*bcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &bcryptHasher{cost}, nil
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Compare(hash string, password string) error {
+	return compare(hash, password)
+}
+
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) (*argon2idHasher, error) {
+	if params.Memory < 8*uint32(params.Threads) || params.Time < 1 || params.Threads < 1 {
+		return nil, errors.New("Invalid argon2id parameters")
+	}
+
+	return &argon2idHasher{params}, nil
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltSize)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Time, h.params.Memory, h.params.Threads, argon2KeySize)
+
+	return encodeArgon2id(h.params, salt, key), nil
+}
+
+func (h *argon2idHasher) Compare(hash string, password string) error {
+	return compare(hash, password)
+}
+
+func (h *argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	return params != h.params
+}
+
+func compare(hash string, password string) error {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+// encodeArgon2id uses the PHC string format shared with other argon2
+// implementations: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func encodeArgon2id(params Argon2idParams, salt []byte, key []byte) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params.Memory,
+		params.Time,
+		params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	invalid := errors.New("Invalid argon2id hash")
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, invalid
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, invalid
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, invalid
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, invalid
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, invalid
+	}
+
+	return params, salt, key, nil
+}
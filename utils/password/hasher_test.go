@@ -0,0 +1,62 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher, err := NewArgon2idHasher(Argon2idParams{Memory: 1024, Time: 1, Threads: 1})
+	assert.NoError(t, err)
+
+	hash, err := hasher.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	assert.NoError(t, hasher.Compare(hash, "correct horse"))
+	assert.ErrorIs(t, hasher.Compare(hash, "wrong horse"), ErrMismatch)
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	stronger, _ := NewArgon2idHasher(Argon2idParams{Memory: 2048, Time: 1, Threads: 1})
+	assert.True(t, stronger.NeedsRehash(hash))
+}
+
+func TestHashersCompareEachOther(t *testing.T) {
+	bcryptHasher, _ := NewBcryptHasher(bcrypt.MinCost)
+	argon2Hasher, _ := NewArgon2idHasher(Argon2idParams{Memory: 1024, Time: 1, Threads: 1})
+
+	bcryptHash, _ := bcryptHasher.Hash("correct horse")
+	argon2Hash, _ := argon2Hasher.Hash("correct horse")
+
+	assert.NoError(t, argon2Hasher.Compare(bcryptHash, "correct horse"))
+	assert.NoError(t, bcryptHasher.Compare(argon2Hash, "correct horse"))
+
+	assert.True(t, argon2Hasher.NeedsRehash(bcryptHash))
+	assert.True(t, bcryptHasher.NeedsRehash(argon2Hash))
+}
+
+func TestBcryptHasher_NeedsRehash(t *testing.T) {
+	hasher, _ := NewBcryptHasher(bcrypt.MinCost + 1)
+	oldHash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+
+	assert.True(t, hasher.NeedsRehash(string(oldHash)))
+
+	newHash, _ := hasher.Hash("correct horse")
+	assert.False(t, hasher.NeedsRehash(newHash))
+
+	_, err := NewBcryptHasher(100)
+	assert.Error(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	policy := NewPolicy(8, 72, []string{"iloveyou", " Sunshine1 "})
+
+	assert.NoError(t, policy.Validate("correct horse", "john@example.com"))
+	assert.EqualError(t, policy.Validate("short", ""), "Password must be at least 8 characters")
+	assert.EqualError(t, policy.Validate(strings.Repeat("a", 73), ""), "Password must be at most 72 characters")
+	assert.EqualError(t, policy.Validate("JOHN@example.com", "john@example.com"), "Password must not be your email address")
+	assert.EqualError(t, policy.Validate("sunshine1", ""), "Password has appeared in a data breach, choose another one")
+}
@@ -0,0 +1,99 @@
+package password
+
+import (
+	"bufio"
+	"crowdfunding-minpro-alterra/config"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Policy decides which new passwords are acceptable. It is applied when a
+// password is set, never when one is checked at login.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	breached  map[string]struct{}
+}
+
+// NewPolicy returns a policy rejecting the given breached passwords,
+// compared case-insensitively.
+func NewPolicy(minLength int, maxLength int, breached []string) *Policy {
+	policy := &Policy{MinLength: minLength, MaxLength: maxLength, breached: map[string]struct{}{}}
+
+	for _, password := range breached {
+		password = strings.ToLower(strings.TrimSpace(password))
+		if password != "" {
+			policy.breached[password] = struct{}{}
+		}
+	}
+
+	return policy
+}
+
+// NewPolicyFromEnv reads PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH and
+// PASSWORD_BREACHED_LIST, a file with one known-breached password per line.
+// The maximum defaults to 72 bytes, the most bcrypt takes into account.
+func NewPolicyFromEnv() (*Policy, error) {
+	var breached []string
+
+	if path := config.GetEnv("PASSWORD_BREACHED_LIST", ""); path != "" {
+		list, err := LoadBreachedList(path)
+		if err != nil {
+			return nil, err
+		}
+		breached = list
+	}
+
+	return NewPolicy(config.GetInt("PASSWORD_MIN_LENGTH", 8), config.GetInt("PASSWORD_MAX_LENGTH", 72), breached), nil
+}
+
+func LoadBreachedList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var passwords []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		passwords = append(passwords, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return passwords, nil
+}
+
+func (p *Policy) Validate(password string, email string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("Password must be at least %d characters", p.MinLength)
+	}
+
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return fmt.Errorf("Password must be at most %d characters", p.MaxLength)
+	}
+
+	normalized := strings.ToLower(password)
+
+	if email != "" {
+		email = strings.ToLower(email)
+		localPart := strings.Split(email, "@")[0]
+
+		if normalized == email || normalized == localPart {
+			return errors.New("Password must not be your email address")
+		}
+	}
+
+	if _, ok := p.breached[normalized]; ok {
+		return errors.New("Password has appeared in a data breach, choose another one")
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 appendix B test vectors,
+// "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits.
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, vector := range vectors {
+		code, err := GenerateCode(rfcSecret, time.Unix(vector.unix, 0))
+
+		assert.NoError(t, err)
+		assert.Equal(t, vector.code, code, "time %d", vector.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok := Validate(rfcSecret, " 050471 ", now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// The code of the previous period is still accepted to allow for drift.
+	_, ok = Validate(rfcSecret, "050471", now.Add(Period*time.Second))
+	assert.True(t, ok)
+
+	_, ok = Validate(rfcSecret, "050471", now.Add(3*Period*time.Second))
+	assert.False(t, ok)
+
+	_, ok = Validate(rfcSecret, "000000", now)
+	assert.False(t, ok)
+
+	_, ok = Validate("not base32!", "050471", now)
+	assert.False(t, ok)
+}